@@ -0,0 +1,40 @@
+// localecheck сверяет константы localizer с файлами переводов: ключи, которых нет в каком-то языке,
+// лишние ключи, переменные шаблонов, отличающиеся от эталонного языка, и неполные формы множественного числа.
+// Код выхода 1, если найдены проблемы
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+)
+
+func main() {
+	ids := flag.String("ids", "../common/pkg/localizer/localize_ids.go", "файл с константами ключей")
+	dir := flag.String("dir", "./locales", "каталог файлов переводов")
+	ref := flag.String("ref", "en", "эталонный язык для сравнения переменных шаблонов")
+	flag.Parse()
+
+	keys, err := localizer.ParseIDs(*ids)
+	if err != nil {
+		log.Fatalln("ошибка чтения констант:", err)
+	}
+
+	issues, err := localizer.CheckCatalog(keys, *dir, *ref)
+	if err != nil {
+		log.Fatalln("ошибка проверки переводов:", err)
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+
+	if len(issues) > 0 {
+		fmt.Printf("\nнайдено проблем: %d\n", len(issues))
+		os.Exit(1)
+	}
+	fmt.Printf("ключей: %d, проблем не найдено\n", len(keys))
+}
@@ -0,0 +1,66 @@
+// mailpreview рендерит все шаблоны писем во всех локалях с тестовыми данными и сохраняет
+// их в каталог: <локаль>/<письмо>.html, .txt и .eml (готовое письмо для почтового клиента)
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/EddyZe/foodApp/authservice/internal/mailer"
+)
+
+func main() {
+	dir := flag.String("dir", "./templates/mail", "каталог шаблонов писем")
+	out := flag.String("out", "./mail-preview", "каталог для результата")
+	defaultLocale := flag.String("default-locale", "en", "локаль по умолчанию")
+	from := flag.String("from", "foodApp <no-reply@example.com>", "отправитель в .eml")
+	flag.Parse()
+
+	renderer, err := mailer.NewRenderer(*dir, *defaultLocale)
+	if err != nil {
+		log.Fatalln("ошибка загрузки шаблонов:", err)
+	}
+
+	data := map[string]interface{}{
+		"appName":        "foodApp",
+		"appSupportLink": "https://example.com/support",
+		"code":           "A1B2C3D4",
+		"url":            "https://example.com/confirm?token=preview",
+		"expiresMinutes": 10,
+	}
+
+	for _, locale := range renderer.Locales() {
+		localeDir := filepath.Join(*out, locale)
+		if err := os.MkdirAll(localeDir, 0o755); err != nil {
+			log.Fatalln(err)
+		}
+
+		for _, name := range renderer.Names(locale) {
+			msg, err := renderer.Render(name, locale, data)
+			if err != nil {
+				log.Fatalf("%s/%s: %v", locale, name, err)
+			}
+
+			eml, err := mailer.Build(mailer.ParseAddress(*from), []string{"user@example.com"}, msg, time.Now())
+			if err != nil {
+				log.Fatalf("%s/%s: %v", locale, name, err)
+			}
+
+			base := filepath.Join(localeDir, name)
+			for ext, content := range map[string][]byte{
+				".html": []byte(msg.HTML),
+				".txt":  []byte(msg.Text),
+				".eml":  eml,
+			} {
+				if err := os.WriteFile(base+ext, content, 0o644); err != nil {
+					log.Fatalln(err)
+				}
+			}
+
+			log.Printf("%s/%s: %s", locale, name, msg.Subject)
+		}
+	}
+}
@@ -3,18 +3,31 @@ package authapp
 import (
 	"github.com/EddyZe/foodApp/authservice/internal/app/storage"
 	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/EddyZe/foodApp/authservice/internal/mailer"
 	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+	"github.com/EddyZe/foodApp/authservice/internal/schedulers/banexpiryscheduler"
 	"github.com/EddyZe/foodApp/authservice/internal/schedulers/dbclearscheduler"
+	"github.com/EddyZe/foodApp/authservice/internal/schedulers/mailscheduler"
+	"github.com/EddyZe/foodApp/authservice/internal/schedulers/outboxscheduler"
 	"github.com/EddyZe/foodApp/authservice/internal/server"
 	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/transport/grpc"
+	"github.com/EddyZe/foodApp/common/pkg/cache"
+	"github.com/EddyZe/foodApp/common/pkg/events"
 	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/validate"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"net"
+	"strings"
+	"time"
 )
 
 func MustRun(logger *logrus.Entry, appConf *config.AppConfig) {
 
 	psql := storage.MustConnectPsql(logger, appConf.Postgres)
-	red := storage.MustConnectRedis(logger, appConf.Redis)
+	red := storage.ConnectRedis(logger, appConf.Redis, prometheus.DefaultRegisterer)
+	defer red.Close()
 
 	//инициализация зависимостей
 	logger.Infoln("Создание репозиториев")
@@ -22,6 +35,7 @@ func MustRun(logger *logrus.Entry, appConf *config.AppConfig) {
 	rr := repositories.NewRoleRepository(psql)
 	urr := repositories.NewUserRoleRepository(psql)
 	br := repositories.NewBanRepository(psql)
+	bar := repositories.NewBanAppealRepository(psql)
 	ar := repositories.NewAccessTokenRepository(psql)
 	trr := repositories.NewRefreshTokenRepository(psql)
 	evr := repositories.NewEmailVerificationCodeRepository(psql)
@@ -29,25 +43,88 @@ func MustRun(logger *logrus.Entry, appConf *config.AppConfig) {
 	hpr := repositories.NewPasswordHistoryRepository(psql)
 	rpr := repositories.NewResetPasswordRepository(psql)
 	cr := repositories.NewClearDBRepository(psql)
+	pr := repositories.NewPermissionRepository(psql)
+	adr := repositories.NewAuditRepository(psql)
+	blr := repositories.NewBlackListTokenRepository(psql)
+	obr := repositories.NewOutboxRepository(psql)
+	mqr := repositories.NewMailQueueRepository(psql)
+	msr := repositories.NewMailSuppressionRepository(psql)
+	icr := repositories.NewInviteCodeRepository(psql)
+	csr := repositories.NewConsentRepository(psql)
 	logger.Infoln("Репозитории созданы")
 
 	logger.Infoln("Созание сервисов")
-	rs := services.NewRoleService(logger, red, rr, urr)
+	producer := newProducer(logger, appConf.Kafka)
+	defer func() {
+		if err := producer.Close(); err != nil {
+			logger.Error("Ошибка при закрытии продюсера: ", err)
+		}
+	}()
+
+	cacheMetrics, err := cache.NewPrometheusMetrics(prometheus.DefaultRegisterer)
+	if err != nil {
+		logger.Error("ошибка регистрации метрик кеша: ", err)
+		panic(err)
+	}
+	appCache := cache.New(red, cache.Options{
+		Version:     appConf.Cache.Version,
+		TTL:         time.Duration(appConf.Redis.Expiration) * time.Minute,
+		NegativeTTL: time.Duration(appConf.Cache.NegativeSeconds) * time.Second,
+		Jitter:      float64(appConf.Cache.JitterPercent) / 100,
+		Metrics:     cacheMetrics,
+	})
+	rs := services.NewRoleService(logger, appCache, rr, urr, pr)
+	obs := services.NewOutboxService(logger, appConf.Outbox, appConf.Kafka.UserEventsTopic, obr, producer)
 	hps := services.NewHistoryPasswordService(logger, hpr)
+	is := services.NewInviteService(logger, icr, rs)
+	css := services.NewConsentService(logger, csr)
+	ts := services.NewTokenService(appConf.Tokens, red, appCache, trr, logger, ar, blr, rs)
 	us := services.NewUserService(
 		logger,
-		red,
+		appCache,
 		rs,
+		ts,
 		ur,
 		hps,
+		obs,
+		is,
+		css,
+		appConf.Registration,
+	)
+	bs := services.NewBanService(logger, br, bar, ts, obs)
+	mailRenderer, err := mailer.NewRenderer(appConf.MailTemplates.Dir, appConf.MailTemplates.DefaultLocale)
+	if err != nil {
+		logger.Error("ошибка загрузки шаблонов писем: ", err)
+		panic(err)
+	}
+	mailSender := newMailer(logger, appConf.MailTransport, appConf.SmptConfig, appConf.MailQueue)
+	if appConf.Dkim.KeyFile != "" {
+		mailSender = newDKIMSigner(logger, appConf.Dkim, appConf.SmptConfig.From).Wrap(mailSender)
+	}
+	defer mailSender.Close()
+	sps := services.NewMailSuppressionService(logger, msr)
+	ms := services.NewMailService(
+		logger,
+		appConf.SmptConfig.From,
+		mailSender,
+		appConf.MailQueue,
+		mailRenderer,
+		mqr,
+		sps,
 	)
-	ts := services.NewTokenService(appConf.Tokens, red, trr, logger, ar)
-	bs := services.NewBanService(logger, br, ts)
-	ms := services.NewMailService(logger, appConf.SmptConfig)
 	mvs := services.NewEmailVerificationCodeService(logger, appConf.EmailVerification, evr, evtr)
-	lms := localizer.NewLocalizeService(logger, appConf.LocalizerConfig.DirFiles)
-	rps := services.NewResetPasswordService(logger, appConf.ResetPassword, rpr)
+	lms := localizer.NewLocalizeService(
+		logger,
+		appConf.LocalizerConfig.DirFiles,
+		localizer.WithDefaultLanguage(appConf.LocalizerConfig.DefaultLanguage),
+	)
+	if appConf.LocalizerConfig.ReloadSeconds > 0 {
+		stopLocales := lms.Watch(time.Duration(appConf.LocalizerConfig.ReloadSeconds) * time.Second)
+		defer stopLocales()
+	}
+	rps := services.NewResetPasswordService(logger, appConf.ResetPassword, rpr, us, appConf.ResetPassword.Secret)
 	cs := services.NewCleanDBService(logger, cr)
+	as := services.NewAuditService(logger, adr)
 	logger.Infoln("Создане сервисов завершено")
 
 	logger.Infoln("Создание шедулеров")
@@ -56,12 +133,117 @@ func MustRun(logger *logrus.Entry, appConf *config.AppConfig) {
 		logger.Error("Ошибка запуска шедулера по очистке базы: ", err)
 		panic(err)
 	}
+	outboxScheduler := outboxscheduler.NewOutboxScheduler(logger, appConf.Outbox, obs)
+	if err := outboxScheduler.Start(); err != nil {
+		logger.Error("Ошибка запуска шедулера отправки событий: ", err)
+		panic(err)
+	}
+	mailScheduler := mailscheduler.NewMailScheduler(logger, appConf.MailQueue, ms)
+	if err := mailScheduler.Start(); err != nil {
+		logger.Error("Ошибка запуска шедулера отправки писем: ", err)
+		panic(err)
+	}
+	banExpiryScheduler := banexpiryscheduler.NewBanExpiryScheduler(logger, bs)
+	if err := banExpiryScheduler.Start(); err != nil {
+		logger.Error("Ошибка запуска шедулера по снятию истекших блокировок: ", err)
+		panic(err)
+	}
+
+	logger.Infoln("Запуск gRPC сервера. Порт: ", appConf.Grpc.Port)
+	lis, err := net.Listen("tcp", ":"+appConf.Grpc.Port)
+	if err != nil {
+		logger.Error("Ошибка при запуске gRPC сервера: ", err)
+		panic(err)
+	}
+	grpcServ, err := grpc.New(logger, appConf.Grpc, psql, us, rs, bs, ts)
+	if err != nil {
+		logger.Error("Ошибка при создании gRPC сервера: ", err)
+		panic(err)
+	}
+	go func() {
+		if err := grpcServ.Serve(lis); err != nil {
+			logger.Error("Ошибка gRPC сервера: ", err)
+		}
+	}()
+	defer grpcServ.GracefulStop()
 
 	//Запуск сервера
 	logger.Infoln("Запуск сервера")
-	serv := server.New(logger, us, ts, rs, bs, ms, mvs, lms, rps, appConf.AppInfo)
+	if err := validate.RegisterTagNames(); err != nil {
+		logger.Error("Ошибка настройки валидатора: ", err)
+		panic(err)
+	}
+	serv := server.New(logger, us, ts, rs, bs, ms, mvs, lms, rps, as, is, css, sps, appConf.AppInfo, appConf.MailBounce, appConf.MailTransport, appConf.RateLimit, appConf.Problem, psql, red)
 	if err := serv.ListenAndServe(); err != nil {
 		panic(err)
 	}
 
 }
+
+// newProducer брокер для outbox: kafka или память процесса для локального запуска
+func newProducer(logger *logrus.Entry, cfg *config.KafkaConfig) events.Producer {
+	if cfg.Broker == "memory" {
+		logger.Warn("События не отправляются в kafka: используется брокер в памяти")
+		return events.NewMemoryBus()
+	}
+
+	return events.NewKafkaProducer(cfg.Brokers)
+}
+
+// newMailer транспорт писем: smtp, каталог в формате Maildir или память процесса для локального запуска и тестов
+func newMailer(
+	logger *logrus.Entry,
+	cfg *config.MailTransportConfig,
+	smtpCfg *config.SmptConfig,
+	queueCfg *config.MailQueueConfig,
+) mailer.Mailer {
+	switch cfg.Transport {
+	case mailer.TransportMemory:
+		logger.Warn("Письма не отправляются: используется транспорт в памяти")
+		return mailer.NewMemoryMailer(cfg.MemoryLimit)
+	case mailer.TransportFile:
+		logger.Warn("Письма не отправляются: сохраняются в каталог ", cfg.FileDir)
+		m, err := mailer.NewMaildirMailer(cfg.FileDir)
+		if err != nil {
+			logger.Error("ошибка при создании каталога писем: ", err)
+			panic(err)
+		}
+		return m
+	}
+
+	if err := mailer.CheckTLSMode(smtpCfg.TLS); err != nil {
+		logger.Error("ошибка в настройках SMTP: ", err)
+		panic(err)
+	}
+	if smtpCfg.TLS == mailer.TLSNone {
+		logger.Warn("Письма отправляются по SMTP без шифрования")
+	}
+
+	return mailer.NewSMTPMailer(mailer.SMTPOptions{
+		Host:        smtpCfg.Host,
+		Port:        smtpCfg.Port,
+		Username:    smtpCfg.Username,
+		Password:    smtpCfg.Password,
+		TLS:         smtpCfg.TLS,
+		PoolSize:    smtpCfg.PoolSize,
+		IdleTimeout: time.Duration(smtpCfg.IdleTimeoutSeconds) * time.Second,
+		SendTimeout: time.Duration(queueCfg.SendTimeoutSeconds) * time.Second,
+	})
+}
+
+// newDKIMSigner подпись писем. Без явного домена используется домен адреса отправителя
+func newDKIMSigner(logger *logrus.Entry, cfg *config.DkimConfig, from string) *mailer.DKIMSigner {
+	key, err := mailer.LoadDKIMKey(cfg.KeyFile)
+	if err != nil {
+		logger.Error("ошибка загрузки DKIM ключа: ", err)
+		panic(err)
+	}
+
+	domain := cfg.Domain
+	if domain == "" {
+		_, domain, _ = strings.Cut(mailer.ParseAddress(from).Address, "@")
+	}
+
+	logger.Infof("Письма подписываются DKIM: домен %s, селектор %s", domain, cfg.Selector)
+	return mailer.NewDKIMSigner(domain, cfg.Selector, key)
+}
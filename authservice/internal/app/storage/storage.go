@@ -4,6 +4,7 @@ import (
 	"github.com/EddyZe/foodApp/authservice/internal/config"
 	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
 	"github.com/EddyZe/foodApp/authservice/internal/datasourse/redis"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,22 +29,12 @@ func ConnectPsql(logger *logrus.Entry, cfg *config.PostgresConfig) (*postgre.Pos
 	return db, nil
 }
 
-func MustConnectRedis(logger *logrus.Entry, cfg *config.RedisConfig) *redis.Redis {
-	r, err := ConnectRedis(logger, cfg)
-	if err != nil {
-		panic(err)
-	}
-
-	return r
-}
-
-func ConnectRedis(logger *logrus.Entry, cfg *config.RedisConfig) (*redis.Redis, error) {
+// ConnectRedis не падает, если redis недоступен: сервис стартует без него и переподключается в фоне
+func ConnectRedis(logger *logrus.Entry, cfg *config.RedisConfig, reg prometheus.Registerer) *redis.Redis {
 	logger.Infoln("Connecting to redis")
-	r, err := redis.Connect(cfg)
-	if err != nil {
-		logger.Errorf("Error connecting to redis: %v", err)
-		return nil, err
+	r := redis.New(logger, cfg, reg)
+	if r.Available() {
+		logger.Infoln("Redis connected")
 	}
-	logger.Infoln("Redis connected")
-	return r, nil
+	return r
 }
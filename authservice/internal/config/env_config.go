@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 
@@ -17,12 +18,24 @@ type AppConfig struct {
 	NewRelic          *NewRelic
 	Sentry            *Sentry
 	Redis             *RedisConfig
+	Cache             *CacheConfig
 	Tokens            *TokenConfig
 	SmptConfig        *SmptConfig
+	MailTransport     *MailTransportConfig
+	MailTemplates     *MailTemplateConfig
+	Dkim              *DkimConfig
+	MailBounce        *MailBounceConfig
+	MailQueue         *MailQueueConfig
 	EmailVerification *EmailVerificationCfg
 	ResetPassword     *ResetPasswordVerificationCfg
 	AppInfo           *AppInfo
 	LocalizerConfig   *LocalizerConfig
+	Kafka             *KafkaConfig
+	Outbox            *OutboxConfig
+	Grpc              *GrpcConfig
+	Registration      *RegistrationConfig
+	Problem           *ProblemConfig
+	RateLimit         *RateLimitConfig
 }
 
 type NewRelic struct {
@@ -48,12 +61,35 @@ type RedisConfig struct {
 	Port       string `env:"AUTH_REDIS_PORT" envDefault:"6379"`
 	DB         int    `env:"AUTH_REDIS_DB" envDefault:"0"`
 	Expiration int    `env:"AUTH_REDIS_EXPIRATION" envDefault:"5"`
+	// FailureThreshold после стольких ошибок подряд запросы в redis приостанавливаются до переподключения
+	FailureThreshold int `env:"AUTH_REDIS_FAILURE_THRESHOLD, default=3"`
+	// CheckSeconds как часто проверять соединение с redis
+	CheckSeconds int `env:"AUTH_REDIS_CHECK_SECONDS, default=5"`
+	// FallbackSize размер резервного кеша в памяти на время недоступности redis. 0 - выключен
+	FallbackSize int `env:"AUTH_REDIS_FALLBACK_SIZE, default=0"`
+	// InvalidationChannel канал, через который реплики сообщают друг другу об изменении ключей,
+	// чтобы убрать их из резервного кеша. Пустой - шина выключена
+	InvalidationChannel string `env:"AUTH_REDIS_INVALIDATION_CHANNEL, default=auth:cache:invalidate"`
+}
+
+// CacheConfig кеш сущностей в redis. Срок жизни записей - AUTH_REDIS_EXPIRATION
+type CacheConfig struct {
+	// Version входит в ключи, увеличивается при изменении формата кешируемых сущностей
+	Version int `env:"AUTH_CACHE_VERSION, default=1"`
+	// NegativeSeconds сколько помнить, что сущности нет. 0 - не кешировать отсутствие
+	NegativeSeconds int `env:"AUTH_CACHE_NEGATIVE_SECONDS, default=30"`
+	// JitterPercent случайный разброс срока жизни записей в процентах
+	JitterPercent int `env:"AUTH_CACHE_JITTER_PERCENT, default=10"`
 }
 
 type TokenConfig struct {
-	Secret                       string `env:"JWT_SECRET" envDefault:""`
-	TokenExpirationMinute        int    `env:"TOKEN_EXPIRATION_MINUTES" envDefault:"15"`
-	RefreshTokenExpirationMinute int    `env:"REFRESH_TOKEN_EXPIRATION_MINUTES" envDefault:"36000"`
+	Secret                        string `env:"JWT_SECRET" envDefault:""`
+	TokenExpirationMinute         int    `env:"TOKEN_EXPIRATION_MINUTES" envDefault:"15"`
+	RefreshTokenExpirationMinute  int    `env:"REFRESH_TOKEN_EXPIRATION_MINUTES" envDefault:"36000"`
+	EmbedPermissions              bool   `env:"JWT_EMBED_PERMISSIONS, default=true"`
+	ImpersonationExpirationMinute int    `env:"IMPERSONATION_TOKEN_EXPIRATION_MINUTES, default=10"`
+	// RevocationFailClosed считать токены отозванными, если хранилище отзывов недоступно
+	RevocationFailClosed bool `env:"TOKEN_REVOCATION_FAIL_CLOSED, default=false"`
 }
 
 type SmptConfig struct {
@@ -62,6 +98,88 @@ type SmptConfig struct {
 	Username string `env:"SMTP_USERNAME" envDefault:""`
 	Password string `env:"SMTP_PASSWORD" envDefault:""`
 	From     string `env:"SMTP_FROM" envDefault:"test@test.com"`
+	// TLS opportunistic (STARTTLS, если сервер его поддерживает), starttls (только с STARTTLS),
+	// tls (implicit TLS, обычно порт 465) или none (только для локального SMTP)
+	TLS                string `env:"SMTP_TLS, default=opportunistic"`
+	PoolSize           int    `env:"SMTP_POOL_SIZE, default=4"`
+	IdleTimeoutSeconds int    `env:"SMTP_IDLE_TIMEOUT_SECONDS, default=30"`
+}
+
+type MailTransportConfig struct {
+	// Transport smtp, file (письма складываются в FileDir в формате Maildir)
+	// или memory (письма в памяти процесса, просмотр через /dev/mail)
+	Transport   string `env:"MAIL_TRANSPORT, default=smtp"`
+	FileDir     string `env:"MAIL_FILE_DIR, default=./mail-out"`
+	MemoryLimit int    `env:"MAIL_MEMORY_LIMIT, default=100"`
+	// DevInspector включает /dev/mail для транспорта memory. Доступ только с разрешением mail:manage
+	DevInspector bool `env:"MAIL_DEV_INSPECTOR, default=false"`
+}
+
+// DkimConfig подпись писем включается, если задан KeyFile. Domain по умолчанию - домен SMTP_FROM
+type DkimConfig struct {
+	Domain   string `env:"MAIL_DKIM_DOMAIN"`
+	Selector string `env:"MAIL_DKIM_SELECTOR, default=default"`
+	KeyFile  string `env:"MAIL_DKIM_KEY_FILE"`
+}
+
+// ProblemConfig ответы об ошибках в формате RFC 7807. Клиенты с Accept: application/problem+json
+// получают его и без Enabled
+type ProblemConfig struct {
+	Enabled  bool   `env:"AUTH_PROBLEM_JSON, default=false"`
+	TypeBase string `env:"AUTH_PROBLEM_TYPE_BASE, default=/problems/"`
+}
+
+// RateLimitConfig ограничения частоты публичных запросов, которые проверяют пароль без входа.
+// Счетчики в памяти процесса, 0 - ограничение выключено
+type RateLimitConfig struct {
+	WindowSeconds     int `env:"AUTH_RATE_LIMIT_WINDOW_SECONDS, default=900"`
+	BanAppealPerEmail int `env:"AUTH_RATE_LIMIT_BAN_APPEAL_PER_EMAIL, default=5"`
+	BanAppealPerIp    int `env:"AUTH_RATE_LIMIT_BAN_APPEAL_PER_IP, default=20"`
+	// ResetPasswordPerEmail и ResetPasswordPerIp запросы кода сброса пароля за окно
+	ResetPasswordPerEmail int `env:"AUTH_RATE_LIMIT_RESET_PASSWORD_PER_EMAIL, default=3"`
+	ResetPasswordPerIp    int `env:"AUTH_RATE_LIMIT_RESET_PASSWORD_PER_IP, default=20"`
+}
+
+type MailBounceConfig struct {
+	// WebhookToken токен провайдера для POST /api/v1/mail/bounces. Пустой - прием возвратов выключен
+	WebhookToken string `env:"MAIL_BOUNCE_WEBHOOK_TOKEN"`
+}
+
+type MailTemplateConfig struct {
+	Dir string `env:"AUTH_MAIL_TEMPLATES_DIR, default=./templates/mail"`
+	// DefaultLocale локаль писем, если для языка пользователя нет шаблонов
+	DefaultLocale string `env:"AUTH_MAIL_DEFAULT_LOCALE, default=en"`
+}
+
+type MailQueueConfig struct {
+	IntervalSeconds int `env:"MAIL_QUEUE_INTERVAL_SECONDS, default=2"`
+	BatchSize       int `env:"MAIL_QUEUE_BATCH_SIZE, default=50"`
+	// Workers сколько писем отправляется параллельно
+	Workers int `env:"MAIL_QUEUE_WORKERS, default=4"`
+	// MaxAttempts после стольких неудачных попыток письмо переходит в dead
+	MaxAttempts       int `env:"MAIL_QUEUE_MAX_ATTEMPTS, default=8"`
+	MaxBackoffSeconds int `env:"MAIL_QUEUE_MAX_BACKOFF_SECONDS, default=900"`
+	// LeaseSeconds через сколько захваченное, но не отмеченное письмо снова попадет в очередь
+	LeaseSeconds int `env:"MAIL_QUEUE_LEASE_SECONDS, default=120"`
+	// SendTimeoutSeconds предельное время отправки одного письма, должно быть меньше LeaseSeconds,
+	// иначе письмо могут захватить и отправить повторно, пока идет первая отправка
+	SendTimeoutSeconds int `env:"MAIL_QUEUE_SEND_TIMEOUT_SECONDS, default=60"`
+	// RetentionHours сколько хранятся сведения о доставленных письмах. Тексты писем стираются сразу после доставки
+	RetentionHours int `env:"MAIL_QUEUE_RETENTION_HOURS, default=168"`
+	// DeadRetentionHours сколько хранятся недоставленные письма, которые можно отправить повторно
+	DeadRetentionHours int `env:"MAIL_QUEUE_DEAD_RETENTION_HOURS, default=72"`
+}
+
+// Validate отправка письма должна укладываться в lease
+func (c *MailQueueConfig) Validate() error {
+	if c.SendTimeoutSeconds <= 0 || c.SendTimeoutSeconds >= c.LeaseSeconds {
+		return fmt.Errorf(
+			"MAIL_QUEUE_SEND_TIMEOUT_SECONDS=%d должен быть больше 0 и меньше MAIL_QUEUE_LEASE_SECONDS=%d",
+			c.SendTimeoutSeconds,
+			c.LeaseSeconds,
+		)
+	}
+	return nil
 }
 
 type AppInfo struct {
@@ -72,18 +190,101 @@ type AppInfo struct {
 
 type LocalizerConfig struct {
 	DirFiles string `env:"AUTH_LOCALIZER_DIR_FILES" envDefault:"./locales"`
+	// ReloadSeconds как часто проверять изменения файлов переводов, 0 - не перечитывать
+	ReloadSeconds int `env:"AUTH_LOCALIZER_RELOAD_SECONDS, default=10"`
+	// DefaultLanguage язык ответов, если Accept-Language не подошел ни к одному из переводов
+	DefaultLanguage string `env:"AUTH_LOCALIZER_DEFAULT_LANG, default=en"`
 }
 
 type EmailVerificationCfg struct {
 	CodeExpiredMinute int `env:"EMAIL_VERIFICATION_CODE_EXPIRED_MINUTE" envDefault:"10"`
+	// ResendCooldownSeconds минимальный интервал между отправками кода одному пользователю
+	ResendCooldownSeconds int `env:"EMAIL_VERIFICATION_RESEND_COOLDOWN_SECONDS, default=60"`
+	// MaxActiveCodes сколько кодов можно запросить за время жизни кода
+	MaxActiveCodes int `env:"EMAIL_VERIFICATION_MAX_ACTIVE_CODES, default=5"`
+	// MaxAttempts неверных попыток ввода, после которых код аннулируется
+	MaxAttempts int `env:"EMAIL_VERIFICATION_MAX_ATTEMPTS, default=5"`
 }
 
 type ResetPasswordVerificationCfg struct {
 	CodeExpiredMinute int `env:"RESET_PASSWORD_VERIFICATION_CODE_EXPIRED_MINUTE" envDefault:"10"`
+	// MaxAttempts неверных попыток ввода, после которых код аннулируется
+	MaxAttempts int `env:"RESET_PASSWORD_MAX_ATTEMPTS, default=5"`
+	// Secret ключ подписи токенов из ссылки сброса. Не должен совпадать с JWT_SECRET
+	Secret string `env:"RESET_PASSWORD_SECRET"`
+}
+
+// Validate токены сброса подписываются своим ключом, чтобы подпись одного вида токенов не подходила к другому
+func (c *ResetPasswordVerificationCfg) Validate(jwtSecret string) error {
+	if c.Secret == "" {
+		return fmt.Errorf("не задан RESET_PASSWORD_SECRET")
+	}
+	if c.Secret == jwtSecret {
+		return fmt.Errorf("RESET_PASSWORD_SECRET не должен совпадать с JWT_SECRET")
+	}
+	return nil
+}
+
+type KafkaConfig struct {
+	// Broker kafka или memory (события остаются в памяти процесса, для локального запуска)
+	Broker          string   `env:"AUTH_EVENT_BROKER, default=kafka"`
+	Brokers         []string `env:"KAFKA_BROKERS, default=localhost:9092"`
+	UserEventsTopic string   `env:"KAFKA_USER_EVENTS_TOPIC, default=auth.user-events"`
+}
+
+type OutboxConfig struct {
+	IntervalSeconds   int `env:"OUTBOX_INTERVAL_SECONDS, default=2"`
+	BatchSize         int `env:"OUTBOX_BATCH_SIZE, default=100"`
+	MaxBackoffSeconds int `env:"OUTBOX_MAX_BACKOFF_SECONDS, default=300"`
+	RetentionHours    int `env:"OUTBOX_RETENTION_HOURS, default=168"`
+}
+
+type GrpcConfig struct {
+	Port string `env:"AUTH_GRPC_PORT, default=9091"`
+	// ServiceTokens токены сервисов, которым разрешено вызывать gRPC API
+	ServiceTokens []string `env:"AUTH_GRPC_SERVICE_TOKENS"`
+	// TLSCertFile и TLSKeyFile сертификат сервера. Без них сервер запускается, только если Insecure
+	TLSCertFile string `env:"AUTH_GRPC_TLS_CERT_FILE"`
+	TLSKeyFile  string `env:"AUTH_GRPC_TLS_KEY_FILE"`
+	// Insecure разрешить gRPC без TLS, только для локального запуска
+	Insecure bool `env:"AUTH_GRPC_INSECURE, default=false"`
+	// HealthCheckSeconds как часто обновлять статус gRPC health по доступности postgres
+	HealthCheckSeconds int `env:"AUTH_GRPC_HEALTH_CHECK_SECONDS, default=5"`
+}
+
+const (
+	RegistrationModeOpen   = "open"
+	RegistrationModeInvite = "invite"
+	RegistrationModeDomain = "domain"
+)
+
+type RegistrationConfig struct {
+	// Mode open, invite (только по инвайт-коду) или domain (только email из AllowedDomains или по инвайт-коду)
+	Mode           string   `env:"REGISTRATION_MODE, default=open"`
+	AllowedDomains []string `env:"REGISTRATION_ALLOWED_DOMAINS"`
+}
+
+// Validate неизвестный режим регистрации - ошибка конфигурации, а не открытая регистрация
+func (c *RegistrationConfig) Validate() error {
+	switch c.Mode {
+	case RegistrationModeOpen, RegistrationModeInvite, RegistrationModeDomain:
+		return nil
+	}
+	return fmt.Errorf("неизвестный режим регистрации REGISTRATION_MODE=%q, допустимо: open, invite, domain", c.Mode)
 }
 
 func Config() *AppConfig {
-	return config.LoadEnvConfig(&cfg)
+	res := config.LoadEnvConfig(&cfg)
+	if err := res.Registration.Validate(); err != nil {
+		log.Fatalln(err)
+	}
+	if err := res.ResetPassword.Validate(res.Tokens.Secret); err != nil {
+		log.Fatalln(err)
+	}
+	if err := res.MailQueue.Validate(); err != nil {
+		log.Fatalln(err)
+	}
+	return res
 }
 
 func LoadEnv(envPath string) {
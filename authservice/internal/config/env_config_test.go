@@ -0,0 +1,39 @@
+package config
+
+import "testing"
+
+func TestRegistrationConfigValidate(t *testing.T) {
+	for _, mode := range []string{RegistrationModeOpen, RegistrationModeInvite, RegistrationModeDomain} {
+		if err := (&RegistrationConfig{Mode: mode}).Validate(); err != nil {
+			t.Fatalf("режим %q: %v", mode, err)
+		}
+	}
+	for _, mode := range []string{"", "closed", "Invite"} {
+		if err := (&RegistrationConfig{Mode: mode}).Validate(); err == nil {
+			t.Fatalf("режим %q должен быть отклонен", mode)
+		}
+	}
+}
+
+func TestResetPasswordConfigValidate(t *testing.T) {
+	if err := (&ResetPasswordVerificationCfg{Secret: "reset"}).Validate("jwt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&ResetPasswordVerificationCfg{}).Validate("jwt"); err == nil {
+		t.Fatal("пустой ключ должен быть отклонен")
+	}
+	if err := (&ResetPasswordVerificationCfg{Secret: "jwt"}).Validate("jwt"); err == nil {
+		t.Fatal("ключ, совпадающий с JWT_SECRET, должен быть отклонен")
+	}
+}
+
+func TestMailQueueConfigValidate(t *testing.T) {
+	if err := (&MailQueueConfig{LeaseSeconds: 120, SendTimeoutSeconds: 60}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, timeout := range []int{0, 120, 300} {
+		if err := (&MailQueueConfig{LeaseSeconds: 120, SendTimeoutSeconds: timeout}).Validate(); err == nil {
+			t.Fatalf("SendTimeoutSeconds=%d должен быть отклонен", timeout)
+		}
+	}
+}
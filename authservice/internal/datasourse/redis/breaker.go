@@ -0,0 +1,56 @@
+package redis
+
+import "sync"
+
+// breaker размыкается после threshold ошибок подряд. Пока он разомкнут, запросы в redis не идут.
+// Замыкает его только фоновая проверка соединения
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	failures  int
+	open      bool
+}
+
+func newBreaker(threshold int) *breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &breaker{
+		threshold: threshold,
+	}
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.open
+}
+
+// failure учитывает ошибку. true - breaker только что разомкнулся
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.open || b.failures < b.threshold {
+		return false
+	}
+	b.open = true
+	return true
+}
+
+// trip размыкает breaker сразу, например если redis недоступен при старте
+func (b *breaker) trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.open = true
+}
+
+// reset сбрасывает счетчик ошибок. true - breaker был разомкнут
+func (b *breaker) reset() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasOpen := b.open
+	b.open = false
+	b.failures = 0
+	return wasOpen
+}
@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// invalidation сообщение шины инвалидации: ключи, которые реплики должны убрать из кеша в памяти
+type invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// publish сообщает остальным репликам об изменении ключа. Публикуют только версионные операции,
+// то есть ключи кеша, и только при включенном резервном кеше: без него подписчиков нет.
+// Ошибка публикации не критична: записи в памяти реплик живут не дольше срока кеша
+// и сбрасываются после переподключения
+func (r *Redis) publish(keys ...string) {
+	if r.channel == "" || r.fallback == nil {
+		return
+	}
+	data, err := json.Marshal(invalidation{Origin: r.instanceId, Keys: keys})
+	if err != nil {
+		return
+	}
+	if err := r.do(func() error {
+		return r.cli.Publish(r.channel, string(data)).Err()
+	}); err != nil {
+		r.log.Debugf("не удалось опубликовать инвалидацию %v: %v", keys, err)
+	}
+}
+
+// subscribe слушает шину инвалидации и убирает ключи из резервного кеша. После (пере)подписки
+// резервный кеш очищается целиком, потому что сообщения за время разрыва потеряны
+func (r *Redis) subscribe() {
+	ps := r.cli.Subscribe(r.channel)
+	go func() {
+		<-r.stop
+		_ = ps.Close()
+	}()
+
+	for {
+		msg, err := ps.Receive()
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+		if err != nil {
+			r.log.Debugf("ошибка получения инвалидаций из redis: %v", err)
+			time.Sleep(r.checkInterval)
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			r.fallback.clear()
+		case *redis.Message:
+			var inv invalidation
+			if err := json.Unmarshal([]byte(m.Payload), &inv); err != nil {
+				r.log.Warnf("некорректное сообщение инвалидации: %v", err)
+				continue
+			}
+			if inv.Origin == r.instanceId {
+				continue
+			}
+			for _, key := range inv.Keys {
+				r.fallback.del(key)
+			}
+		}
+	}
+}
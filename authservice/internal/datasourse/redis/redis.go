@@ -2,64 +2,370 @@ package redis
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/EddyZe/foodApp/authservice/internal/config"
 	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
 )
 
+// maxPendingWrites сколько записей, не дошедших до redis, хранится до его восстановления
+const maxPendingWrites = 10000
+
+// ErrUnavailable redis недоступен (breaker разомкнут), значения нет и в резервном кеше
+var ErrUnavailable = errors.New("redis недоступен")
+
+// pendingWrite запись или удаление, которые повторятся в redis после восстановления
+type pendingWrite struct {
+	value     string
+	expiresAt time.Time
+	deleted   bool
+	// versioned запись через SetVersioned/DelVersioned, повторяется как удаление с увеличением версии
+	versioned bool
+}
+
 type Redis struct {
-	expiration time.Duration
-	cli        *redis.Client
+	log           *logrus.Entry
+	expiration    time.Duration
+	checkInterval time.Duration
+	cli           *redis.Client
+	breaker       *breaker
+	// available метрика redis_available
+	available prometheus.Gauge
+	// fallback nil, если резервный кеш выключен
+	fallback *lru
+	// channel канал шины инвалидации, пустой - шина выключена
+	channel    string
+	instanceId string
+
+	mu      sync.Mutex
+	pending map[string]pendingWrite
+
+	stop      chan struct{}
+	closeOnce sync.Once
 }
 
+// New клиент redis, который не требует доступности redis при старте. Пока redis недоступен,
+// чтения идут в резервный кеш (если он включен), а записи копятся и повторяются после переподключения.
+// Метрика redis_available регистрируется в reg, nil - не регистрируется
+func New(log *logrus.Entry, cfg *config.RedisConfig, reg prometheus.Registerer) *Redis {
+	r := newRedis(log, cfg)
+	if reg != nil {
+		gauge, err := registerGauge(reg, r.available)
+		if err != nil {
+			log.Errorf("метрика redis_available не зарегистрирована: %v", err)
+		} else {
+			r.available = gauge
+		}
+	}
+	if err := r.cli.Ping().Err(); err != nil {
+		r.breaker.trip()
+		r.available.Set(0)
+		log.Warnf("redis недоступен, сервис запущен без него: %v", err)
+	} else {
+		r.available.Set(1)
+	}
+	go r.monitor()
+	if r.channel != "" && r.fallback != nil {
+		go r.subscribe()
+	}
+
+	return r
+}
+
+// Connect клиент redis, требующий доступности redis при подключении
 func Connect(cfg *config.RedisConfig) (*Redis, error) {
+	r := newRedis(logrus.NewEntry(logrus.StandardLogger()), cfg)
+	if err := r.cli.Ping().Err(); err != nil {
+		_ = r.cli.Close()
+		return nil, err
+	}
+	r.available.Set(1)
+	go r.monitor()
+
+	return r, nil
+}
+
+// registerGauge регистрирует g в reg. Если такая метрика уже зарегистрирована (второй клиент в том же
+// процессе), возвращает существующую
+func registerGauge(reg prometheus.Registerer, g prometheus.Gauge) (prometheus.Gauge, error) {
+	err := reg.Register(g)
+	if err == nil {
+		return g, nil
+	}
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		if existing, ok := registered.ExistingCollector.(prometheus.Gauge); ok {
+			return existing, nil
+		}
+	}
+	return nil, err
+}
+
+func newRedis(log *logrus.Entry, cfg *config.RedisConfig) *Redis {
 	r := &Redis{
-		expiration: time.Duration(cfg.Expiration) * time.Minute,
+		log:           log,
+		expiration:    time.Duration(cfg.Expiration) * time.Minute,
+		checkInterval: time.Duration(cfg.CheckSeconds) * time.Second,
 		cli: redis.NewClient(&redis.Options{
 			Addr:       fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
 			Password:   cfg.Password,
 			DB:         cfg.DB,
 			MaxRetries: 5,
 		}),
+		breaker: newBreaker(cfg.FailureThreshold),
+		available: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "redis_available",
+			Help: "1 if redis is reachable, 0 while the circuit breaker is open",
+		}),
+		channel:    cfg.InvalidationChannel,
+		instanceId: uuid.New().String(),
+		pending:    make(map[string]pendingWrite),
+		stop:       make(chan struct{}),
 	}
-	if err := r.cli.Ping().Err(); err != nil {
-		return nil, err
+	if r.checkInterval <= 0 {
+		r.checkInterval = 5 * time.Second
 	}
+	if cfg.FallbackSize > 0 {
+		r.fallback = newLru(cfg.FallbackSize)
+	}
+	return r
+}
 
-	return r, nil
+// Available redis доступен: breaker замкнут
+func (r *Redis) Available() bool {
+	return r.breaker.allow()
 }
 
 func (r *Redis) Put(key string, value interface{}) error {
 	return r.PutEx(key, value, r.expiration)
 }
 
+// PutEx при недоступном redis запись откладывается до переподключения и ошибка не возвращается
 func (r *Redis) PutEx(key string, value interface{}, expiration time.Duration) error {
-	cli := r.cli
 	jsonValue, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
-	res := cli.Set(key, string(jsonValue), expiration)
-	return res.Err()
+	if r.fallback != nil {
+		r.fallback.put(key, string(jsonValue), expiration)
+	}
+
+	err = r.do(func() error {
+		return r.cli.Set(key, string(jsonValue), expiration).Err()
+	})
+	if err == nil {
+		r.forget(key)
+		return nil
+	}
+
+	var expiresAt time.Time
+	if expiration > 0 {
+		expiresAt = time.Now().Add(expiration)
+	}
+	return r.postpone(key, pendingWrite{value: string(jsonValue), expiresAt: expiresAt}, err)
+}
+
+// PutExNow в отличие от PutEx не откладывает запись: если redis недоступен, возвращается ошибка.
+// Для записей, потерю которых нельзя допустить: отложенная запись пропадет при перезапуске сервиса
+func (r *Redis) PutExNow(key string, value interface{}, expiration time.Duration) error {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	if err := r.do(func() error {
+		return r.cli.Set(key, string(jsonValue), expiration).Err()
+	}); err != nil {
+		return err
+	}
+	if r.fallback != nil {
+		r.fallback.put(key, string(jsonValue), expiration)
+	}
+	r.forget(key)
+	return nil
 }
 
 func (r *Redis) Get(key string) (string, bool) {
-	cli := r.cli
-	res := cli.Get(key)
-	if res.Err() != nil {
-		return "", false
+	val, ok, _ := r.Lookup(key)
+	return val, ok
+}
+
+// Lookup в отличие от Get возвращает ошибку, если redis недоступен и значения нет в резервном кеше.
+// Отсутствие ключа - не ошибка
+func (r *Redis) Lookup(key string) (string, bool, error) {
+	var val string
+	err := r.do(func() error {
+		res := r.cli.Get(key)
+		val = res.Val()
+		return res.Err()
+	})
+	switch {
+	case err == nil:
+		if r.fallback != nil {
+			r.fallback.put(key, val, r.expiration)
+		}
+		return val, true, nil
+	case errors.Is(err, redis.Nil):
+		return "", false, nil
 	}
-	return res.Val(), true
+
+	if r.fallback != nil {
+		if val, ok := r.fallback.get(key); ok {
+			return val, true, nil
+		}
+	}
+	return "", false, err
 }
 
+// Del при недоступном redis удаление откладывается до переподключения и ошибка не возвращается
 func (r *Redis) Del(key string) error {
-	cli := r.cli
-	res := cli.Del(key)
-	return res.Err()
+	if r.fallback != nil {
+		r.fallback.del(key)
+	}
+
+	err := r.do(func() error {
+		return r.cli.Del(key).Err()
+	})
+	if err == nil {
+		r.forget(key)
+		return nil
+	}
+	return r.postpone(key, pendingWrite{deleted: true}, err)
 }
 
 func (r *Redis) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
 	return r.cli.Close()
 }
+
+// do выполняет запрос, если breaker замкнут. redis.Nil ошибкой соединения не считается
+func (r *Redis) do(op func() error) error {
+	if !r.breaker.allow() {
+		return ErrUnavailable
+	}
+
+	err := op()
+	if err == nil || errors.Is(err, redis.Nil) {
+		r.breaker.reset()
+		return err
+	}
+	if r.breaker.failure() {
+		r.available.Set(0)
+		r.log.Errorf("redis недоступен, запросы к нему приостановлены до переподключения: %v", err)
+	}
+	return err
+}
+
+func (r *Redis) postpone(key string, w pendingWrite, cause error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pending[key]; !ok && len(r.pending) >= maxPendingWrites {
+		return cause
+	}
+	r.pending[key] = w
+	return nil
+}
+
+// forget убирает отложенную запись ключа, если новое значение уже записано в redis
+func (r *Redis) forget(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, key)
+}
+
+// monitor проверяет соединение и после восстановления замыкает breaker и повторяет отложенные записи
+func (r *Redis) monitor() {
+	ticker := time.NewTicker(r.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.check()
+		}
+	}
+}
+
+func (r *Redis) check() {
+	if err := r.cli.Ping().Err(); err != nil {
+		if r.breaker.failure() {
+			r.available.Set(0)
+			r.log.Errorf("redis недоступен, запросы к нему приостановлены до переподключения: %v", err)
+		}
+		return
+	}
+
+	if err := r.flush(); err != nil {
+		r.log.Warnf("не удалось повторить отложенные записи в redis: %v", err)
+		return
+	}
+	if r.breaker.reset() {
+		r.available.Set(1)
+		if r.fallback != nil {
+			r.fallback.clear()
+		}
+		r.log.Info("соединение с redis восстановлено")
+	}
+}
+
+// flush повторяет отложенные записи. Записи с истекшим сроком удаляются из redis
+func (r *Redis) flush() error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[string]pendingWrite)
+	r.mu.Unlock()
+	total := len(pending)
+
+	for key, w := range pending {
+		var ttl time.Duration
+		if !w.expiresAt.IsZero() {
+			ttl = time.Until(w.expiresAt)
+		}
+		// запись с истекшим сроком превращается в удаление
+		expired := !w.expiresAt.IsZero() && ttl <= 0
+
+		var err error
+		switch {
+		case w.versioned:
+			// версионные записи откладываются только как удаления, см. SetVersioned
+			err = r.delVersioned(key)
+			if err == nil {
+				r.publish(key)
+			}
+		case w.deleted || expired:
+			err = r.cli.Del(key).Err()
+		default:
+			err = r.cli.Set(key, w.value, ttl).Err()
+		}
+		if err != nil {
+			r.requeue(pending)
+			return err
+		}
+		delete(pending, key)
+	}
+
+	if total > 0 {
+		r.log.Infof("в redis повторено отложенных записей: %d", total)
+	}
+	return nil
+}
+
+// requeue возвращает неповторенные записи в очередь. Более новые записи тех же ключей не затираются
+func (r *Redis) requeue(pending map[string]pendingWrite) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, w := range pending {
+		if _, ok := r.pending[key]; !ok {
+			r.pending[key] = w
+		}
+	}
+}
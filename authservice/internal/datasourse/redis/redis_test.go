@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+func TestUnavailableWithFallback(t *testing.T) {
+	r := New(logrus.NewEntry(logrus.New()), &config.RedisConfig{
+		Host:             "127.0.0.1",
+		Port:             "1",
+		Expiration:       5,
+		FailureThreshold: 3,
+		CheckSeconds:     60,
+		FallbackSize:     2,
+	}, nil)
+	defer r.Close()
+
+	if r.Available() {
+		t.Fatal("redis на закрытом порту не должен считаться доступным")
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := r.Put(key, key); err != nil {
+			t.Errorf("запись при недоступном redis должна откладываться, получено %v", err)
+		}
+	}
+	if val, ok, err := r.Lookup("c"); err != nil || !ok || val != `"c"` {
+		t.Errorf("значение должно читаться из резервного кеша: %q %v %v", val, ok, err)
+	}
+	if _, _, err := r.Lookup("a"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("вытесненный ключ должен давать ErrUnavailable, получено %v", err)
+	}
+
+	if err := r.Del("c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Get("c"); ok {
+		t.Error("удаленный ключ не должен читаться из резервного кеша")
+	}
+	if len(r.pending) != 3 || !r.pending["c"].deleted {
+		t.Errorf("отложенные записи: %+v", r.pending)
+	}
+
+	if err := r.SetVersioned("b", "b2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if w := r.pending["b"]; !w.versioned || !w.deleted {
+		t.Errorf("версионная запись должна откладываться как удаление, чтобы не перезаписать более новое значение: %+v", w)
+	}
+	if val, ok := r.Get("b"); !ok || val != `"b2"` {
+		t.Errorf("до переподключения значение должно читаться из резервного кеша: %q %v", val, ok)
+	}
+	if _, err := r.Version("b"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("версия при недоступном redis неизвестна, получено %v", err)
+	}
+
+	// отзыв токенов не откладывается: отложенная запись пропала бы при перезапуске
+	if err := r.PutExNow("revoked", true, 0); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("ожидалась ErrUnavailable, получено %v", err)
+	}
+	if _, ok := r.pending["revoked"]; ok {
+		t.Error("PutExNow не должен откладывать запись")
+	}
+}
+
+func TestRegisterGauge(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	first := New(logrus.NewEntry(logrus.New()), &config.RedisConfig{Host: "127.0.0.1", Port: "1", CheckSeconds: 60}, reg)
+	defer first.Close()
+	// второй клиент в том же процессе не должен паниковать на повторной регистрации
+	second := New(logrus.NewEntry(logrus.New()), &config.RedisConfig{Host: "127.0.0.1", Port: "1", CheckSeconds: 60}, reg)
+	defer second.Close()
+
+	if first.available != second.available {
+		t.Error("клиенты должны использовать одну метрику redis_available")
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker(2)
+	if b.failure() || !b.allow() {
+		t.Error("одна ошибка не должна размыкать breaker")
+	}
+	if !b.failure() || b.allow() {
+		t.Error("breaker должен разомкнуться после порога ошибок")
+	}
+	if !b.reset() || !b.allow() {
+		t.Error("reset должен замкнуть breaker")
+	}
+}
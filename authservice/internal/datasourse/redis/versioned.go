@@ -0,0 +1,155 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	versionSuffix = ":ver"
+	// versionExpiration срок жизни версии ключа. После него версия считается нулевой, что безопасно:
+	// загрузка, начатая со старой версией, все равно не запишется
+	versionExpiration = time.Hour
+)
+
+var (
+	compareAndSetScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[2])
+if not v then v = '0' end
+if v ~= ARGV[1] then return 0 end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+	setVersionedScript = redis.NewScript(`
+redis.call('INCR', KEYS[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+if tonumber(ARGV[2]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+	delVersionedScript = redis.NewScript(`
+redis.call('INCR', KEYS[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[1])
+return redis.call('DEL', KEYS[1])
+`)
+)
+
+func versionKey(key string) string {
+	return key + versionSuffix
+}
+
+// Version текущая версия ключа, 0 - ключ не менялся
+func (r *Redis) Version(key string) (int64, error) {
+	var version int64
+	err := r.do(func() error {
+		v, err := r.cli.Get(versionKey(key)).Int64()
+		version = v
+		return err
+	})
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return version, err
+}
+
+// CompareAndSet записывает значение, только если версия ключа все еще равна version.
+// Версия при этом не меняется: запись загруженного значения не считается изменением
+func (r *Redis) CompareAndSet(key string, version int64, value interface{}, expiration time.Duration) (bool, error) {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	var stored bool
+	err = r.do(func() error {
+		res, err := compareAndSetScript.Run(
+			r.cli,
+			[]string{key, versionKey(key)},
+			strconv.FormatInt(version, 10),
+			string(jsonValue),
+			expiration.Milliseconds(),
+		).Int64()
+		stored = res == 1
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if stored && r.fallback != nil {
+		r.fallback.put(key, string(jsonValue), expiration)
+	}
+	return stored, nil
+}
+
+// SetVersioned записывает значение и увеличивает версию ключа. Остальные реплики получают инвалидацию.
+// При недоступном redis значение остается только в резервном кеше, а в redis ключ будет удален после переподключения
+func (r *Redis) SetVersioned(key string, value interface{}, expiration time.Duration) error {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if r.fallback != nil {
+		r.fallback.put(key, string(jsonValue), expiration)
+	}
+
+	err = r.do(func() error {
+		return r.setVersioned(key, string(jsonValue), expiration)
+	})
+	if err == nil {
+		r.forget(key)
+		r.publish(key)
+		return nil
+	}
+
+	// откладывается удаление, а не запись: к переподключению значение могло устареть,
+	// а повтор записи перезаписал бы более новое. Кеш загрузит актуальное значение сам
+	return r.postpone(key, pendingWrite{deleted: true, versioned: true}, err)
+}
+
+// DelVersioned удаляет значение и увеличивает версию ключа. Остальные реплики получают инвалидацию
+func (r *Redis) DelVersioned(key string) error {
+	if r.fallback != nil {
+		r.fallback.del(key)
+	}
+
+	err := r.do(func() error {
+		return r.delVersioned(key)
+	})
+	if err == nil {
+		r.forget(key)
+		r.publish(key)
+		return nil
+	}
+	return r.postpone(key, pendingWrite{deleted: true, versioned: true}, err)
+}
+
+func (r *Redis) setVersioned(key, value string, expiration time.Duration) error {
+	return setVersionedScript.Run(
+		r.cli,
+		[]string{key, versionKey(key)},
+		value,
+		expiration.Milliseconds(),
+		versionExpiration.Milliseconds(),
+	).Err()
+}
+
+func (r *Redis) delVersioned(key string) error {
+	return delVersionedScript.Run(
+		r.cli,
+		[]string{key, versionKey(key)},
+		versionExpiration.Milliseconds(),
+	).Err()
+}
@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"time"
+)
+
+type UserSearchFilter struct {
+	Email          string     `form:"email" binding:"max=256"`
+	EmailConfirmed *bool      `form:"email_confirmed"`
+	Role           string     `form:"role" binding:"max=256"`
+	Banned         *bool      `form:"banned"`
+	CreatedFrom    *time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo      *time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Sort           string     `form:"sort" binding:"omitempty,oneof=id email created_at"`
+	Order          string     `form:"order" binding:"omitempty,oneof=asc desc"`
+	Limit          int        `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor         string     `form:"cursor"`
+}
+
+type UserDto struct {
+	Id             int64     `json:"id"`
+	Email          string    `json:"email"`
+	EmailIsConfirm bool      `json:"email_is_confirm"`
+	Roles          []string  `json:"roles"`
+	IsBanned       bool      `json:"is_banned"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type UserPage struct {
+	Items      []UserDto `json:"items"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+type SessionDto struct {
+	Id        int64     `json:"id"`
+	IssueAt   time.Time `json:"issue_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type AdminUserDetail struct {
+	User        UserDto              `json:"user"`
+	Roles       []entity.Role        `json:"roles"`
+	Sessions    []SessionDto         `json:"sessions"`
+	Ban         *entity.Ban          `json:"ban"`
+	AuditEvents []entity.Audit       `json:"audit_events"`
+	Consents    []entity.UserConsent `json:"consents"`
+}
+
+// UserDataExport данные пользователя, которые хранит сервис авторизации
+type UserDataExport struct {
+	User        UserDto              `json:"user"`
+	Sessions    []SessionDto         `json:"sessions"`
+	Bans        []BanDto             `json:"bans"`
+	Consents    []entity.UserConsent `json:"consents"`
+	AuditEvents []entity.Audit       `json:"audit_events"`
+	ExportedAt  time.Time            `json:"exported_at"`
+}
+
+type ImpersonationTokenDto struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	UserId      int64     `json:"user_id"`
+	ActorId     int64     `json:"actor_id"`
+}
@@ -5,6 +5,8 @@ import "time"
 type LoginDto struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// AcceptedDocuments id документов, принятых после ответа CONSENT_REQUIRED
+	AcceptedDocuments []int64 `json:"accepted_documents,omitempty" binding:"max=16"`
 }
 
 type RegisterDto struct {
@@ -12,6 +14,10 @@ type RegisterDto struct {
 	Password  string `json:"password" binding:"required,min=6"`
 	FirstName string `json:"first_name" biding:"min=2,max=35"`
 	LastName  string `json:"last_name" binding:"max=35"`
+	// InviteCode обязателен в режиме регистрации по приглашениям
+	InviteCode string `json:"invite_code,omitempty" binding:"max=64"`
+	// AcceptedDocuments id текущих версий соглашения и политики конфиденциальности
+	AcceptedDocuments []int64 `json:"accepted_documents,omitempty" binding:"max=16"`
 }
 
 type TokensDto struct {
@@ -26,20 +32,39 @@ type ConfirmEmail struct {
 }
 
 type BanUser struct {
-	UserId    int64  `json:"user_id" binding:"required"`
-	Cause     string `json:"cause" binding:"required"`
-	Days      int32  `json:"days,omitempty"`
-	IsForever bool   `json:"is_forever,omitempty"`
+	UserId         int64  `json:"user_id" binding:"required"`
+	Cause          string `json:"cause" binding:"required"`
+	ReasonCategory string `json:"reason_category,omitempty" binding:"omitempty,oneof=spam abuse fraud inappropriate_content other"`
+	Days           int32  `json:"days,omitempty"`
+	IsForever      bool   `json:"is_forever,omitempty"`
 }
 type UnBanUser struct {
-	UserId int64 `json:"user_id" binding:"required"`
+	UserId int64  `json:"user_id" binding:"required"`
+	Reason string `json:"reason,omitempty" binding:"max=1024"`
 }
 
 type ResetPassword struct {
 	Email string `json:"email" binding:"required,email"`
 }
 
+// EnterCodeResetPassword новый пароль по коду из письма вместе с email или по токену из ссылки
 type EnterCodeResetPassword struct {
-	Code        string `json:"code" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required"`
+	Email       string `json:"email,omitempty" binding:"required_without=Token,omitempty,email"`
+	Code        string `json:"code,omitempty" binding:"required_without=Token,max=64"`
+	Token       string `json:"token,omitempty" binding:"max=512"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// UpdateLocale пустой locale сбрасывает выбор, язык снова берется из запроса
+type UpdateLocale struct {
+	Locale string `json:"locale" binding:"max=16"`
+}
+
+type LocaleDto struct {
+	Locale    string   `json:"locale,omitempty"`
+	Available []string `json:"available"`
+}
+
+type DeleteAccount struct {
+	Password string `json:"password" binding:"required"`
 }
@@ -0,0 +1,31 @@
+package dto
+
+import "time"
+
+type BanDto struct {
+	Id             int64      `json:"id"`
+	IsForever      bool       `json:"is_forever"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiredAt      time.Time  `json:"expired_at"`
+	Cause          string     `json:"cause"`
+	ReasonCategory string     `json:"reason_category"`
+	IssuedBy       *int64     `json:"issued_by,omitempty"`
+	UnbannedAt     *time.Time `json:"unbanned_at,omitempty"`
+	UnbannedBy     *int64     `json:"unbanned_by,omitempty"`
+	UnbanReason    *string    `json:"unban_reason,omitempty"`
+}
+
+type CreateBanAppeal struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+	Message  string `json:"message" binding:"required,max=2048"`
+}
+
+type ReviewBanAppeal struct {
+	Approve *bool  `json:"approve" binding:"required"`
+	Comment string `json:"comment,omitempty" binding:"max=1024"`
+}
+
+type BanAppealFilter struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending approved rejected"`
+}
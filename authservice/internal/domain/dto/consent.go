@@ -0,0 +1,15 @@
+package dto
+
+import "github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+
+type PublishConsentDocument struct {
+	Type    string `json:"type" binding:"required,oneof=terms privacy"`
+	Version string `json:"version" binding:"required,max=64"`
+	Url     string `json:"url" binding:"required,url,max=1024"`
+}
+
+// ConsentRequiredDto детали ошибки CONSENT_REQUIRED: документы, которые нужно принять
+type ConsentRequiredDto struct {
+	DocumentIds []int64                  `json:"document_ids"`
+	Documents   []entity.ConsentDocument `json:"documents"`
+}
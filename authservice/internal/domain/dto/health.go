@@ -0,0 +1,14 @@
+package dto
+
+const (
+	HealthOk       = "ok"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+	HealthUp       = "up"
+)
+
+// Health ответ readiness probe: общий статус и состояние зависимостей
+type Health struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+type CreateInviteCode struct {
+	MaxUses   int        `json:"max_uses" binding:"required,min=1,max=100000"`
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+	Roles     []string   `json:"roles,omitempty" binding:"max=16,dive,required,max=256"`
+}
+
+type InviteCodeDto struct {
+	Id        int64      `json:"id"`
+	Code      string     `json:"code"`
+	MaxUses   int        `json:"max_uses"`
+	UsedCount int        `json:"used_count"`
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+	CreatedBy *int64     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	Roles     []string   `json:"roles"`
+}
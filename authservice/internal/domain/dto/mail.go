@@ -0,0 +1,23 @@
+package dto
+
+type MailMessageFilter struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending sending sent dead"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// BounceReport возвраты и жалобы от почтового провайдера
+type BounceReport struct {
+	Events []BounceEvent `json:"events" binding:"required,min=1,max=500,dive"`
+}
+
+type BounceEvent struct {
+	Type      string `json:"type" binding:"required,oneof=bounce complaint"`
+	Email     string `json:"email" binding:"required,email,max=256"`
+	Permanent bool   `json:"permanent"`
+	Reason    string `json:"reason" binding:"max=1024"`
+}
+
+type BounceReportResult struct {
+	Received   int `json:"received"`
+	Suppressed int `json:"suppressed"`
+}
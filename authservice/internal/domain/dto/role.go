@@ -0,0 +1,18 @@
+package dto
+
+type RoleDto struct {
+	Name        string `json:"name" binding:"required,max=256"`
+	Description string `json:"description" binding:"required,max=1024"`
+}
+
+type PermissionDto struct {
+	Name        string `json:"name" binding:"required,max=256"`
+	Description string `json:"description" binding:"required,max=1024"`
+}
+
+type RoleWithPermissions struct {
+	Id          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
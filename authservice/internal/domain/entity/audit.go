@@ -8,8 +8,10 @@ import (
 type Audit struct {
 	Id        sql.NullInt64 `db:"id" json:"id"`
 	UserId    sql.NullInt64 `db:"user_id" json:"userId"`
+	ActorId   sql.NullInt64 `db:"actor_id" json:"actor_id"`
 	Action    string        `db:"action" json:"action"`
 	IpAddress string        `db:"ip_address" json:"ip_address"`
 	UserAgent string        `db:"user_agent" json:"user_agent"`
+	Details   *string       `db:"details" json:"details,omitempty"`
 	CreatedAt time.Time     `db:"created_at" json:"created_at"`
 }
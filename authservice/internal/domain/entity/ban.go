@@ -5,11 +5,27 @@ import (
 	"time"
 )
 
+const (
+	BanCategorySpam                 = "spam"
+	BanCategoryAbuse                = "abuse"
+	BanCategoryFraud                = "fraud"
+	BanCategoryInappropriateContent = "inappropriate_content"
+	BanCategoryOther                = "other"
+)
+
+// UnbanReasonExpired причина снятия блокировки по истечении срока
+const UnbanReasonExpired = "expired"
+
 type Ban struct {
-	Id        sql.NullInt64 `db:"id" json:"-"`
-	UserId    sql.NullInt64 `db:"user_id" json:"-"`
-	IsForever bool          `db:"is_forever" json:"is_forever"`
-	CreatedAt time.Time     `db:"created_at" json:"created_at"`
-	ExpiredAt time.Time     `db:"expired_at" json:"expired_at"`
-	Cause     string        `db:"cause" json:"cause"`
+	Id             sql.NullInt64 `db:"id" json:"-"`
+	UserId         sql.NullInt64 `db:"user_id" json:"-"`
+	IsForever      bool          `db:"is_forever" json:"is_forever"`
+	CreatedAt      time.Time     `db:"created_at" json:"created_at"`
+	ExpiredAt      time.Time     `db:"expired_at" json:"expired_at"`
+	Cause          string        `db:"cause" json:"cause"`
+	ReasonCategory string        `db:"reason_category" json:"reason_category"`
+	IssuedBy       *int64        `db:"issued_by" json:"issued_by,omitempty"`
+	UnbannedAt     *time.Time    `db:"unbanned_at" json:"unbanned_at,omitempty"`
+	UnbannedBy     *int64        `db:"unbanned_by" json:"unbanned_by,omitempty"`
+	UnbanReason    *string       `db:"unban_reason" json:"unban_reason,omitempty"`
 }
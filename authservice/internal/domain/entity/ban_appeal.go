@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+const (
+	AppealStatusPending  = "pending"
+	AppealStatusApproved = "approved"
+	AppealStatusRejected = "rejected"
+)
+
+type BanAppeal struct {
+	Id            int64      `db:"id" json:"id"`
+	BanId         int64      `db:"ban_id" json:"ban_id"`
+	UserId        int64      `db:"user_id" json:"user_id"`
+	Message       string     `db:"message" json:"message"`
+	Status        string     `db:"status" json:"status"`
+	ReviewedBy    *int64     `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewComment *string    `db:"review_comment" json:"review_comment,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	ReviewedAt    *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+}
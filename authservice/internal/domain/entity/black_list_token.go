@@ -0,0 +1,8 @@
+package entity
+
+import "time"
+
+type BlackListToken struct {
+	Token     string    `db:"token" json:"token"`
+	ExpiredAt time.Time `db:"expired_at" json:"expired_at"`
+}
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+const (
+	ConsentDocumentTerms   = "terms"
+	ConsentDocumentPrivacy = "privacy"
+)
+
+type ConsentDocument struct {
+	Id          int64     `db:"id" json:"id"`
+	Type        string    `db:"type" json:"type"`
+	Version     string    `db:"version" json:"version"`
+	Url         string    `db:"url" json:"url"`
+	PublishedBy *int64    `db:"published_by" json:"published_by,omitempty"`
+	PublishedAt time.Time `db:"published_at" json:"published_at"`
+}
+
+// UserConsent принятая пользователем версия документа
+type UserConsent struct {
+	DocumentId int64     `db:"document_id" json:"document_id"`
+	Type       string    `db:"type" json:"type"`
+	Version    string    `db:"version" json:"version"`
+	AcceptedAt time.Time `db:"accepted_at" json:"accepted_at"`
+}
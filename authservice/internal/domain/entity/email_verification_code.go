@@ -10,8 +10,10 @@ type EmailVerificationCode struct {
 	UserId     int64         `db:"user_id" json:"user_id"`
 	Code       string        `db:"code" json:"code"`
 	IsVerified bool          `db:"is_verified" json:"is_verified"`
-	CreatedAt  time.Time     `db:"created_at" json:"created_at"`
-	ExpiredAt  time.Time     `db:"expired_at" json:"expired_at"`
+	// FailedAttempts неверные попытки ввода этого кода
+	FailedAttempts int       `db:"failed_attempts" json:"failed_attempts"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	ExpiredAt      time.Time `db:"expired_at" json:"expired_at"`
 }
 
 func (e EmailVerificationCode) IsExpired() bool {
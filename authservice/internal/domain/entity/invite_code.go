@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+type InviteCode struct {
+	Id        int64      `db:"id" json:"id"`
+	Code      string     `db:"code" json:"code"`
+	MaxUses   int        `db:"max_uses" json:"max_uses"`
+	UsedCount int        `db:"used_count" json:"used_count"`
+	ExpiredAt *time.Time `db:"expired_at" json:"expired_at,omitempty"`
+	CreatedBy *int64     `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	// Roles имена ролей через запятую, заполняется только при выборке списка
+	Roles string `db:"roles" json:"-"`
+}
+
+// IsUsable код не отозван, не истек и использован меньше разрешенного числа раз
+func (i *InviteCode) IsUsable(now time.Time) bool {
+	if i.RevokedAt != nil || i.UsedCount >= i.MaxUses {
+		return false
+	}
+	return i.ExpiredAt == nil || now.Before(*i.ExpiredAt)
+}
+
+type InviteCodeUsage struct {
+	Id       int64     `db:"id" json:"id"`
+	InviteId int64     `db:"invite_id" json:"invite_id"`
+	UserId   *int64    `db:"user_id" json:"user_id,omitempty"`
+	UsedAt   time.Time `db:"used_at" json:"used_at"`
+}
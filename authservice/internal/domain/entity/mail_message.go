@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	MailStatusPending = "pending"
+	MailStatusSending = "sending"
+	MailStatusSent    = "sent"
+	// MailStatusDead попытки доставки исчерпаны, письмо можно отправить повторно вручную
+	MailStatusDead = "dead"
+)
+
+type MailMessage struct {
+	Id            int64          `db:"id" json:"id"`
+	Template      string         `db:"template" json:"template"`
+	Sender        string         `db:"sender" json:"sender"`
+	Recipients    pq.StringArray `db:"recipients" json:"recipients"`
+	Subject       string         `db:"subject" json:"subject"`
+	HtmlBody      string         `db:"html_body" json:"-"`
+	TextBody      string         `db:"text_body" json:"-"`
+	Status        string         `db:"status" json:"status"`
+	Attempts      int            `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time      `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     *string        `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+	SentAt        *time.Time     `db:"sent_at" json:"sent_at,omitempty"`
+}
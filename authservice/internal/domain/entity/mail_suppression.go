@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+type MailSuppression struct {
+	Id        int64     `db:"id" json:"id"`
+	Email     string    `db:"email" json:"email"`
+	Reason    string    `db:"reason" json:"reason"`
+	Detail    *string   `db:"detail" json:"detail,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
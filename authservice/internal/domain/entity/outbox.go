@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+type OutboxMessage struct {
+	Id            int64      `db:"id" json:"id"`
+	AggregateKey  string     `db:"aggregate_key" json:"aggregate_key"`
+	EventType     string     `db:"event_type" json:"event_type"`
+	Topic         string     `db:"topic" json:"topic"`
+	Payload       string     `db:"payload" json:"payload"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	Attempts      int        `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     *string    `db:"last_error" json:"last_error,omitempty"`
+	PublishedAt   *time.Time `db:"published_at" json:"published_at,omitempty"`
+}
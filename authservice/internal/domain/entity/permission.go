@@ -0,0 +1,9 @@
+package entity
+
+import "database/sql"
+
+type Permission struct {
+	Id          sql.NullInt64 `db:"id" json:"id"`
+	Name        string        `db:"name" json:"name"`
+	Description string        `db:"description" json:"description"`
+}
@@ -10,6 +10,15 @@ type User struct {
 	Email          string        `db:"email" json:"email"`
 	Password       string        `db:"password" json:"password"`
 	EmailIsConfirm bool          `db:"email_is_confirm" json:"email_is_confirm"`
+	Locale         *string       `db:"locale" json:"locale,omitempty"`
 	CreatedAt      time.Time     `db:"created_at" json:"created_at"`
 	UpdatedAt      time.Time     `db:"updated_at" json:"updated_at"`
 }
+
+// PreferredLocale язык из профиля, пустая строка - не выбран
+func (u *User) PreferredLocale() string {
+	if u.Locale == nil {
+		return ""
+	}
+	return *u.Locale
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// UserSummary строка списка пользователей для администратора
+type UserSummary struct {
+	Id             int64     `db:"id" json:"id"`
+	Email          string    `db:"email" json:"email"`
+	EmailIsConfirm bool      `db:"email_is_confirm" json:"email_is_confirm"`
+	Roles          string    `db:"roles" json:"-"`
+	IsBanned       bool      `db:"is_banned" json:"is_banned"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
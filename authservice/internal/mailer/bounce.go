@@ -0,0 +1,182 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+)
+
+const (
+	BounceTypeBounce    = "bounce"
+	BounceTypeComplaint = "complaint"
+)
+
+// Bounce недоставленное письмо или жалоба получателя. Permanent - адрес больше не принимает почту
+type Bounce struct {
+	Email     string
+	Type      string
+	Permanent bool
+	Reason    string
+}
+
+var ErrNotReport = errors.New("message is not a delivery status or feedback report")
+
+// ParseReport разбирает письмо-отчет из почтового ящика возвратов: DSN (RFC 3464, multipart/report;
+// report-type=delivery-status) или жалобу ARF (RFC 5965, report-type=feedback-report)
+func ParseReport(data []byte) ([]Bounce, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" {
+		return nil, ErrNotReport
+	}
+
+	var res []Bounce
+	var complaint bool
+	var originalTo string
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "message/delivery-status", "message/global-delivery-status":
+			res = append(res, parseDeliveryStatus(body)...)
+		case "message/feedback-report":
+			complaint = true
+			for _, fields := range parseFieldGroups(body) {
+				if rcpt := fields["original-rcpt-to"]; rcpt != "" {
+					res = append(res, Bounce{
+						Email:     rcpt,
+						Type:      BounceTypeComplaint,
+						Permanent: true,
+						Reason:    fields["feedback-type"],
+					})
+				}
+			}
+		case "message/rfc822", "text/rfc822-headers":
+			if original, err := mail.ReadMessage(bytes.NewReader(appendBodySeparator(body))); err == nil {
+				if addr, err := mail.ParseAddress(original.Header.Get("To")); err == nil {
+					originalTo = addr.Address
+				}
+			}
+		}
+	}
+
+	// в ARF отчете Original-Rcpt-To необязателен, тогда адрес берется из приложенного письма
+	if complaint && len(res) == 0 && originalTo != "" {
+		res = append(res, Bounce{
+			Email:     originalTo,
+			Type:      BounceTypeComplaint,
+			Permanent: true,
+			Reason:    "abuse",
+		})
+	}
+
+	if res == nil && !complaint {
+		return nil, ErrNotReport
+	}
+
+	return res, nil
+}
+
+// parseDeliveryStatus получатели с Action: failed или delayed. Статус 5.x.x - постоянная ошибка
+func parseDeliveryStatus(body []byte) []Bounce {
+	var res []Bounce
+	for _, fields := range parseFieldGroups(body) {
+		rcpt := fields["final-recipient"]
+		if rcpt == "" {
+			rcpt = fields["original-recipient"]
+		}
+		action := strings.ToLower(fields["action"])
+		if rcpt == "" || (action != "failed" && action != "delayed") {
+			continue
+		}
+
+		reason := fields["diagnostic-code"]
+		if reason == "" {
+			reason = fields["status"]
+		}
+
+		res = append(res, Bounce{
+			Email:     rcpt,
+			Type:      BounceTypeBounce,
+			Permanent: action == "failed" && strings.HasPrefix(fields["status"], "5"),
+			Reason:    reason,
+		})
+	}
+	return res
+}
+
+// parseFieldGroups группы полей "Name: value", разделенные пустыми строками. Для адресов
+// вида "rfc822; user@host" остается только адрес
+func parseFieldGroups(body []byte) []map[string]string {
+	var res []map[string]string
+	current := make(map[string]string)
+	var last string
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		line = strings.TrimRight(line, "\r")
+
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				res = append(res, current)
+				current = make(map[string]string)
+			}
+			continue
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && last != "" {
+			current[last] += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		last = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if last == "final-recipient" || last == "original-recipient" || last == "original-rcpt-to" {
+			if _, addr, ok := strings.Cut(value, ";"); ok {
+				value = addr
+			}
+			value = strings.Trim(strings.TrimSpace(value), "<>")
+		}
+		current[last] = value
+	}
+	if len(current) > 0 {
+		res = append(res, current)
+	}
+
+	return res
+}
+
+// appendBodySeparator text/rfc822-headers содержит только заголовки, без пустой строки mail.ReadMessage их не разберет
+func appendBodySeparator(headers []byte) []byte {
+	if bytes.Contains(headers, []byte("\r\n\r\n")) || bytes.Contains(headers, []byte("\n\n")) {
+		return headers
+	}
+	return append(bytes.TrimRight(headers, "\r\n"), []byte("\r\n\r\n")...)
+}
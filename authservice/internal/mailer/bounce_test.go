@@ -0,0 +1,91 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+const dsnReport = `From: MAILER-DAEMON@mx.example.org
+To: app@example.com
+Subject: Undelivered Mail Returned to Sender
+MIME-Version: 1.0
+Content-Type: multipart/report; report-type=delivery-status; boundary="b1"
+
+--b1
+Content-Type: text/plain
+
+This is the mail system.
+
+--b1
+Content-Type: message/delivery-status
+
+Reporting-MTA: dns; mx.example.org
+
+Final-Recipient: rfc822; gone@example.org
+Action: failed
+Status: 5.1.1
+Diagnostic-Code: smtp; 550 5.1.1 User unknown
+
+Final-Recipient: rfc822; full@example.org
+Action: delayed
+Status: 4.2.2
+
+--b1--
+`
+
+const arfReport = `From: fbl@isp.example
+To: abuse@example.com
+Subject: Complaint
+MIME-Version: 1.0
+Content-Type: multipart/report; report-type=feedback-report; boundary="b2"
+
+--b2
+Content-Type: text/plain
+
+This is an abuse report.
+
+--b2
+Content-Type: message/feedback-report
+
+Feedback-Type: abuse
+User-Agent: FBL/1.0
+Version: 1
+
+--b2
+Content-Type: message/rfc822
+
+From: app@example.com
+To: angry@example.net
+Subject: Confirm your email
+
+hello
+--b2--
+`
+
+func TestParseReport(t *testing.T) {
+	bounces, err := ParseReport([]byte(strings.ReplaceAll(dsnReport, "\n", "\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bounces) != 2 {
+		t.Fatalf("expected 2 bounces, got %+v", bounces)
+	}
+	if bounces[0].Email != "gone@example.org" || !bounces[0].Permanent || bounces[0].Type != BounceTypeBounce {
+		t.Errorf("unexpected permanent bounce: %+v", bounces[0])
+	}
+	if bounces[1].Email != "full@example.org" || bounces[1].Permanent {
+		t.Errorf("unexpected transient bounce: %+v", bounces[1])
+	}
+
+	complaints, err := ParseReport([]byte(arfReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(complaints) != 1 || complaints[0].Email != "angry@example.net" || complaints[0].Type != BounceTypeComplaint {
+		t.Errorf("unexpected complaint: %+v", complaints)
+	}
+
+	if _, err := ParseReport([]byte("Subject: hi\r\n\r\nbody")); err != ErrNotReport {
+		t.Errorf("expected ErrNotReport, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dkimHeaders заголовки, которые подписываются, если они есть в письме
+var dkimHeaders = []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"}
+
+var wsp = regexp.MustCompile(`[ \t]+`)
+
+// DKIMSigner подпись писем DKIM (RFC 6376): rsa-sha256, канонизация relaxed/relaxed.
+// Публичный ключ публикуется в DNS TXT записи <selector>._domainkey.<domain>
+type DKIMSigner struct {
+	domain   string
+	selector string
+	key      *rsa.PrivateKey
+}
+
+func NewDKIMSigner(domain, selector string, key *rsa.PrivateKey) *DKIMSigner {
+	return &DKIMSigner{
+		domain:   domain,
+		selector: selector,
+		key:      key,
+	}
+}
+
+// LoadDKIMKey читает RSA ключ в PEM (PKCS#1 или PKCS#8)
+func LoadDKIMKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("dkim key: pem block not found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("dkim key: only rsa keys are supported")
+	}
+
+	return key, nil
+}
+
+// Sign добавляет заголовок DKIM-Signature в начало письма
+func (s *DKIMSigner) Sign(msg []byte) ([]byte, error) {
+	header, body, ok := bytes.Cut(msg, []byte("\r\n\r\n"))
+	if !ok {
+		return nil, errors.New("dkim: message has no body separator")
+	}
+
+	bodyHash := sha256.Sum256(canonicalBody(body))
+	fields := splitHeader(string(header))
+
+	signed := make([]string, 0, len(dkimHeaders))
+	h := sha256.New()
+	used := make(map[int]bool)
+	for _, name := range dkimHeaders {
+		// при повторах подписывается последний экземпляр заголовка (RFC 6376 5.4.2)
+		for i := len(fields) - 1; i >= 0; i-- {
+			if used[i] || !strings.EqualFold(fieldName(fields[i]), name) {
+				continue
+			}
+			used[i] = true
+			h.Write([]byte(canonicalHeader(fields[i]) + "\r\n"))
+			signed = append(signed, strings.ToLower(name))
+			break
+		}
+	}
+
+	value := fmt.Sprintf(
+		"v=1; a=rsa-sha256; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		s.domain,
+		s.selector,
+		time.Now().Unix(),
+		strings.Join(signed, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+	h.Write([]byte(canonicalHeader("DKIM-Signature: " + value)))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	var res bytes.Buffer
+	res.WriteString("DKIM-Signature: ")
+	res.WriteString(value)
+	res.WriteString(foldBase64(base64.StdEncoding.EncodeToString(signature)))
+	res.WriteString("\r\n")
+	res.Write(msg)
+
+	return res.Bytes(), nil
+}
+
+// splitHeader разбивает заголовок письма на поля вместе с продолжениями строк
+func splitHeader(header string) []string {
+	var res []string
+	for _, line := range strings.Split(header, "\r\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(res) > 0 {
+			res[len(res)-1] += "\r\n" + line
+			continue
+		}
+		res = append(res, line)
+	}
+	return res
+}
+
+func fieldName(field string) string {
+	name, _, _ := strings.Cut(field, ":")
+	return strings.TrimSpace(name)
+}
+
+// canonicalHeader relaxed канонизация поля: имя в нижнем регистре, строки склеены, пробелы схлопнуты
+func canonicalHeader(field string) string {
+	name, value, _ := strings.Cut(field, ":")
+	value = strings.NewReplacer("\r\n", "").Replace(value)
+	value = strings.TrimSpace(wsp.ReplaceAllString(value, " "))
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + value
+}
+
+// canonicalBody relaxed канонизация тела: пробелы схлопнуты и убраны в конце строк, пустые строки в конце удалены
+func canonicalBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(wsp.ReplaceAllString(line, " "), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// foldBase64 переносит длинную подпись, чтобы строки заголовка не превышали 78 символов
+func foldBase64(s string) string {
+	const width = 72
+	var b strings.Builder
+	for len(s) > width {
+		b.WriteString(s[:width])
+		b.WriteString("\r\n\t")
+		s = s[width:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+type signingMailer struct {
+	Mailer
+	signer *DKIMSigner
+}
+
+// Wrap транспорт, который подписывает письма перед отправкой
+func (s *DKIMSigner) Wrap(m Mailer) Mailer {
+	return &signingMailer{
+		Mailer: m,
+		signer: s,
+	}
+}
+
+func (m *signingMailer) Send(from string, to []string, data []byte) error {
+	signed, err := m.signer.Sign(data)
+	if err != nil {
+		return err
+	}
+
+	return m.Mailer.Send(from, to, signed)
+}
+
+// Unwrap транспорт без подписи, например чтобы достать MemoryMailer
+func (m *signingMailer) Unwrap() Mailer {
+	return m.Mailer
+}
@@ -0,0 +1,269 @@
+package mailer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Проверка подписи ниже написана отдельно от dkim.go по RFC 6376 и не использует его функции,
+// иначе ошибка канонизации одинаково повторилась бы и в подписи, и в проверке
+
+// dkimTags разбирает значение DKIM-Signature в теги без пробелов
+func dkimTags(value string) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(value, ";") {
+		name, v, ok := strings.Cut(tag, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.TrimSpace(name)] = strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+				return -1
+			}
+			return r
+		}, v)
+	}
+	return tags
+}
+
+// relaxWSP заменяет каждую последовательность пробелов и табуляций одним пробелом
+func relaxWSP(s string) string {
+	var b strings.Builder
+	inWSP := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			if !inWSP {
+				b.WriteByte(' ')
+			}
+			inWSP = true
+			continue
+		}
+		inWSP = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// verifyRelaxedHeader RFC 6376 3.4.2
+func verifyRelaxedHeader(name, value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = strings.Trim(relaxWSP(value), " ")
+	return strings.ToLower(strings.TrimRight(name, " \t")) + ":" + value
+}
+
+// verifyRelaxedBody RFC 6376 3.4.4
+func verifyRelaxedBody(body string) string {
+	lines := strings.Split(body, "\r\n")
+	var b strings.Builder
+	empty := 0
+	for _, line := range lines {
+		line = strings.TrimRight(relaxWSP(line), " ")
+		if line == "" {
+			empty++
+			continue
+		}
+		b.WriteString(strings.Repeat("\r\n", empty))
+		empty = 0
+		b.WriteString(line)
+		b.WriteString("\r\n")
+	}
+	// пустые строки в конце тела отбрасываются вместе со счетчиком
+	return b.String()
+}
+
+type headerField struct {
+	name, value string
+}
+
+func verifyDKIM(pub *rsa.PublicKey, msg string) error {
+	header, body, ok := strings.Cut(msg, "\r\n\r\n")
+	if !ok {
+		return errors.New("нет тела письма")
+	}
+
+	var fields []headerField
+	for _, line := range strings.Split(header, "\r\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(fields) > 0 {
+			fields[len(fields)-1].value += "\r\n" + line
+			continue
+		}
+		name, value, _ := strings.Cut(line, ":")
+		fields = append(fields, headerField{name, value})
+	}
+
+	sigIdx := -1
+	for i, f := range fields {
+		if strings.EqualFold(strings.TrimSpace(f.name), "DKIM-Signature") {
+			sigIdx = i
+			break
+		}
+	}
+	if sigIdx < 0 {
+		return errors.New("нет DKIM-Signature")
+	}
+	sig := fields[sigIdx]
+	tags := dkimTags(sig.value)
+	if tags["a"] != "rsa-sha256" || tags["c"] != "relaxed/relaxed" {
+		return fmt.Errorf("неожиданные теги: a=%s c=%s", tags["a"], tags["c"])
+	}
+
+	bh := sha256.Sum256([]byte(verifyRelaxedBody(body)))
+	if tags["bh"] != base64.StdEncoding.EncodeToString(bh[:]) {
+		return errors.New("хеш тела не совпадает")
+	}
+
+	h := sha256.New()
+	used := make(map[int]bool)
+	for _, name := range strings.Split(tags["h"], ":") {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if i == sigIdx || used[i] || !strings.EqualFold(strings.TrimSpace(fields[i].name), name) {
+				continue
+			}
+			used[i] = true
+			h.Write([]byte(verifyRelaxedHeader(fields[i].name, fields[i].value) + "\r\n"))
+			break
+		}
+	}
+
+	// сама подпись участвует с пустым значением b=
+	bStart := strings.Index(sig.value, "b=")
+	for bStart > 0 && !strings.ContainsAny(sig.value[bStart-1:bStart], "; \t\n") {
+		bStart = strings.Index(sig.value[bStart+1:], "b=") + bStart + 1
+	}
+	bEnd := strings.Index(sig.value[bStart:], ";")
+	withoutB := sig.value[:bStart+2]
+	if bEnd >= 0 {
+		withoutB += sig.value[bStart+bEnd:]
+	}
+	h.Write([]byte(verifyRelaxedHeader(sig.name, withoutB)))
+
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return err
+	}
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, h.Sum(nil), signature)
+}
+
+func signTestMessage(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	msg, err := Build(&mail.Address{Name: "Приложение", Address: "app@example.com"}, []string{"user@example.org"}, &Message{
+		Subject: "Подтвердите email",
+		HTML:    "<p>Код: <b>A1B2C3D4</b></p>",
+		Text:    "Код: A1B2C3D4  \n\n\n",
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := NewDKIMSigner("example.com", "mail", key).Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(signed), "DKIM-Signature: ") {
+		t.Fatalf("подпись не в начале письма: %q", string(signed)[:40])
+	}
+	return string(signed)
+}
+
+func TestDKIMSign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := signTestMessage(t, key)
+
+	header, _, _ := strings.Cut(signed, "\r\n\r\n")
+	tags := dkimTags(strings.SplitN(header, ":", 2)[1])
+	if tags["d"] != "example.com" || tags["s"] != "mail" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+	for _, name := range []string{"from", "to", "subject", "date", "message-id"} {
+		if !strings.Contains(":"+tags["h"]+":", ":"+name+":") {
+			t.Errorf("заголовок %s не подписан: h=%s", name, tags["h"])
+		}
+	}
+
+	if err := verifyDKIM(&key.PublicKey, signed); err != nil {
+		t.Fatalf("подпись не прошла проверку: %v", err)
+	}
+
+	// relaxed канонизация выдерживает то, что делают с письмом почтовые серверы по пути
+	relayed := strings.Replace(signed, "\r\nSubject: ", "\r\nSUBJECT:   ", 1)
+	relayed = strings.Replace(relayed, "\r\nMIME-Version: 1.0", "\r\nMIME-Version:\r\n\t1.0 ", 1)
+	relayed = strings.TrimSuffix(relayed, "\r\n") + "  \r\n\r\n\r\n"
+	if err := verifyDKIM(&key.PublicKey, relayed); err != nil {
+		t.Errorf("подпись не прошла проверку после пересылки: %v", err)
+	}
+
+	tampered := map[string]string{
+		"тема":    strings.Replace(signed, "Subject: ", "Subject: Re: ", 1),
+		"тело":    strings.Replace(signed, "A1B2C3D4", "A1B2C3D5", 1),
+		"адресат": strings.Replace(signed, "<user@example.org>", "<other@example.org>", 1),
+	}
+	for name, msg := range tampered {
+		if err := verifyDKIM(&key.PublicKey, msg); err == nil {
+			t.Errorf("измененное письмо (%s) прошло проверку", name)
+		}
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyDKIM(&other.PublicKey, signed); err == nil {
+		t.Error("подпись прошла проверку чужим ключом")
+	}
+}
+
+// Хеши тела из RFC 6376: пример из приложения A и пустое тело (3.4.4)
+func TestDKIMBodyHashVectors(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := NewDKIMSigner("example.com", "brisbane", key)
+
+	cases := []struct {
+		body, bh string
+	}{
+		{"Hi.\r\n\r\nWe lost the game. Are you hungry yet?\r\n\r\nJoe.\r\n", "2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8="},
+		{"", "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+		{"\r\n\r\n", "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+	}
+
+	for _, tc := range cases {
+		signed, err := signer.Sign([]byte("From: Joe SixPack <joe@football.example.com>\r\nSubject: Is dinner ready?\r\n\r\n" + tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, _, _ := strings.Cut(string(signed), "\r\n\r\n")
+		if got := dkimTags(strings.SplitN(header, ":", 2)[1])["bh"]; got != tc.bh {
+			t.Errorf("bh для %q = %s, want %s", tc.body, got, tc.bh)
+		}
+		if err := verifyDKIM(&key.PublicKey, string(signed)); err != nil {
+			t.Errorf("тело %q: %v", tc.body, err)
+		}
+	}
+}
+
+func TestCanonicalization(t *testing.T) {
+	// примеры из RFC 6376, 3.4.5
+	if got := canonicalHeader("A: X"); got != "a:X" {
+		t.Errorf("got %q", got)
+	}
+	if got := canonicalHeader("B : Y\t\r\n\tZ  "); got != "b:Y Z" {
+		t.Errorf("got %q", got)
+	}
+	if got := string(canonicalBody([]byte(" C \r\nD \t E\r\n\r\n\r\n"))); got != " C\r\nD E\r\n" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// MaildirMailer складывает письма в каталог в формате Maildir (tmp, new, cur).
+// Каждый файл - готовое письмо .eml, его можно открыть почтовым клиентом
+type MaildirMailer struct {
+	dir      string
+	hostname string
+	counter  atomic.Int64
+}
+
+func NewMaildirMailer(dir string) (*MaildirMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	return &MaildirMailer{
+		dir:      dir,
+		hostname: hostname,
+	}, nil
+}
+
+// Send пишет письмо в tmp и переносит в new, чтобы читатель каталога не увидел недописанный файл
+func (m *MaildirMailer) Send(_ string, _ []string, data []byte) error {
+	name := fmt.Sprintf("%d.%d_%d.%s", time.Now().UnixNano(), os.Getpid(), m.counter.Add(1), m.hostname)
+
+	tmp := filepath.Join(m.dir, "tmp", name)
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(m.dir, "new", name))
+}
+
+func (m *MaildirMailer) Close() error {
+	return nil
+}
@@ -0,0 +1,13 @@
+package mailer
+
+const (
+	TransportSMTP   = "smtp"
+	TransportFile   = "file"
+	TransportMemory = "memory"
+)
+
+// Mailer доставляет готовое письмо (RFC 5322) получателям. from и to - адреса конверта без имен
+type Mailer interface {
+	Send(from string, to []string, data []byte) error
+	Close() error
+}
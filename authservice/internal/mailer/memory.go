@@ -0,0 +1,166 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultMemoryLimit = 100
+
+// SentMessage письмо, перехваченное MemoryMailer. Части письма уже декодированы
+type SentMessage struct {
+	From    string    `json:"from"`
+	To      []string  `json:"to"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+	HTML    string    `json:"html"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// MemoryMailer хранит последние limit писем в памяти процесса. Для локального запуска и тестов:
+// письма можно посмотреть через Handler или получить из тестов через Messages
+type MemoryMailer struct {
+	mu       sync.RWMutex
+	limit    int
+	messages []SentMessage
+}
+
+func NewMemoryMailer(limit int) *MemoryMailer {
+	if limit <= 0 {
+		limit = defaultMemoryLimit
+	}
+
+	return &MemoryMailer{limit: limit}
+}
+
+func (m *MemoryMailer) Send(from string, to []string, data []byte) error {
+	msg, err := parseMessage(data)
+	if err != nil {
+		return err
+	}
+	msg.From = from
+	msg.To = slices.Clone(to)
+	msg.SentAt = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, *msg)
+	if len(m.messages) > m.limit {
+		m.messages = slices.Clone(m.messages[len(m.messages)-m.limit:])
+	}
+
+	return nil
+}
+
+func (m *MemoryMailer) Close() error {
+	return nil
+}
+
+// Messages письма от старых к новым. to непустой - только письма этому получателю
+func (m *MemoryMailer) Messages(to string) []SentMessage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]SentMessage, 0, len(m.messages))
+	for _, msg := range m.messages {
+		if to == "" || slices.ContainsFunc(msg.To, func(addr string) bool { return strings.EqualFold(addr, to) }) {
+			res = append(res, msg)
+		}
+	}
+
+	return res
+}
+
+// Last последнее письмо получателю
+func (m *MemoryMailer) Last(to string) (SentMessage, bool) {
+	msgs := m.Messages(to)
+	if len(msgs) == 0 {
+		return SentMessage{}, false
+	}
+
+	return msgs[len(msgs)-1], true
+}
+
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
+
+// Handler просмотр перехваченных писем: GET [?to=адрес] - список, DELETE - очистка
+func (m *MemoryMailer) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			json.NewEncoder(w).Encode(m.Messages(r.URL.Query().Get("to")))
+		case http.MethodDelete:
+			m.Reset()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// parseMessage разбирает письмо, собранное Build: тема по RFC 2047, текстовая и HTML части
+func parseMessage(data []byte) (*SentMessage, error) {
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SentMessage{}
+	dec := new(mime.WordDecoder)
+	if res.Subject, err = dec.DecodeHeader(parsed.Header.Get("Subject")); err != nil {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := io.ReadAll(parsed.Body)
+		if err != nil {
+			return nil, err
+		}
+		res.Text = string(body)
+		return res, nil
+	}
+
+	// multipart.Reader сам снимает quoted-printable
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "text/plain":
+			res.Text = string(body)
+		case "text/html":
+			res.HTML = string(body)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,101 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Build собирает письмо multipart/alternative с текстовой и HTML частями.
+// Заголовки с не ASCII символами кодируются по RFC 2047
+func Build(from *mail.Address, to []string, msg *Message, date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	if err := writePart(w, "text/plain; charset=UTF-8", msg.Text); err != nil {
+		return nil, err
+	}
+	if err := writePart(w, "text/html; charset=UTF-8", msg.HTML); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	recipients := make([]string, 0, len(to))
+	for _, addr := range to {
+		recipients = append(recipients, (&mail.Address{Address: addr}).String())
+	}
+
+	messageId, err := newMessageId(from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var res bytes.Buffer
+	writeHeader(&res, "From", from.String())
+	writeHeader(&res, "To", strings.Join(recipients, ", "))
+	writeHeader(&res, "Subject", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	writeHeader(&res, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&res, "Message-ID", messageId)
+	writeHeader(&res, "MIME-Version", "1.0")
+	writeHeader(&res, "Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", w.Boundary()))
+	res.WriteString("\r\n")
+	res.Write(body.Bytes())
+
+	return res.Bytes(), nil
+}
+
+// ParseAddress адрес отправителя из конфига: "name@host" или "Имя <name@host>"
+func ParseAddress(addr string) *mail.Address {
+	res, err := mail.ParseAddress(addr)
+	if err != nil {
+		return &mail.Address{Address: addr}
+	}
+	return res
+}
+
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+func writePart(w *multipart.Writer, contentType, content string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func newMessageId(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if _, host, ok := strings.Cut(from, "@"); ok && host != "" {
+		domain = host
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain), nil
+}
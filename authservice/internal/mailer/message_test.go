@@ -0,0 +1,92 @@
+package mailer
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	msg := &Message{
+		Subject: "Сброс пароля FoodApp",
+		HTML:    "<p>Код: <b>K7Q2M9XD</b></p>",
+		Text:    "Код: K7Q2M9XD\n" + strings.Repeat("длинная строка ", 10) + "\n",
+	}
+	date := time.Date(2025, 7, 1, 10, 30, 0, 0, time.UTC)
+
+	data, err := Build(ParseAddress("FoodApp <no-reply@food.app>"), []string{"ivan@mail.ru"}, msg, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if got := parsed.Header.Get("To"); got != "<ivan@mail.ru>" {
+		t.Errorf("To = %q", got)
+	}
+	if got, _ := parsed.Header.Date(); !got.Equal(date) {
+		t.Errorf("Date = %v", got)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@food.app>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v)", parsed.Header.Get("Content-Type"), err)
+	}
+
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	}
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, w := range want {
+		part, err := mr.NextRawPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := part.Header.Get("Content-Type"); got != w.contentType {
+			t.Errorf("Content-Type части = %q, want %q", got, w.contentType)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// quoted-printable переводит концы строк в CRLF
+		if string(body) != strings.ReplaceAll(w.body, "\n", "\r\n") {
+			t.Errorf("тело части %s = %q", w.contentType, body)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("лишняя часть письма: %v", err)
+	}
+
+	// строки не длиннее 998 байт (RFC 5322)
+	for _, line := range strings.Split(string(data), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("строка длиной %d", len(line))
+		}
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	if got := ParseAddress("FoodApp <no-reply@food.app>"); got.Name != "FoodApp" || got.Address != "no-reply@food.app" {
+		t.Errorf("got %+v", got)
+	}
+	if got := ParseAddress("not an address"); got.Address != "not an address" {
+		t.Errorf("got %+v", got)
+	}
+}
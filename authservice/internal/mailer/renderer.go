@@ -0,0 +1,189 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	TemplateConfirmEmail  = "confirm_email"
+	TemplateResetPassword = "reset_password"
+
+	htmlLayout = "layout.html"
+	textLayout = "layout.txt"
+	// commonPartial общие для всех писем локали блоки, например подпись
+	commonPartial = "common"
+)
+
+// Message готовое к отправке письмо
+type Message struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Renderer письма из шаблонов dir/layout.{html,txt} и частей dir/<локаль>/<письмо>.{html,txt}.
+// Часть .txt определяет блоки subject, title и content, часть .html - title и content
+type Renderer struct {
+	defaultLocale string
+	html          map[string]map[string]*htmltemplate.Template
+	text          map[string]map[string]*texttemplate.Template
+}
+
+func NewRenderer(dir, defaultLocale string) (*Renderer, error) {
+	htmlBase, err := htmltemplate.ParseFiles(filepath.Join(dir, htmlLayout))
+	if err != nil {
+		return nil, err
+	}
+	textBase, err := texttemplate.ParseFiles(filepath.Join(dir, textLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{
+		defaultLocale: defaultLocale,
+		html:          make(map[string]map[string]*htmltemplate.Template),
+		text:          make(map[string]map[string]*texttemplate.Template),
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if err := r.loadLocale(dir, entry.Name(), htmlBase, textBase); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := r.html[defaultLocale]; !ok {
+		return nil, fmt.Errorf("mail templates for default locale %q not found in %s", defaultLocale, dir)
+	}
+
+	return r, nil
+}
+
+func (r *Renderer) loadLocale(dir, locale string, htmlBase *htmltemplate.Template, textBase *texttemplate.Template) error {
+	localeDir := filepath.Join(dir, locale)
+	names, err := filepath.Glob(filepath.Join(localeDir, "*.html"))
+	if err != nil {
+		return err
+	}
+
+	r.html[locale] = make(map[string]*htmltemplate.Template)
+	r.text[locale] = make(map[string]*texttemplate.Template)
+
+	for _, path := range names {
+		name := strings.TrimSuffix(filepath.Base(path), ".html")
+		if name == commonPartial {
+			continue
+		}
+
+		htmlFiles := withCommon(localeDir, name, ".html")
+		textFiles := withCommon(localeDir, name, ".txt")
+
+		htmlTpl, err := htmltemplate.Must(htmlBase.Clone()).ParseFiles(htmlFiles...)
+		if err != nil {
+			return fmt.Errorf("%s/%s.html: %w", locale, name, err)
+		}
+		textTpl, err := texttemplate.Must(textBase.Clone()).ParseFiles(textFiles...)
+		if err != nil {
+			return fmt.Errorf("%s/%s.txt: %w", locale, name, err)
+		}
+		if textTpl.Lookup("subject") == nil {
+			return fmt.Errorf("%s/%s.txt: subject is not defined", locale, name)
+		}
+
+		r.html[locale][name] = htmlTpl
+		r.text[locale][name] = textTpl
+	}
+
+	return nil
+}
+
+// withCommon файлы части письма вместе с common, если он есть у локали
+func withCommon(localeDir, name, ext string) []string {
+	files := make([]string, 0, 2)
+	common := filepath.Join(localeDir, commonPartial+ext)
+	if _, err := os.Stat(common); err == nil {
+		files = append(files, common)
+	}
+	return append(files, filepath.Join(localeDir, name+ext))
+}
+
+// Render письмо на языке из lang (значение Accept-Language или код локали)
+func (r *Renderer) Render(name, lang string, data map[string]interface{}) (*Message, error) {
+	locale := r.Locale(lang)
+
+	htmlTpl, ok := r.html[locale][name]
+	if !ok {
+		htmlTpl, ok = r.html[r.defaultLocale][name]
+		if !ok {
+			return nil, fmt.Errorf("mail template %q not found", name)
+		}
+		locale = r.defaultLocale
+	}
+	textTpl := r.text[locale][name]
+
+	var subject, html, text bytes.Buffer
+	if err := textTpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := htmlTpl.ExecuteTemplate(&html, htmlLayout, data); err != nil {
+		return nil, err
+	}
+	if err := textTpl.ExecuteTemplate(&text, textLayout, data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
+
+// Locale первая локаль из lang, для которой есть шаблоны. Иначе локаль по умолчанию
+func (r *Renderer) Locale(lang string) string {
+	for _, part := range strings.Split(lang, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if _, ok := r.html[tag]; ok {
+			return tag
+		}
+		base, _, _ := strings.Cut(tag, "-")
+		if _, ok := r.html[base]; ok {
+			return base
+		}
+	}
+	return r.defaultLocale
+}
+
+func (r *Renderer) Locales() []string {
+	res := make([]string, 0, len(r.html))
+	for locale := range r.html {
+		res = append(res, locale)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Names письма, доступные в локали
+func (r *Renderer) Names(locale string) []string {
+	res := make([]string, 0, len(r.html[locale]))
+	for name := range r.html[locale] {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,175 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// в ru нет письма notice, оно берется из локали по умолчанию
+func newTestRenderer(t *testing.T) *Renderer {
+	t.Helper()
+	dir := writeTemplates(t, map[string]string{
+		"layout.html":      `<h1>{{template "title" .}}</h1>{{template "content" .}}`,
+		"layout.txt":       `{{template "title" .}}|{{template "content" .}}`,
+		"en/greeting.html": `{{define "title"}}Hello{{end}}{{define "content"}}<p>{{.name}}</p>{{end}}`,
+		"en/greeting.txt":  `{{define "subject"}}Hi {{.name}}{{end}}{{define "title"}}Hello{{end}}{{define "content"}}{{.name}}{{end}}`,
+		"en/notice.html":   `{{define "title"}}Notice{{end}}{{define "content"}}en notice{{end}}`,
+		"en/notice.txt":    `{{define "subject"}}Notice{{end}}{{define "title"}}Notice{{end}}{{define "content"}}en notice{{end}}`,
+		"ru/greeting.html": `{{define "title"}}Привет{{end}}{{define "content"}}<p>{{.name}}</p>{{end}}`,
+		"ru/greeting.txt":  `{{define "subject"}}Привет, {{.name}}{{end}}{{define "title"}}Привет{{end}}{{define "content"}}{{.name}}{{end}}`,
+	})
+
+	r, err := NewRenderer(dir, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRender(t *testing.T) {
+	r := newTestRenderer(t)
+
+	msg, err := r.Render("greeting", "ru-RU,ru;q=0.9,en;q=0.8", map[string]interface{}{"name": "<Иван>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Привет, <Иван>" {
+		t.Errorf("Subject = %q", msg.Subject)
+	}
+	if msg.Text != "Привет|<Иван>\n" {
+		t.Errorf("Text = %q", msg.Text)
+	}
+	// в HTML данные экранируются
+	if msg.HTML != "<h1>Привет</h1><p>&lt;Иван&gt;</p>" {
+		t.Errorf("HTML = %q", msg.HTML)
+	}
+}
+
+func TestRenderFallback(t *testing.T) {
+	r := newTestRenderer(t)
+
+	cases := []struct {
+		name, lang, template, subject, text string
+	}{
+		{"неизвестный язык", "de", "greeting", "Hi Иван", "Hello|Иван\n"},
+		{"пустой язык", "", "greeting", "Hi Иван", "Hello|Иван\n"},
+		{"письма нет в локали", "ru", "notice", "Notice", "Notice|en notice\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := r.Render(tc.template, tc.lang, map[string]interface{}{"name": "Иван"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Subject != tc.subject {
+				t.Fatalf("Subject = %q, want %q", msg.Subject, tc.subject)
+			}
+			// текстовая часть из той же локали, что и тема
+			if msg.Text != tc.text {
+				t.Fatalf("Text = %q, want %q", msg.Text, tc.text)
+			}
+		})
+	}
+
+	if _, err := r.Render("missing", "en", nil); err == nil {
+		t.Fatal("ожидалась ошибка для неизвестного письма")
+	}
+}
+
+func TestNewRendererErrors(t *testing.T) {
+	layouts := map[string]string{
+		"layout.html": `{{template "content" .}}`,
+		"layout.txt":  `{{template "content" .}}`,
+	}
+
+	t.Run("нет локали по умолчанию", func(t *testing.T) {
+		files := map[string]string{
+			"ru/a.html": `{{define "content"}}a{{end}}`,
+			"ru/a.txt":  `{{define "subject"}}a{{end}}{{define "content"}}a{{end}}`,
+		}
+		for k, v := range layouts {
+			files[k] = v
+		}
+		if _, err := NewRenderer(writeTemplates(t, files), "en"); err == nil {
+			t.Fatal("ожидалась ошибка")
+		}
+	})
+
+	t.Run("нет темы письма", func(t *testing.T) {
+		files := map[string]string{
+			"en/a.html": `{{define "content"}}a{{end}}`,
+			"en/a.txt":  `{{define "content"}}a{{end}}`,
+		}
+		for k, v := range layouts {
+			files[k] = v
+		}
+		if _, err := NewRenderer(writeTemplates(t, files), "en"); err == nil {
+			t.Fatal("ожидалась ошибка")
+		}
+	})
+}
+
+// Шаблоны сервиса: все письма есть во всех локалях и рендерятся с данными, которые передают обработчики
+func TestServiceTemplates(t *testing.T) {
+	r, err := NewRenderer("../../templates/mail", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]interface{}{
+		"appName":        "FoodApp",
+		"appSupportLink": "https://example.com/support",
+		"code":           "K7Q2M9XD",
+		"url":            "https://example.com/reset-password?token=abc",
+		"expiresMinutes": 10,
+	}
+
+	names := r.Names("en")
+	for _, name := range []string{TemplateConfirmEmail, TemplateResetPassword} {
+		if !slices.Contains(names, name) {
+			t.Fatalf("нет письма %s в en: %v", name, names)
+		}
+	}
+
+	for _, locale := range r.Locales() {
+		if got := r.Names(locale); !slices.Equal(got, names) {
+			t.Errorf("письма %s: %v, в en: %v", locale, got, names)
+		}
+		for _, name := range names {
+			msg, err := r.Render(name, locale, data)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, name, err)
+			}
+			if msg.Subject == "" || strings.Contains(msg.Subject, "\n") {
+				t.Errorf("%s/%s: тема %q", locale, name, msg.Subject)
+			}
+			for part, body := range map[string]string{"html": msg.HTML, "text": msg.Text} {
+				if !strings.Contains(body, "K7Q2M9XD") {
+					t.Errorf("%s/%s: в %s нет кода", locale, name, part)
+				}
+				if strings.Contains(body, "<no value>") {
+					t.Errorf("%s/%s: в %s есть незаполненные поля", locale, name, part)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+const (
+	// TLSNone без шифрования, только для локального SMTP
+	TLSNone = "none"
+	// TLSOpportunistic STARTTLS, если сервер его поддерживает, иначе без шифрования. Так же работает smtp.SendMail
+	TLSOpportunistic = "opportunistic"
+	// TLSStartTLS соединение без шифрования, которое поднимается до TLS командой STARTTLS (обычно порт 587)
+	TLSStartTLS = "starttls"
+	// TLSImplicit TLS с первого байта (обычно порт 465)
+	TLSImplicit = "tls"
+
+	// quitTimeout сколько ждать ответа на QUIT при закрытии пула
+	quitTimeout = 5 * time.Second
+)
+
+// ErrAuthUnsupported заданы логин и пароль, но сервер не предлагает AUTH
+var ErrAuthUnsupported = errors.New("smtp server does not support AUTH, but credentials are configured")
+
+// CheckTLSMode ошибка, если mode не один из TLSNone, TLSOpportunistic, TLSStartTLS, TLSImplicit
+func CheckTLSMode(mode string) error {
+	switch mode {
+	case TLSNone, TLSOpportunistic, TLSStartTLS, TLSImplicit:
+		return nil
+	}
+	return fmt.Errorf("unknown smtp tls mode %q: expected %s, %s, %s or %s", mode, TLSNone, TLSOpportunistic, TLSStartTLS, TLSImplicit)
+}
+
+type SMTPOptions struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// TLS режим шифрования, по умолчанию TLSOpportunistic
+	TLS string
+	// PoolSize сколько соединений держать открытыми между отправками
+	PoolSize int
+	// IdleTimeout соединение, простоявшее дольше, закрывается вместо повторного использования
+	IdleTimeout time.Duration
+	DialTimeout time.Duration
+	// SendTimeout сколько может длиться отправка одного письма вместе с проверкой соединения из пула.
+	// Это срок на все чтения и записи в соединение, зависший сервер не задержит отправку дольше
+	SendTimeout time.Duration
+}
+
+type pooledClient struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+// SMTPMailer отправка через SMTP с пулом соединений. Перед повторным использованием
+// соединение проверяется командой RSET, мертвые соединения закрываются
+type SMTPMailer struct {
+	opts      SMTPOptions
+	addr      string
+	tlsConfig *tls.Config
+	pool      chan *pooledClient
+}
+
+func NewSMTPMailer(opts SMTPOptions) *SMTPMailer {
+	if opts.TLS == "" {
+		opts.TLS = TLSOpportunistic
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 10 * time.Second
+	}
+	if opts.SendTimeout <= 0 {
+		opts.SendTimeout = time.Minute
+	}
+
+	return &SMTPMailer{
+		opts:      opts,
+		addr:      net.JoinHostPort(opts.Host, opts.Port),
+		tlsConfig: &tls.Config{ServerName: opts.Host},
+		pool:      make(chan *pooledClient, max(opts.PoolSize, 0)),
+	}
+}
+
+func (m *SMTPMailer) Send(from string, to []string, data []byte) error {
+	deadline := time.Now().Add(m.opts.SendTimeout)
+	c, err := m.get(deadline)
+	if err != nil {
+		return err
+	}
+
+	if err := send(c.client, from, to, data); err != nil {
+		c.client.Close()
+		return err
+	}
+
+	m.put(c)
+	return nil
+}
+
+// Close закрывает соединения пула
+func (m *SMTPMailer) Close() error {
+	for {
+		select {
+		case c := <-m.pool:
+			_ = c.conn.SetDeadline(time.Now().Add(quitTimeout))
+			c.client.Quit()
+		default:
+			return nil
+		}
+	}
+}
+
+// get соединение из пула или новое. На соединение ставится срок deadline
+func (m *SMTPMailer) get(deadline time.Time) (*pooledClient, error) {
+	for {
+		select {
+		case c := <-m.pool:
+			if m.opts.IdleTimeout > 0 && time.Since(c.lastUsed) > m.opts.IdleTimeout {
+				c.client.Close()
+				continue
+			}
+			if err := c.conn.SetDeadline(deadline); err != nil {
+				c.client.Close()
+				continue
+			}
+			if err := c.client.Reset(); err != nil {
+				c.client.Close()
+				continue
+			}
+			return c, nil
+		default:
+			return m.dial(deadline)
+		}
+	}
+}
+
+func (m *SMTPMailer) put(c *pooledClient) {
+	c.lastUsed = time.Now()
+	select {
+	case m.pool <- c:
+	default:
+		c.client.Quit()
+	}
+}
+
+func (m *SMTPMailer) dial(deadline time.Time) (*pooledClient, error) {
+	dialer := &net.Dialer{Timeout: m.opts.DialTimeout, Deadline: deadline}
+
+	var conn net.Conn
+	var err error
+	if m.opts.TLS == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", m.addr, m.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", m.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, m.opts.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if m.opts.TLS == TLSStartTLS || m.opts.TLS == TLSOpportunistic {
+		ok, _ := client.Extension("STARTTLS")
+		switch {
+		case ok:
+			if err := client.StartTLS(m.tlsConfig); err != nil {
+				client.Close()
+				return nil, err
+			}
+		case m.opts.TLS == TLSStartTLS:
+			client.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+	}
+
+	// без AUTH письмо ушло бы неавторизованным, и сервер мог бы его отклонить или принять как спам.
+	// PlainAuth сам откажется передавать пароль без TLS на сервер, отличный от localhost
+	if m.opts.Username != "" || m.opts.Password != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			client.Close()
+			return nil, ErrAuthUnsupported
+		}
+		if err := client.Auth(smtp.PlainAuth("", m.opts.Username, m.opts.Password, m.opts.Host)); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return &pooledClient{conn: conn, client: client}, nil
+}
+
+func send(c *smtp.Client, from string, to []string, data []byte) error {
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,153 @@
+package mailer
+
+import (
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Сервер принял соединение и молчит: отправка должна завершиться ошибкой по SendTimeout, а не висеть
+func TestSMTPSendTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	m := NewSMTPMailer(SMTPOptions{Host: host, Port: port, SendTimeout: 200 * time.Millisecond})
+	defer m.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Send("from@food.app", []string{"to@food.app"}, []byte("Subject: test\r\n\r\ntest\r\n"))
+	}()
+
+	select {
+	case err := <-done:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Fatalf("ожидался таймаут, получено: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("отправка не прервана по SendTimeout")
+	}
+}
+
+// fakeSMTP минимальный SMTP сервер: отвечает на EHLO списком extensions и принимает одно письмо
+func fakeSMTP(t *testing.T, extensions []string) (host, port string, received chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received = make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+
+		_ = tp.PrintfLine("220 fake ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.Fields(line + " ")[0])
+			switch cmd {
+			case "EHLO":
+				lines := append([]string{"fake"}, extensions...)
+				for i, ext := range lines {
+					sep := "-"
+					if i == len(lines)-1 {
+						sep = " "
+					}
+					_ = tp.PrintfLine("250%s%s", sep, ext)
+				}
+			case "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				received <- string(data)
+				_ = tp.PrintfLine("250 queued")
+			case "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				return
+			default:
+				_ = tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port, received
+}
+
+// По умолчанию STARTTLS используется, если сервер его предлагает, а без него письмо уходит как раньше
+func TestSMTPOpportunisticWithoutStartTLS(t *testing.T) {
+	host, port, received := fakeSMTP(t, nil)
+	m := NewSMTPMailer(SMTPOptions{Host: host, Port: port})
+	defer m.Close()
+
+	if err := m.Send("from@food.app", []string{"to@food.app"}, []byte("Subject: test\r\n\r\nbody\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; !strings.Contains(got, "body") {
+		t.Fatalf("письмо: %q", got)
+	}
+}
+
+func TestSMTPRequiredStartTLS(t *testing.T) {
+	host, port, _ := fakeSMTP(t, nil)
+	m := NewSMTPMailer(SMTPOptions{Host: host, Port: port, TLS: TLSStartTLS})
+	defer m.Close()
+
+	if err := m.Send("from@food.app", []string{"to@food.app"}, []byte("test")); err == nil {
+		t.Fatal("без STARTTLS на сервере отправка должна завершиться ошибкой")
+	}
+}
+
+// Заданные логин и пароль не пропускаются молча, если сервер не предлагает AUTH
+func TestSMTPCredentialsWithoutAuth(t *testing.T) {
+	host, port, _ := fakeSMTP(t, []string{"8BITMIME"})
+	m := NewSMTPMailer(SMTPOptions{Host: host, Port: port, Username: "user", Password: "secret"})
+	defer m.Close()
+
+	err := m.Send("from@food.app", []string{"to@food.app"}, []byte("test"))
+	if !errors.Is(err, ErrAuthUnsupported) {
+		t.Fatalf("err = %v, want ErrAuthUnsupported", err)
+	}
+}
+
+func TestCheckTLSMode(t *testing.T) {
+	for _, mode := range []string{TLSNone, TLSOpportunistic, TLSStartTLS, TLSImplicit} {
+		if err := CheckTLSMode(mode); err != nil {
+			t.Errorf("%s: %v", mode, err)
+		}
+	}
+	for _, mode := range []string{"", "ssl", "STARTTLS"} {
+		if err := CheckTLSMode(mode); err == nil {
+			t.Errorf("режим %q должен быть отклонен", mode)
+		}
+	}
+}
@@ -22,7 +22,7 @@ func (r *AccessTokenRepository) SaveTx(ctx context.Context, tx *sqlx.Tx, token *
 		token,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := tx.QueryRowxContext(
@@ -30,7 +30,7 @@ func (r *AccessTokenRepository) SaveTx(ctx context.Context, tx *sqlx.Tx, token *
 		query,
 		args...,
 	).Scan(&token.Id); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -44,7 +44,7 @@ func (r *AccessTokenRepository) DeleteByRefreshTokenByTokenTx(ctx context.Contex
            join auth.refresh_token rt on rt.access_token_id=ac.id where rt.token=$1)`,
 		refreshToken,
 	); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -53,25 +53,42 @@ func (r *AccessTokenRepository) DeleteByRefreshTokenByTokenTx(ctx context.Contex
 func (r *AccessTokenRepository) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids ...int64) error {
 	query, args, err := sqlx.In(`delete from auth.access_token where id in (?)`, ids)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	query = tx.Rebind(query)
 
 	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
 }
 
+// DeleteByIdsReturningTx удаляет токены и возвращает их, чтобы занести в черный список
+func (r *AccessTokenRepository) DeleteByIdsReturningTx(ctx context.Context, tx *sqlx.Tx, ids ...int64) ([]entity.AccessToken, error) {
+	query, args, err := sqlx.In(`delete from auth.access_token where id in (?) returning *`, ids)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	query = tx.Rebind(query)
+
+	var res []entity.AccessToken
+	if err := tx.SelectContext(ctx, &res, query, args...); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
 func (r *AccessTokenRepository) DeleteByTokenTx(ctx context.Context, tx *sqlx.Tx, token string) error {
 	if err := tx.QueryRowxContext(
 		ctx,
 		`delete from auth.access_token where token=$1`,
 		token,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -89,7 +106,7 @@ func (r *AccessTokenRepository) FindByToken(token string) (*entity.AccessToken,
 		"select * from auth.access_token where token = $1",
 		token,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &res, nil
@@ -22,38 +22,62 @@ func (r *AuditRepository) Save(audit *entity.Audit) error {
 	defer cancel()
 
 	query, args, err := r.BindNamed(
-		`insert into auth.audit_log(user_id, action, ip_address, user_agent)
-			VALUES (:user_id, :action, :ip_address, :user_agent)`,
+		`insert into auth.audit_log(user_id, actor_id, action, ip_address, user_agent, details)
+			VALUES (:user_id, :actor_id, :action, nullif(:ip_address, '')::inet, :user_agent, cast(:details as jsonb))`,
 		audit,
 	)
 
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := r.QueryRowxContext(ctx, query, args...).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 	return nil
 }
 
 func (r *AuditRepository) SaveTx(ctx context.Context, tx *sqlx.Tx, audit *entity.Audit) error {
 	query, args, err := tx.BindNamed(
-		`insert into auth.audit_log(user_id, action, ip_address, user_agent)
-			VALUES (:user_id, :action, :ip_address, :user_agent)`,
+		`insert into auth.audit_log(user_id, actor_id, action, ip_address, user_agent, details)
+			VALUES (:user_id, :actor_id, :action, nullif(:ip_address, '')::inet, :user_agent, cast(:details as jsonb))`,
 		audit,
 	)
 
 	if err != nil {
-		return err
+		return dbError(err)
 	}
-	if err := r.QueryRowxContext(ctx, query, args...).Err(); err != nil {
-		return err
+	if err := tx.QueryRowxContext(ctx, query, args...).Err(); err != nil {
+		return dbError(err)
 	}
 
 	return nil
 }
 
+// FindByUserId последние события, где пользователь был целью или исполнителем
+func (r *AuditRepository) FindByUserId(userId int64, limit int) ([]entity.Audit, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.Audit
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select id, user_id, actor_id, action, coalesce(host(ip_address), '') as ip_address,
+       		coalesce(user_agent, '') as user_agent, details::text as details, created_at
+			from auth.audit_log
+			where user_id = $1 or actor_id = $1
+			order by created_at desc
+			limit $2`,
+		userId,
+		limit,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
 func (r *AuditRepository) CreateTx() (*sqlx.Tx, error) {
 	return createTx(r.DB)
 }
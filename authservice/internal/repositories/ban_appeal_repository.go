@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type BanAppealRepository struct {
+	*postgre.PostgresDb
+}
+
+func NewBanAppealRepository(db *postgre.PostgresDb) *BanAppealRepository {
+	return &BanAppealRepository{db}
+}
+
+func (r *BanAppealRepository) Save(appeal *entity.BanAppeal) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query, args, err := r.BindNamed(
+		`insert into auth.ban_appeals(ban_id, user_id, message, status)
+			values (:ban_id, :user_id, :message, :status)
+			returning id, created_at`,
+		appeal,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if err := r.QueryRowxContext(ctx, query, args...).Scan(&appeal.Id, &appeal.CreatedAt); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *BanAppealRepository) FindById(id int64) (*entity.BanAppeal, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.BanAppeal
+	if err := r.GetContext(ctx, &res, "select * from auth.ban_appeals where id = $1", id); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+// FindPendingByBanId ищет необработанную апелляцию по блокировке
+func (r *BanAppealRepository) FindPendingByBanId(banId int64) (*entity.BanAppeal, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.BanAppeal
+	if err := r.GetContext(
+		ctx,
+		&res,
+		"select * from auth.ban_appeals where ban_id = $1 and status = $2",
+		banId,
+		entity.AppealStatusPending,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+// FindByStatus апелляции с указанным статусом. Пустой статус - все апелляции
+func (r *BanAppealRepository) FindByStatus(status string, limit int) ([]entity.BanAppeal, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.BanAppeal
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select * from auth.ban_appeals
+			where $1 = '' or status = $1
+			order by created_at, id
+			limit $2`,
+		status,
+		limit,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+// ReviewTx выставляет решение по апелляции, если она еще не рассмотрена
+func (r *BanAppealRepository) ReviewTx(ctx context.Context, tx *sqlx.Tx, appeal *entity.BanAppeal) error {
+	query, args, err := tx.BindNamed(
+		`update auth.ban_appeals
+			set status = :status, reviewed_by = :reviewed_by, review_comment = :review_comment, reviewed_at = now()
+			where id = :id and status = 'pending'
+			returning reviewed_at`,
+		appeal,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if err := tx.QueryRowxContext(ctx, query, args...).Scan(&appeal.ReviewedAt); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *BanAppealRepository) CreateTx() (*sqlx.Tx, error) {
+	return createTx(r.DB)
+}
+
+func (r *BanAppealRepository) CommitTx(tx *sqlx.Tx) error {
+	return commitTx(tx)
+}
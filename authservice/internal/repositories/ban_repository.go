@@ -22,22 +22,22 @@ func (r *BanRepository) Save(ban *entity.Ban) error {
 	defer cancel()
 
 	query, args, err := r.BindNamed(
-		`insert into auth.users_ban(user_id, cause, expired_at, is_forever) 
-			values (:user_id, :cause, :expired_at, :is_forever)
+		`insert into auth.users_ban(user_id, cause, expired_at, is_forever, reason_category, issued_by) 
+			values (:user_id, :cause, :expired_at, :is_forever, :reason_category, :issued_by)
 			returning id, created_at`,
 		ban,
 	)
 
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := r.QueryRowxContext(
 		ctx,
 		query,
-		args,
+		args...,
 	).Scan(&ban.Id, &ban.CreatedAt); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -53,11 +53,12 @@ func (r *BanRepository) FindActiveUserBans(userId int64) (*entity.Ban, error) {
 		ctx,
 		&ban,
 		`select ub.* from auth.users_ban ub join auth.users u on ub.user_id = u.id
-         where u.id = $1 and (now() < expired_at or is_forever = true)
-         order by created_at desc`,
+         where u.id = $1 and ub.unbanned_at is null and (now() < ub.expired_at or ub.is_forever = true)
+         order by ub.created_at desc
+         limit 1`,
 		userId,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &ban, nil
@@ -65,11 +66,13 @@ func (r *BanRepository) FindActiveUserBans(userId int64) (*entity.Ban, error) {
 
 func (r *BanRepository) SetBanTx(ctx context.Context, tx *sqlx.Tx, ban *entity.Ban) error {
 	query, args, err := tx.BindNamed(
-		"insert into auth.users_ban (user_id, cause, expired_at, is_forever) values (:user_id, :cause, :expired_at, :is_forever) returning id, created_at",
+		`insert into auth.users_ban (user_id, cause, expired_at, is_forever, reason_category, issued_by)
+			values (:user_id, :cause, :expired_at, :is_forever, :reason_category, :issued_by)
+			returning id, created_at`,
 		ban,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := tx.QueryRowxContext(
@@ -77,24 +80,94 @@ func (r *BanRepository) SetBanTx(ctx context.Context, tx *sqlx.Tx, ban *entity.B
 		query,
 		args...,
 	).Scan(&ban.Id, &ban.CreatedAt); err != nil {
-		return err
+		return dbError(err)
 	}
 	return nil
 }
 
-func (r *BanRepository) DeleteByUserId(userId int64) error {
+// FindByUserId история блокировок пользователя, новые сверху
+func (r *BanRepository) FindByUserId(userId int64) ([]entity.Ban, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := r.QueryRowxContext(
+	var res []entity.Ban
+	if err := r.SelectContext(
 		ctx,
-		`delete from auth.users_ban where user_id = $1`,
+		&res,
+		"select * from auth.users_ban where user_id = $1 order by created_at desc, id desc",
 		userId,
-	).Err(); err != nil {
-		return err
+	); err != nil {
+		return nil, dbError(err)
 	}
 
-	return nil
+	return res, nil
+}
+
+// LiftActiveByUserIdTx закрывает активные блокировки пользователя. Строки остаются в истории.
+// Открытая блокировка у пользователя одна (уникальный индекс), но запрос этого не предполагает
+func (r *BanRepository) LiftActiveByUserIdTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userId int64,
+	unbannedBy *int64,
+	reason string,
+) ([]entity.Ban, error) {
+	var res []entity.Ban
+	if err := tx.SelectContext(
+		ctx,
+		&res,
+		`update auth.users_ban set unbanned_at = now(), unbanned_by = $2, unban_reason = nullif($3, '')
+			where user_id = $1 and unbanned_at is null and (now() < expired_at or is_forever = true)
+			returning *`,
+		userId,
+		unbannedBy,
+		reason,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+// LiftExpiredByUserIdTx закрывает истекшие, но еще не закрытые шедулером блокировки пользователя,
+// чтобы они не мешали новой блокировке
+func (r *BanRepository) LiftExpiredByUserIdTx(ctx context.Context, tx *sqlx.Tx, userId int64) ([]entity.Ban, error) {
+	var res []entity.Ban
+	if err := tx.SelectContext(
+		ctx,
+		&res,
+		`update auth.users_ban set unbanned_at = expired_at, unban_reason = $2
+			where user_id = $1 and unbanned_at is null and is_forever = false and expired_at <= now()
+			returning *`,
+		userId,
+		entity.UnbanReasonExpired,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+// LiftExpiredTx закрывает пачку истекших блокировок и возвращает их
+func (r *BanRepository) LiftExpiredTx(ctx context.Context, tx *sqlx.Tx, limit int) ([]entity.Ban, error) {
+	var res []entity.Ban
+	if err := tx.SelectContext(
+		ctx,
+		&res,
+		`update auth.users_ban set unbanned_at = expired_at, unban_reason = $1
+			where id in (select id from auth.users_ban
+			             where unbanned_at is null and is_forever = false and expired_at <= now()
+			             order by expired_at
+			             limit $2
+			             for update skip locked)
+			returning *`,
+		entity.UnbanReasonExpired,
+		limit,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
 }
 
 func (r *BanRepository) CreateTx() (*sqlx.Tx, error) {
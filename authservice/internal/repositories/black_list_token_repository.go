@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type BlackListTokenRepository struct {
+	*postgre.PostgresDb
+}
+
+func NewBlackListTokenRepository(db *postgre.PostgresDb) *BlackListTokenRepository {
+	return &BlackListTokenRepository{db}
+}
+
+func (r *BlackListTokenRepository) SaveAllTx(ctx context.Context, tx *sqlx.Tx, tokens []entity.BlackListToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	if _, err := tx.NamedExecContext(
+		ctx,
+		`insert into auth.black_list_token (token, expired_at) values (:token, :expired_at)
+			on conflict (token) do nothing`,
+		tokens,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *BlackListTokenRepository) CreateTx() (*sqlx.Tx, error) {
+	return createTx(r.DB)
+}
+
+func (r *BlackListTokenRepository) CommitTx(tx *sqlx.Tx) error {
+	return commitTx(tx)
+}
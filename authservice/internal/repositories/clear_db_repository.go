@@ -24,7 +24,7 @@ func (r *ClearDBRepository) ClearDB() error {
 		ctx,
 		`select auth.clean_expired_data()`,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"context"
+	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// currentConsentDocuments последняя опубликованная версия каждого типа документа
+const currentConsentDocuments = `select distinct on (type) * from auth.consent_documents
+	order by type, published_at desc, id desc`
+
+type ConsentRepository struct {
+	*postgre.PostgresDb
+}
+
+func NewConsentRepository(db *postgre.PostgresDb) *ConsentRepository {
+	return &ConsentRepository{db}
+}
+
+func (r *ConsentRepository) SaveDocument(doc *entity.ConsentDocument) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query, args, err := r.BindNamed(
+		`insert into auth.consent_documents(type, version, url, published_by)
+			values (:type, :version, :url, :published_by)
+			returning id, published_at`,
+		doc,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if err := r.QueryRowxContext(ctx, query, args...).Scan(&doc.Id, &doc.PublishedAt); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *ConsentRepository) ExistsDocument(docType, version string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res := false
+	if err := r.QueryRowxContext(
+		ctx,
+		`select exists(select 1 from auth.consent_documents where type = $1 and version = $2)`,
+		docType,
+		version,
+	).Scan(&res); err != nil {
+		return false
+	}
+
+	return res
+}
+
+func (r *ConsentRepository) FindAllDocuments() ([]entity.ConsentDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.ConsentDocument
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select * from auth.consent_documents order by published_at desc, id desc`,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+func (r *ConsentRepository) FindCurrentDocuments() ([]entity.ConsentDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.ConsentDocument
+	if err := r.SelectContext(ctx, &res, currentConsentDocuments); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+// FindPendingDocuments текущие версии документов, которые пользователь еще не принял
+func (r *ConsentRepository) FindPendingDocuments(userId int64) ([]entity.ConsentDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.ConsentDocument
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select d.* from (`+currentConsentDocuments+`) d
+			where not exists(select 1 from auth.user_consents uc where uc.user_id = $1 and uc.document_id = d.id)
+			order by d.id`,
+		userId,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+func (r *ConsentRepository) SaveUserConsentsTx(ctx context.Context, tx *sqlx.Tx, userId int64, documentIds []int64) error {
+	for _, documentId := range documentIds {
+		if _, err := tx.ExecContext(
+			ctx,
+			`insert into auth.user_consents(user_id, document_id) values ($1, $2) on conflict do nothing`,
+			userId,
+			documentId,
+		); err != nil {
+			return dbError(err)
+		}
+	}
+
+	return nil
+}
+
+func (r *ConsentRepository) FindByUserId(userId int64) ([]entity.UserConsent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.UserConsent
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select uc.document_id, d.type, d.version, uc.accepted_at
+			from auth.user_consents uc
+			join auth.consent_documents d on d.id = uc.document_id
+			where uc.user_id = $1
+			order by uc.accepted_at, uc.id`,
+		userId,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+func (r *ConsentRepository) CreateTx() (*sqlx.Tx, error) {
+	return createTx(r.DB)
+}
+
+func (r *ConsentRepository) CommitTx(tx *sqlx.Tx) error {
+	return commitTx(tx)
+}
@@ -27,11 +27,11 @@ func (r *EmailVerificationCodeRepository) Save(code *entity.EmailVerificationCod
 		code,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := r.QueryRowxContext(ctx, query, args...).Scan(&code.Id); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -45,11 +45,11 @@ func (r *EmailVerificationCodeRepository) SaveTx(ctx context.Context, tx *sqlx.T
 		code,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := tx.QueryRowxContext(ctx, query, args...).Scan(&code.Id); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -67,7 +67,7 @@ func (r *EmailVerificationCodeRepository) FindByCode(codeString string) (*entity
 		`select * from auth.email_verification_codes where code=$1`,
 		codeString,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &code, nil
@@ -83,7 +83,7 @@ func (r *EmailVerificationCodeRepository) FindByCodeTx(ctx context.Context, tx *
 		`select * from auth.email_verification_codes where code=$1`,
 		codeString,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &code, nil
@@ -98,7 +98,7 @@ func (r *EmailVerificationCodeRepository) DeleteByCode(codeString string) error
 		`delete from auth.email_verification_codes where code = $1`,
 		codeString,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -114,7 +114,7 @@ func (r *EmailVerificationCodeRepository) SetVerified(codeString string, b bool)
 		b,
 		codeString,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 	return nil
 }
@@ -135,12 +135,124 @@ func (r *EmailVerificationCodeRepository) FindCodeByVerifiedToken(token string)
 	where t.token = $1`,
 		token,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &res, nil
 }
 
+// CodeIssueStats сколько кодов выдано пользователю за окно и сколько секунд ждать до следующей выдачи
+type CodeIssueStats struct {
+	Issued       int   `db:"issued"`
+	CooldownLeft int64 `db:"cooldown_left"`
+	WindowLeft   int64 `db:"window_left"`
+}
+
+const codeIssueStatsQuery = `select count(*) as issued,
+		coalesce(ceil(extract(epoch from max(created_at) + make_interval(secs => $3) - now())), 0)::bigint as cooldown_left,
+		coalesce(ceil(extract(epoch from min(created_at) + make_interval(mins => $2) - now())), 0)::bigint as window_left
+	from auth.email_verification_codes
+	where user_id = $1 and created_at > now() - make_interval(mins => $2)`
+
+// FindIssueStats считает выданные за windowMinutes коды. Интервалы считаются в базе,
+// так как created_at заполняется базой
+func (r *EmailVerificationCodeRepository) FindIssueStats(userId int64, windowMinutes, cooldownSeconds int) (*CodeIssueStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res CodeIssueStats
+	if err := r.GetContext(ctx, &res, codeIssueStatsQuery, userId, windowMinutes, cooldownSeconds); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+// FindIssueStatsForUpdateTx как FindIssueStats, но сначала блокирует строку пользователя до конца транзакции.
+// Одновременные запросы кода одного пользователя выполняются по очереди и видят коды друг друга
+func (r *EmailVerificationCodeRepository) FindIssueStatsForUpdateTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userId int64,
+	windowMinutes, cooldownSeconds int,
+) (*CodeIssueStats, error) {
+	var locked int64
+	if err := tx.GetContext(ctx, &locked, `select id from auth.users where id = $1 for update`, userId); err != nil {
+		return nil, dbError(err)
+	}
+
+	var res CodeIssueStats
+	if err := tx.GetContext(ctx, &res, codeIssueStatsQuery, userId, windowMinutes, cooldownSeconds); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+// FindActiveByUserId последний действующий код пользователя
+func (r *EmailVerificationCodeRepository) FindActiveByUserId(userId int64) (*entity.EmailVerificationCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.EmailVerificationCode
+	if err := r.GetContext(
+		ctx,
+		&res,
+		`select * from auth.email_verification_codes
+			where user_id = $1 and is_verified = true
+			order by created_at desc, id desc
+			limit 1`,
+		userId,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+// InvalidateByUserIdTx аннулирует прежние коды пользователя и ссылки из писем с ними
+func (r *EmailVerificationCodeRepository) InvalidateByUserIdTx(ctx context.Context, tx *sqlx.Tx, userId int64) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`update auth.email_verification_token set is_active = false
+			where code_id in (select id from auth.email_verification_codes where user_id = $1)`,
+		userId,
+	); err != nil {
+		return dbError(err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`update auth.email_verification_codes set is_verified = false where user_id = $1 and is_verified = true`,
+		userId,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// AddFailedAttempt увеличивает счетчик неверных попыток и аннулирует код, когда достигнут maxAttempts
+func (r *EmailVerificationCodeRepository) AddFailedAttempt(id int64, maxAttempts int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var attempts int
+	if err := r.QueryRowxContext(
+		ctx,
+		`update auth.email_verification_codes
+			set failed_attempts = failed_attempts + 1, is_verified = failed_attempts + 1 < $2
+			where id = $1
+			returning failed_attempts`,
+		id,
+		maxAttempts,
+	).Scan(&attempts); err != nil {
+		return 0, dbError(err)
+	}
+
+	return attempts, nil
+}
+
 func (r *EmailVerificationCodeRepository) CreateTx() (*sqlx.Tx, error) {
 	return createTx(r.DB)
 }
@@ -25,11 +25,11 @@ func (r *EmailVerificationTokenRepository) Save(tok *entity.EmailVerificationTok
 		tok,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := r.QueryRowxContext(ctx, query, args...).Scan(&tok.Id); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -45,7 +45,7 @@ func (r *EmailVerificationTokenRepository) SetIsActive(token string, b bool) err
 		b,
 		token,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -63,7 +63,7 @@ func (r *EmailVerificationTokenRepository) FindToken(token string) (*entity.Emai
 		"select * from auth.email_verification_token where token=$1",
 		token,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &res, nil
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type InviteCodeRepository struct {
+	*postgre.PostgresDb
+}
+
+func NewInviteCodeRepository(db *postgre.PostgresDb) *InviteCodeRepository {
+	return &InviteCodeRepository{db}
+}
+
+func (r *InviteCodeRepository) SaveTx(ctx context.Context, tx *sqlx.Tx, invite *entity.InviteCode) error {
+	query, args, err := tx.BindNamed(
+		`insert into auth.invite_codes(code, max_uses, expired_at, created_by)
+			values (:code, :max_uses, :expired_at, :created_by)
+			returning id, created_at`,
+		invite,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if err := tx.QueryRowxContext(ctx, query, args...).Scan(&invite.Id, &invite.CreatedAt); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *InviteCodeRepository) SaveRoleTx(ctx context.Context, tx *sqlx.Tx, inviteId, roleId int64) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`insert into auth.invite_code_roles(invite_id, role_id) values ($1, $2) on conflict do nothing`,
+		inviteId,
+		roleId,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// FindAll инвайт-коды с именами ролей, новые первыми
+func (r *InviteCodeRepository) FindAll(limit int) ([]entity.InviteCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.InviteCode
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select ic.*,
+				coalesce((select string_agg(r.name, ',' order by r.name) from auth.role r
+					join auth.invite_code_roles icr on r.id = icr.role_id where icr.invite_id = ic.id), '') as roles
+			from auth.invite_codes ic
+			order by ic.created_at desc, ic.id desc
+			limit $1`,
+		limit,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+// FindByCodeForUpdateTx блокирует строку кода до конца транзакции, чтобы параллельные регистрации не превысили лимит
+func (r *InviteCodeRepository) FindByCodeForUpdateTx(ctx context.Context, tx *sqlx.Tx, code string) (*entity.InviteCode, error) {
+	var res entity.InviteCode
+	if err := tx.GetContext(
+		ctx,
+		&res,
+		`select *, '' as roles from auth.invite_codes where code = $1 for update`,
+		code,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+func (r *InviteCodeRepository) FindRoleIdsTx(ctx context.Context, tx *sqlx.Tx, inviteId int64) ([]int64, error) {
+	var res []int64
+	if err := tx.SelectContext(
+		ctx,
+		&res,
+		`select role_id from auth.invite_code_roles where invite_id = $1`,
+		inviteId,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+// UseTx увеличивает счетчик использований и записывает, кто зарегистрировался по коду
+func (r *InviteCodeRepository) UseTx(ctx context.Context, tx *sqlx.Tx, inviteId, userId int64) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`update auth.invite_codes set used_count = used_count + 1 where id = $1`,
+		inviteId,
+	); err != nil {
+		return dbError(err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`insert into auth.invite_code_usages(invite_id, user_id) values ($1, $2)`,
+		inviteId,
+		userId,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *InviteCodeRepository) FindUsages(inviteId int64) ([]entity.InviteCodeUsage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.InviteCodeUsage
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select * from auth.invite_code_usages where invite_id = $1 order by used_at, id`,
+		inviteId,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+// Revoke отзывает код. Уже отозванный код не меняется
+func (r *InviteCodeRepository) Revoke(id int64) (*entity.InviteCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.InviteCode
+	if err := r.GetContext(
+		ctx,
+		&res,
+		`update auth.invite_codes set revoked_at = coalesce(revoked_at, now())
+			where id = $1
+			returning *, '' as roles`,
+		id,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+func (r *InviteCodeRepository) CreateTx() (*sqlx.Tx, error) {
+	return createTx(r.DB)
+}
+
+func (r *InviteCodeRepository) CommitTx(tx *sqlx.Tx) error {
+	return commitTx(tx)
+}
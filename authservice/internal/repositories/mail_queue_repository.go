@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"context"
+	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"time"
+)
+
+type MailQueueRepository struct {
+	*postgre.PostgresDb
+}
+
+func NewMailQueueRepository(db *postgre.PostgresDb) *MailQueueRepository {
+	return &MailQueueRepository{db}
+}
+
+func (r *MailQueueRepository) Save(msg *entity.MailMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query, args, err := r.BindNamed(
+		`insert into auth.mail_queue (template, sender, recipients, subject, html_body, text_body)
+			values (:template, :sender, :recipients, :subject, :html_body, :text_body)
+			returning id, status, attempts, next_attempt_at, created_at`,
+		msg,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if err := r.QueryRowxContext(ctx, query, args...).Scan(
+		&msg.Id,
+		&msg.Status,
+		&msg.Attempts,
+		&msg.NextAttemptAt,
+		&msg.CreatedAt,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// Claim забирает письма, готовые к отправке, и продлевает их на lease. Если worker упадет, не отметив результат,
+// письмо снова станет доступно после lease. Попытка засчитывается при захвате, поэтому письмо не зациклится
+func (r *MailQueueRepository) Claim(limit int, lease time.Duration) ([]entity.MailMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.MailMessage
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`update auth.mail_queue
+			set status = 'sending', attempts = attempts + 1, next_attempt_at = now() + make_interval(secs => $2)
+			where id in (select id from auth.mail_queue
+			             where status in ('pending', 'sending') and next_attempt_at <= now()
+			             order by id
+			             limit $1
+			             for update skip locked)
+			returning *`,
+		limit,
+		lease.Seconds(),
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+// MarkSent отмечает доставку и стирает тексты письма: в них коды и ссылки для входа, хранить их после доставки незачем
+func (r *MailQueueRepository) MarkSent(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`update auth.mail_queue
+			set status = 'sent', sent_at = now(), last_error = null, html_body = '', text_body = ''
+			where id = $1`,
+		id,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// MarkFailed возвращает письмо в очередь с отложенной попыткой
+func (r *MailQueueRepository) MarkFailed(id int64, lastError string, nextAttemptAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`update auth.mail_queue set status = 'pending', last_error = $2, next_attempt_at = $3 where id = $1`,
+		id,
+		lastError,
+		nextAttemptAt,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// Release возвращает захваченное, но не отправленное письмо в очередь без учета попытки
+func (r *MailQueueRepository) Release(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`update auth.mail_queue
+			set status = 'pending', attempts = greatest(attempts - 1, 0), next_attempt_at = now()
+			where id = $1 and status = 'sending'`,
+		id,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *MailQueueRepository) MarkDead(id int64, lastError string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`update auth.mail_queue set status = 'dead', last_error = $2 where id = $1`,
+		id,
+		lastError,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// Requeue возвращает письмо из dead в очередь со сброшенным счетчиком попыток
+func (r *MailQueueRepository) Requeue(id int64) (*entity.MailMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.MailMessage
+	if err := r.GetContext(
+		ctx,
+		&res,
+		`update auth.mail_queue set status = 'pending', attempts = 0, next_attempt_at = now()
+			where id = $1 and status = 'dead'
+			returning *`,
+		id,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+func (r *MailQueueRepository) FindById(id int64) (*entity.MailMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.MailMessage
+	if err := r.GetContext(ctx, &res, `select * from auth.mail_queue where id = $1`, id); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+// FindAll последние письма, status пустой - любые
+func (r *MailQueueRepository) FindAll(status string, limit int) ([]entity.MailMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.MailMessage
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select * from auth.mail_queue where ($1 = '' or status = $1) order by id desc limit $2`,
+		status,
+		limit,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+// DeleteSentBefore удаляет доставленные письма старше указанного времени
+func (r *MailQueueRepository) DeleteSentBefore(before time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`delete from auth.mail_queue where status = 'sent' and sent_at < $1`,
+		before,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// DeleteDeadBefore удаляет недоставленные письма, созданные раньше указанного времени
+func (r *MailQueueRepository) DeleteDeadBefore(before time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`delete from auth.mail_queue where status = 'dead' and created_at < $1`,
+		before,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
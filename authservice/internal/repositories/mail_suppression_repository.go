@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type MailSuppressionRepository struct {
+	*postgre.PostgresDb
+}
+
+func NewMailSuppressionRepository(db *postgre.PostgresDb) *MailSuppressionRepository {
+	return &MailSuppressionRepository{db}
+}
+
+// UpsertTx добавляет адрес в список. Жалоба не заменяется возвратом, так как она важнее
+func (r *MailSuppressionRepository) UpsertTx(ctx context.Context, tx *sqlx.Tx, s *entity.MailSuppression) error {
+	if err := tx.GetContext(
+		ctx,
+		s,
+		`insert into auth.mail_suppressions (email, reason, detail) values ($1, $2, $3)
+			on conflict (lower(email)) do update
+			set reason     = case when auth.mail_suppressions.reason = 'complaint' then auth.mail_suppressions.reason
+			                      else excluded.reason end,
+			    detail     = excluded.detail,
+			    updated_at = now()
+			returning *`,
+		s.Email,
+		s.Reason,
+		s.Detail,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *MailSuppressionRepository) ExistsByEmail(email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res bool
+	if err := r.GetContext(
+		ctx,
+		&res,
+		`select exists(select 1 from auth.mail_suppressions where lower(email) = lower($1))`,
+		email,
+	); err != nil {
+		return false, dbError(err)
+	}
+
+	return res, nil
+}
+
+func (r *MailSuppressionRepository) FindAll(limit int) ([]entity.MailSuppression, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.MailSuppression
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select * from auth.mail_suppressions order by updated_at desc, id desc limit $1`,
+		limit,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+func (r *MailSuppressionRepository) DeleteById(id int64) (*entity.MailSuppression, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.MailSuppression
+	if err := r.GetContext(ctx, &res, `delete from auth.mail_suppressions where id = $1 returning *`, id); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+func (r *MailSuppressionRepository) CreateTx() (*sqlx.Tx, error) {
+	return createTx(r.DB)
+}
+
+func (r *MailSuppressionRepository) CommitTx(tx *sqlx.Tx) error {
+	return commitTx(tx)
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// outboxLockId ключ advisory lock: отправкой занимается только один экземпляр сервиса, иначе нарушится порядок
+const outboxLockId = 7243100001
+
+type OutboxRepository struct {
+	*postgre.PostgresDb
+}
+
+func NewOutboxRepository(db *postgre.PostgresDb) *OutboxRepository {
+	return &OutboxRepository{db}
+}
+
+func (r *OutboxRepository) SaveTx(ctx context.Context, tx *sqlx.Tx, msg *entity.OutboxMessage) error {
+	query, args, err := tx.BindNamed(
+		`insert into auth.outbox (aggregate_key, event_type, topic, payload)
+			values (:aggregate_key, :event_type, :topic, cast(:payload as jsonb))
+			returning id, created_at, next_attempt_at`,
+		msg,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if err := tx.QueryRowxContext(ctx, query, args...).Scan(&msg.Id, &msg.CreatedAt, &msg.NextAttemptAt); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// TryLockTx берет блокировку отправителя до конца транзакции. false - отправкой уже занят другой экземпляр
+func (r *OutboxRepository) TryLockTx(ctx context.Context, tx *sqlx.Tx) (bool, error) {
+	var locked bool
+	if err := tx.GetContext(ctx, &locked, "select pg_try_advisory_xact_lock($1)", outboxLockId); err != nil {
+		return false, dbError(err)
+	}
+
+	return locked, nil
+}
+
+// FindReadyTx неотправленные сообщения, у которых подошло время попытки.
+// Сообщение не берется, пока по тому же ключу есть более раннее сообщение, ожидающее повтора
+func (r *OutboxRepository) FindReadyTx(ctx context.Context, tx *sqlx.Tx, limit int) ([]entity.OutboxMessage, error) {
+	var res []entity.OutboxMessage
+	if err := tx.SelectContext(
+		ctx,
+		&res,
+		`select o.id, o.aggregate_key, o.event_type, o.topic, o.payload::text as payload, o.created_at,
+		        o.attempts, o.next_attempt_at, o.last_error, o.published_at
+			from auth.outbox o
+			where o.published_at is null
+			  and o.dead_at is null
+			  and o.next_attempt_at <= now()
+			  and not exists(select 1 from auth.outbox p
+			                 where p.aggregate_key = o.aggregate_key
+			                   and p.published_at is null
+			                   and p.dead_at is null
+			                   and p.id < o.id
+			                   and p.next_attempt_at > now())
+			order by o.id
+			limit $1`,
+		limit,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+func (r *OutboxRepository) MarkPublishedTx(ctx context.Context, tx *sqlx.Tx, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args, err := sqlx.In(`update auth.outbox set published_at = now(), last_error = null where id in (?)`, ids)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// MarkFailedTx увеличивает счетчик попыток и откладывает следующую попытку
+func (r *OutboxRepository) MarkFailedTx(ctx context.Context, tx *sqlx.Tx, id int64, lastError string, nextAttemptAt time.Time) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`update auth.outbox set attempts = attempts + 1, last_error = $2, next_attempt_at = $3 where id = $1`,
+		id,
+		lastError,
+		nextAttemptAt,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// MarkDeadTx откладывает сообщение в dead-letter: оно больше не отправляется и остается в таблице для разбора
+func (r *OutboxRepository) MarkDeadTx(ctx context.Context, tx *sqlx.Tx, id int64, reason string) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`update auth.outbox set dead_at = now(), last_error = $2 where id = $1`,
+		id,
+		reason,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// DeletePublishedBefore удаляет отправленные сообщения старше указанного времени
+func (r *OutboxRepository) DeletePublishedBefore(before time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`delete from auth.outbox where published_at is not null and published_at < $1`,
+		before,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) CreateTx() (*sqlx.Tx, error) {
+	return createTx(r.DB)
+}
+
+func (r *OutboxRepository) CommitTx(tx *sqlx.Tx) error {
+	return commitTx(tx)
+}
@@ -25,7 +25,7 @@ func (r *PasswordHistoryRepository) Save(pass *entity.PasswordHistory) error {
 			values (:user_id, :old_password) returning id`, pass,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := r.QueryRowxContext(
@@ -33,7 +33,27 @@ func (r *PasswordHistoryRepository) Save(pass *entity.PasswordHistory) error {
 		query,
 		args...,
 	).Scan(&pass.Id); err != nil {
-		return err
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *PasswordHistoryRepository) SaveTx(ctx context.Context, tx *sqlx.Tx, pass *entity.PasswordHistory) error {
+	query, args, err := tx.BindNamed(
+		`insert into auth.password_history (user_id, old_password) 
+			values (:user_id, :old_password) returning id`, pass,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if err := tx.QueryRowxContext(
+		ctx,
+		query,
+		args...,
+	).Scan(&pass.Id); err != nil {
+		return dbError(err)
 	}
 
 	return nil
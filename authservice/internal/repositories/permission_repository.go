@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"context"
+	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type PermissionRepository struct {
+	*postgre.PostgresDb
+}
+
+func NewPermissionRepository(db *postgre.PostgresDb) *PermissionRepository {
+	return &PermissionRepository{db}
+}
+
+func (r *PermissionRepository) GetPermissionsByRoleId(roleId int64) []entity.Permission {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.Permission
+
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select p.* from auth.permission p join auth.role_permission rp on p.id = rp.permission_id
+			where rp.role_id=$1`,
+		roleId,
+	); err != nil {
+		return make([]entity.Permission, 0)
+	}
+
+	return res
+}
+
+func (r *PermissionRepository) FindById(id int64) (*entity.Permission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.Permission
+	if err := r.GetContext(
+		ctx,
+		&res,
+		`select * from auth.permission where id=$1`,
+		id,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+func (r *PermissionRepository) FindByName(name string) (*entity.Permission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.Permission
+	if err := r.GetContext(
+		ctx,
+		&res,
+		`select * from auth.permission where name=$1`,
+		name,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+func (r *PermissionRepository) FindAll() []entity.Permission {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.Permission
+
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select * from auth.permission order by id`,
+	); err != nil {
+		return make([]entity.Permission, 0)
+	}
+
+	return res
+}
+
+func (r *PermissionRepository) Save(permission *entity.Permission) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query, args, err := r.BindNamed(
+		`insert into auth.permission (name, description) values (:name, :description) returning id`,
+		permission,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if err := r.QueryRowxContext(ctx, query, args...).Scan(&permission.Id); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *PermissionRepository) Update(permission *entity.Permission) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query, args, err := r.BindNamed(
+		`update auth.permission set name = :name, description = :description where id = :id`,
+		permission,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if _, err := r.ExecContext(ctx, query, args...); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *PermissionRepository) DeleteById(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`delete from auth.permission where id = $1`,
+		id,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *PermissionRepository) AddToRole(roleId, permissionId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`insert into auth.role_permission (role_id, permission_id) values ($1, $2) on conflict do nothing`,
+		roleId,
+		permissionId,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *PermissionRepository) RemoveFromRole(roleId, permissionId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`delete from auth.role_permission where role_id = $1 and permission_id = $2`,
+		roleId,
+		permissionId,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *PermissionRepository) FindRoleIdsByPermissionId(permissionId int64) []int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []int64
+
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select role_id from auth.role_permission where permission_id = $1`,
+		permissionId,
+	); err != nil {
+		return make([]int64, 0)
+	}
+
+	return res
+}
+
+func (r *PermissionRepository) CreateTx() (*sqlx.Tx, error) {
+	return createTx(r.DB)
+}
+
+func (r *PermissionRepository) CommitTx(tx *sqlx.Tx) error {
+	return commitTx(tx)
+}
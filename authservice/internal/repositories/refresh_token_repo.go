@@ -28,11 +28,11 @@ func (r *RefreshTokenRepository) Save(token *entity.RefreshToken) error {
 		token,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := r.QueryRowxContext(ctx, query, args...).Scan(&token.Id); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -46,11 +46,11 @@ func (r *RefreshTokenRepository) SaveTx(ctx context.Context, tx *sqlx.Tx, token
 		token,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := tx.QueryRowxContext(ctx, query, args...).Scan(&token.Id); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -68,7 +68,7 @@ func (r *RefreshTokenRepository) FindByToken(token string) (*entity.RefreshToken
 		`select * from auth.refresh_token where token = $1`,
 		token,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &res, nil
@@ -83,7 +83,7 @@ func (r *RefreshTokenRepository) FindByTokenTx(ctx context.Context, tx *sqlx.Tx,
 		`select * from auth.refresh_token where token = $1`,
 		token,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &res, nil
@@ -98,7 +98,7 @@ func (r *RefreshTokenRepository) DeleteByToken(token string) error {
 		`delete from auth.refresh_token where token = $1`,
 		token,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -110,7 +110,7 @@ func (r *RefreshTokenRepository) DeleteByTokenTx(ctx context.Context, tx *sqlx.T
 		`delete from auth.refresh_token where token = $1`,
 		token,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -126,7 +126,7 @@ func (r *RefreshTokenRepository) RevokeToken(token string, isRevoked bool) error
 		isRevoked,
 		token,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 	return nil
 }
@@ -140,10 +140,10 @@ func (r *RefreshTokenRepository) Update(token *entity.RefreshToken) error {
 		token,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 	if err := r.QueryRowxContext(ctx, query, args...).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 	return nil
 }
@@ -158,7 +158,7 @@ func (r *RefreshTokenRepository) RemoveAllRefreshTokensUser(userId int64) ([]ent
 		userId,
 	)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 	defer func(rows *sqlx.Rows) {
 		err := rows.Close()
@@ -171,7 +171,7 @@ func (r *RefreshTokenRepository) RemoveAllRefreshTokensUser(userId int64) ([]ent
 	for rows.Next() {
 		var token entity.RefreshToken
 		if err := rows.StructScan(&token); err != nil {
-			return nil, err
+			return nil, dbError(err)
 		}
 		tokens = append(tokens, token)
 	}
@@ -186,7 +186,7 @@ func (r *RefreshTokenRepository) RemoveAllRefreshTokensUserTx(ctx context.Contex
 		userId,
 	)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 	defer func(rows *sqlx.Rows) {
 		err := rows.Close()
@@ -199,7 +199,7 @@ func (r *RefreshTokenRepository) RemoveAllRefreshTokensUserTx(ctx context.Contex
 	for rows.Next() {
 		var token entity.RefreshToken
 		if err := rows.StructScan(&token); err != nil {
-			return nil, err
+			return nil, dbError(err)
 		}
 		tokens = append(tokens, token)
 	}
@@ -219,7 +219,7 @@ func (r *RefreshTokenRepository) FindByAccessToken(token string) (*entity.Refres
 			where act.token = $1`,
 		token,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &res, nil
@@ -238,12 +238,31 @@ func (r *RefreshTokenRepository) RemoveByAccessTokenTx(ctx context.Context, tx *
        			where ac.token = $1) returning token`,
 		accessToken,
 	).Scan(&refreshToken); err != nil {
-		return "", err
+		return "", dbError(err)
 	}
 
 	return refreshToken, nil
 }
 
+func (r *RefreshTokenRepository) FindActiveByUserId(userId int64) ([]entity.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.RefreshToken
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select * from auth.refresh_token
+			where user_id = $1 and expired_at > now() and is_revoke = false
+			order by issue_at desc`,
+		userId,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
 func (r *RefreshTokenRepository) CreateTx() (*sqlx.Tx, error) {
 	return createTx(r.DB)
 }
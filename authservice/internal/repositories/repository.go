@@ -1,6 +1,16 @@
 package repositories
 
-import "github.com/jmoiron/sqlx"
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/EddyZe/foodApp/common/pkg/apperr"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
 
 type Repository interface {
 	CreateTx() (*sqlx.Tx, error)
@@ -10,13 +20,41 @@ type Repository interface {
 func commitTx(tx *sqlx.Tx) error {
 	if err := tx.Commit(); err != nil {
 		if err := tx.Rollback(); err != nil {
-			return err
+			return dbError(err)
 		}
-		return err
+		return dbError(err)
 	}
 	return nil
 }
 
 func createTx(db *sqlx.DB) (*sqlx.Tx, error) {
-	return db.Beginx()
+	tx, err := db.Beginx()
+	return tx, dbError(err)
+}
+
+// dbError переводит ошибки базы в виды apperr: нет строки - NotFound, нарушение уникальности - Conflict,
+// обрыв соединения или таймаут - Unavailable. Остальные ошибки возвращаются как есть. Исходная
+// ошибка остается причиной, errors.Is(err, sql.ErrNoRows) продолжает работать
+func dbError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pqErr *pq.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return apperr.NotFound(err)
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		return apperr.Conflict(err)
+	case errors.As(err, &pqErr) && (pqErr.Code.Class() == "08" || pqErr.Code.Class() == "57"):
+		// 08 - ошибки соединения, 57 - сервер останавливается или запрос отменен
+		return apperr.Unavailable(err)
+	case errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr):
+		return apperr.Unavailable(err)
+	}
+	return err
 }
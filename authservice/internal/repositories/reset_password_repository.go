@@ -27,7 +27,7 @@ func (r *ResetPasswordRepository) Save(reset *entity.ResetPasswordCode) error {
 	)
 
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := r.QueryRowxContext(
@@ -35,7 +35,7 @@ func (r *ResetPasswordRepository) Save(reset *entity.ResetPasswordCode) error {
 		query,
 		args...,
 	).Scan(&reset.Id, &reset.CreatedAt, &reset.IsValid); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -48,7 +48,7 @@ func (r *ResetPasswordRepository) SaveTx(ctx context.Context, tx *sqlx.Tx, reset
 		reset,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := tx.QueryRowxContext(
@@ -56,7 +56,7 @@ func (r *ResetPasswordRepository) SaveTx(ctx context.Context, tx *sqlx.Tx, reset
 		query,
 		args...,
 	).Scan(&reset.Id, &reset.CreatedAt, &reset.IsValid); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -74,7 +74,7 @@ func (r *ResetPasswordRepository) FindByCode(code string) (*entity.ResetPassword
 		`select * from auth.reset_password_codes where code = $1`,
 		code,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &res, nil
@@ -89,7 +89,7 @@ func (r *ResetPasswordRepository) DeleteByCode(code string) error {
 		`delete from auth.reset_password_codes where code = $1`,
 		code,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -101,7 +101,7 @@ func (r *ResetPasswordRepository) DeleteByCodeTx(ctx context.Context, tx *sqlx.T
 		`delete from auth.reset_password_codes where code = $1`,
 		code,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -117,7 +117,91 @@ func (r *ResetPasswordRepository) SetIsValid(code string, b bool) error {
 		b,
 		code,
 	).Err(); err != nil {
-		return err
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *ResetPasswordRepository) FindById(id int64) (*entity.ResetPasswordCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.ResetPasswordCode
+	if err := r.GetContext(ctx, &res, `select * from auth.reset_password_codes where id = $1`, id); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+// FindActiveByUserId последний действующий код пользователя
+func (r *ResetPasswordRepository) FindActiveByUserId(userId int64) (*entity.ResetPasswordCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.ResetPasswordCode
+	if err := r.GetContext(
+		ctx,
+		&res,
+		`select * from auth.reset_password_codes
+			where user_id = $1 and is_valid = true
+			order by created_at desc, id desc
+			limit 1`,
+		userId,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+// AddFailedAttempt увеличивает счетчик неверных попыток и аннулирует код, когда достигнут maxAttempts
+func (r *ResetPasswordRepository) AddFailedAttempt(id int64, maxAttempts int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var attempts int
+	if err := r.QueryRowxContext(
+		ctx,
+		`update auth.reset_password_codes
+			set failed_attempts = failed_attempts + 1, is_valid = failed_attempts + 1 < $2
+			where id = $1
+			returning failed_attempts`,
+		id,
+		maxAttempts,
+	).Scan(&attempts); err != nil {
+		return 0, dbError(err)
+	}
+
+	return attempts, nil
+}
+
+// ConsumeTx аннулирует действующий код и возвращает его пользователя. Если код уже использован
+// или аннулирован, строка не обновляется и возвращается ErrNotFound
+func (r *ResetPasswordRepository) ConsumeTx(ctx context.Context, tx *sqlx.Tx, id int64) (int64, error) {
+	var userId int64
+	if err := tx.QueryRowxContext(
+		ctx,
+		`update auth.reset_password_codes set is_valid = false
+			where id = $1 and is_valid = true
+			returning user_id`,
+		id,
+	).Scan(&userId); err != nil {
+		return 0, dbError(err)
+	}
+
+	return userId, nil
+}
+
+// InvalidateByUserIdTx аннулирует все коды сброса пользователя
+func (r *ResetPasswordRepository) InvalidateByUserIdTx(ctx context.Context, tx *sqlx.Tx, userId int64) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`update auth.reset_password_codes set is_valid = false where user_id = $1 and is_valid = true`,
+		userId,
+	); err != nil {
+		return dbError(err)
 	}
 
 	return nil
@@ -21,16 +21,16 @@ func (r *RoleRepository) FindByName(name string) (*entity.Role, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	var res *entity.Role
+	var res entity.Role
 
 	if err := r.GetContext(
 		ctx,
-		res,
+		&res,
 		`select * from auth.role where name=$1`,
 		name); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
-	return res, nil
+	return &res, nil
 }
 
 func (r *RoleRepository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (*entity.Role, error) {
@@ -40,7 +40,7 @@ func (r *RoleRepository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name str
 		&res,
 		`select * from auth.role where name =$1`,
 		name); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &res, nil
@@ -65,6 +65,92 @@ func (r *RoleRepository) GetRoleByUserId(userId int64) []entity.Role {
 	return res
 }
 
+func (r *RoleRepository) FindById(id int64) (*entity.Role, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res entity.Role
+	if err := r.GetContext(
+		ctx,
+		&res,
+		`select * from auth.role where id=$1`,
+		id); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &res, nil
+}
+
+func (r *RoleRepository) FindAll() []entity.Role {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []entity.Role
+
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select * from auth.role order by id`,
+	); err != nil {
+		return make([]entity.Role, 0)
+	}
+
+	return res
+}
+
+func (r *RoleRepository) Save(role *entity.Role) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query, args, err := r.BindNamed(
+		`insert into auth.role (name, description) values (:name, :description) returning id`,
+		role,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if err := r.QueryRowxContext(ctx, query, args...).Scan(&role.Id); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *RoleRepository) Update(role *entity.Role) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query, args, err := r.BindNamed(
+		`update auth.role set name = :name, description = :description where id = :id`,
+		role,
+	)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if _, err := r.ExecContext(ctx, query, args...); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *RoleRepository) DeleteById(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := r.ExecContext(
+		ctx,
+		`delete from auth.role where id = $1`,
+		id,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
 func (r *RoleRepository) CreateTx() (*sqlx.Tx, error) {
 	return createTx(r.DB)
 }
@@ -2,8 +2,10 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
 	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -28,11 +30,11 @@ func (r *UserRepository) Save(u *entity.User) error {
 		u,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := r.QueryRowxContext(ctx, query, args...).Scan(&u.Id); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -40,15 +42,15 @@ func (r *UserRepository) Save(u *entity.User) error {
 
 func (r *UserRepository) SaveTx(ctx context.Context, tx *sqlx.Tx, u *entity.User) error {
 	query, args, err := tx.BindNamed(
-		`insert into auth.users (email, password) values (:email, :password) returning id`,
+		`insert into auth.users (email, password) values (:email, :password) returning id, created_at, updated_at`,
 		u,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
-	if err := tx.QueryRowxContext(ctx, query, args...).Scan(&u.Id); err != nil {
-		return err
+	if err := tx.QueryRowxContext(ctx, query, args...).Scan(&u.Id, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		return dbError(err)
 	}
 
 	return nil
@@ -64,7 +66,7 @@ func (r *UserRepository) FindByEmail(email string) (*entity.User, error) {
 		`select * from auth.users where email = $1`,
 		email,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 	return &u, nil
 }
@@ -78,7 +80,7 @@ func (r *UserRepository) FindByEmailTx(ctx context.Context, tx *sqlx.Tx, email s
 		`select * from auth.users where email = $1`,
 		email,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 	return &u, nil
 }
@@ -114,7 +116,7 @@ func (r *UserRepository) FindByRefreshToken(refreshToken string) (*entity.User,
     	where rt.token=$1`,
 		refreshToken,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &u, nil
@@ -130,7 +132,7 @@ func (r *UserRepository) FindById(id int64) (*entity.User, error) {
 		`select * from auth.users where id = $1`,
 		id,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return &u, nil
@@ -149,27 +151,219 @@ func (r *UserRepository) SetEmailIsConfirm(userId int64, b bool) (*entity.User,
 		b,
 		userId,
 	); err != nil {
-		return nil, err
+		return nil, dbError(err)
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) SetEmailIsConfirmTx(ctx context.Context, tx *sqlx.Tx, userId int64, b bool) (*entity.User, error) {
+	var user entity.User
+
+	if err := tx.GetContext(
+		ctx,
+		&user,
+		`update auth.users set email_is_confirm = $1 where id = $2 returning *`,
+		b,
+		userId,
+	); err != nil {
+		return nil, dbError(err)
 	}
 
 	return &user, nil
 }
 
-func (r *UserRepository) EditPassword(userId int64, newPassword string) error {
+// SetLocale сохраняет предпочитаемый язык, nil - сбросить
+func (r *UserRepository) SetLocale(userId int64, locale *string) (*entity.User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
 
-	if err := r.QueryRowxContext(
+	var user entity.User
+
+	if err := r.GetContext(
+		ctx,
+		&user,
+		`update auth.users set locale = $1, updated_at = now() where id = $2 returning *`,
+		locale,
+		userId,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &user, nil
+}
+
+// DeleteByIdTx удаляет пользователя. Токены, роли и блокировки удаляются каскадно
+func (r *UserRepository) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, userId int64) (*entity.User, error) {
+	var user entity.User
+
+	if err := tx.GetContext(
+		ctx,
+		&user,
+		`delete from auth.users where id = $1 returning *`,
+		userId,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &user, nil
+}
+
+// FindByIdForUpdateTx читает пользователя и блокирует строку до конца транзакции
+func (r *UserRepository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*entity.User, error) {
+	var u entity.User
+	if err := tx.GetContext(
+		ctx,
+		&u,
+		`select * from auth.users where id = $1 for update`,
+		id,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return &u, nil
+}
+
+func (r *UserRepository) EditPasswordTx(ctx context.Context, tx *sqlx.Tx, userId int64, newPassword string) (*entity.User, error) {
+	var user entity.User
+
+	if err := tx.GetContext(
 		ctx,
-		`update auth.users set password = $1 where id = $2`,
+		&user,
+		`update auth.users set password = $1 where id = $2 returning *`,
 		newPassword,
 		userId,
-	).Err(); err != nil {
-		return err
+	); err != nil {
+		return nil, dbError(err)
 	}
 
-	return nil
+	return &user, nil
+}
+
+// UserSearchParams фильтры и keyset-пагинация списка пользователей.
+// AfterValue и AfterId - значения сортируемой колонки и id последней строки предыдущей страницы
+type UserSearchParams struct {
+	EmailPrefix    string
+	EmailConfirmed *bool
+	Role           string
+	Banned         *bool
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+	SortColumn     string
+	Desc           bool
+	AfterValue     interface{}
+	AfterId        int64
+	Limit          int
+}
+
+var userSortColumns = map[string]string{
+	"id":         "u.id",
+	"email":      "u.email",
+	"created_at": "u.created_at",
+}
+
+const userIsBannedCondition = `exists(select 1 from auth.users_ban ub
+		where ub.user_id = u.id and ub.unbanned_at is null and (now() < ub.expired_at or ub.is_forever = true))`
+
+func (r *UserRepository) Search(params *UserSearchParams) ([]entity.UserSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	query, args := buildUserSearchQuery(params)
+
+	var res []entity.UserSummary
+	if err := r.SelectContext(ctx, &res, query, args...); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+// buildUserSearchQuery запрос списка пользователей и его аргументы
+func buildUserSearchQuery(params *UserSearchParams) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if params.EmailPrefix != "" {
+		conditions = append(conditions, "lower(u.email) like "+arg(escapeLike(strings.ToLower(params.EmailPrefix))+"%"))
+	}
+	if params.EmailConfirmed != nil {
+		conditions = append(conditions, "coalesce(u.email_is_confirm, false) = "+arg(*params.EmailConfirmed))
+	}
+	if params.Role != "" {
+		conditions = append(conditions, `exists(select 1 from auth.user_roles ur join auth.role r on r.id = ur.role_id
+			where ur.user_id = u.id and r.name = `+arg(params.Role)+`)`)
+	}
+	if params.Banned != nil {
+		if *params.Banned {
+			conditions = append(conditions, userIsBannedCondition)
+		} else {
+			conditions = append(conditions, "not "+userIsBannedCondition)
+		}
+	}
+	if params.CreatedFrom != nil {
+		conditions = append(conditions, "u.created_at >= "+arg(*params.CreatedFrom))
+	}
+	if params.CreatedTo != nil {
+		conditions = append(conditions, "u.created_at < "+arg(*params.CreatedTo))
+	}
+
+	column, ok := userSortColumns[params.SortColumn]
+	if !ok {
+		column = userSortColumns["id"]
+	}
+	direction, cmp := "asc", ">"
+	if params.Desc {
+		direction, cmp = "desc", "<"
+	}
+
+	if params.AfterId > 0 {
+		if column == userSortColumns["id"] {
+			conditions = append(conditions, fmt.Sprintf("u.id %s %s", cmp, arg(params.AfterId)))
+		} else {
+			// created_at хранится без часового пояса, поэтому значение курсора приводится к тому же типу
+			value := arg(params.AfterValue)
+			if column == userSortColumns["created_at"] {
+				value += "::timestamp"
+			}
+			conditions = append(conditions, fmt.Sprintf("(%s, u.id) %s (%s, %s)", column, cmp, value, arg(params.AfterId)))
+		}
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "where " + strings.Join(conditions, " and ")
+	}
+
+	orderBy := fmt.Sprintf("u.id %s", direction)
+	if column != userSortColumns["id"] {
+		orderBy = fmt.Sprintf("%s %s, u.id %s", column, direction, direction)
+	}
+
+	query := fmt.Sprintf(
+		`select u.id, u.email, coalesce(u.email_is_confirm, false) as email_is_confirm, u.created_at, u.updated_at,
+			coalesce((select string_agg(r.name, ',' order by r.name) from auth.role r
+				join auth.user_roles ur on r.id = ur.role_id where ur.user_id = u.id), '') as roles,
+			%s as is_banned
+		from auth.users u
+		%s
+		order by %s
+		limit %s`,
+		userIsBannedCondition,
+		where,
+		orderBy,
+		arg(params.Limit),
+	)
+
+	return query, args
+}
 
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
 func (r *UserRepository) CreateTx() (*sqlx.Tx, error) {
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildUserSearchQuery(t *testing.T) {
+	confirmed := true
+	banned := false
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	query, args := buildUserSearchQuery(&UserSearchParams{
+		EmailPrefix:    "Ivan_%",
+		EmailConfirmed: &confirmed,
+		Role:           "ADMIN",
+		Banned:         &banned,
+		CreatedFrom:    &from,
+		SortColumn:     "email",
+		Desc:           true,
+		AfterValue:     "ivan@mail.ru",
+		AfterId:        42,
+		Limit:          21,
+	})
+
+	for _, part := range []string{
+		"lower(u.email) like $1",
+		"coalesce(u.email_is_confirm, false) = $2",
+		"r.name = $3",
+		"not exists(select 1 from auth.users_ban",
+		"u.created_at >= $4",
+		"(u.email, u.id) < ($5, $6)",
+		"order by u.email desc, u.id desc",
+		"limit $7",
+	} {
+		if !strings.Contains(query, part) {
+			t.Errorf("в запросе нет %q:\n%s", part, query)
+		}
+	}
+
+	want := []interface{}{`ivan\_\%%`, true, "ADMIN", from, "ivan@mail.ru", int64(42), 21}
+	if len(args) != len(want) {
+		t.Fatalf("аргументы: ожидалось %v, получено %v", want, args)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("аргумент $%d: ожидалось %v, получено %v", i+1, want[i], args[i])
+		}
+	}
+}
+
+func TestBuildUserSearchQueryDefaults(t *testing.T) {
+	query, args := buildUserSearchQuery(&UserSearchParams{SortColumn: "password", AfterId: 7, Limit: 11})
+
+	if strings.Contains(query, "password") {
+		t.Errorf("неизвестная колонка сортировки попала в запрос:\n%s", query)
+	}
+	for _, part := range []string{"where u.id > $1", "order by u.id asc", "limit $2"} {
+		if !strings.Contains(query, part) {
+			t.Errorf("в запросе нет %q:\n%s", part, query)
+		}
+	}
+	if len(args) != 2 || args[0] != int64(7) || args[1] != 11 {
+		t.Errorf("аргументы: получено %v", args)
+	}
+}
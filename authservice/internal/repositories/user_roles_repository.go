@@ -27,10 +27,10 @@ func (r *UserRoleRepository) SetUserRole(role entity.UserRole) error {
 	)
 
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 	if err := r.QueryRowxContext(ctx, query, args...).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -42,16 +42,64 @@ func (r *UserRoleRepository) SetUserRoleTx(ctx context.Context, tx *sqlx.Tx, rol
 		role,
 	)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if err := tx.QueryRowxContext(ctx, query, atgs...).Err(); err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
 }
 
+// DeleteUserRoleTx снимает роль с пользователя в транзакции
+func (r *UserRoleRepository) DeleteUserRoleTx(ctx context.Context, tx *sqlx.Tx, userId, roleId int64) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`delete from auth.user_roles where user_id = $1 and role_id = $2`,
+		userId,
+		roleId,
+	); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// LockUserIdsByRoleIdTx пользователи с ролью. Строки блокируются до конца транзакции,
+// чтобы параллельные снятия роли видели актуальное число держателей
+func (r *UserRoleRepository) LockUserIdsByRoleIdTx(ctx context.Context, tx *sqlx.Tx, roleId int64) ([]int64, error) {
+	var res []int64
+	if err := tx.SelectContext(
+		ctx,
+		&res,
+		`select user_id from auth.user_roles where role_id = $1 for update`,
+		roleId,
+	); err != nil {
+		return nil, dbError(err)
+	}
+
+	return res, nil
+}
+
+func (r *UserRoleRepository) FindUserIdsByRoleId(roleId int64) []int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res []int64
+
+	if err := r.SelectContext(
+		ctx,
+		&res,
+		`select user_id from auth.user_roles where role_id = $1`,
+		roleId,
+	); err != nil {
+		return make([]int64, 0)
+	}
+
+	return res
+}
+
 func (r *UserRoleRepository) CreateTx() (*sqlx.Tx, error) {
 	return createTx(r.DB)
 }
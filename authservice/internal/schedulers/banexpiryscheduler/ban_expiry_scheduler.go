@@ -0,0 +1,35 @@
+package banexpiryscheduler
+
+import (
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+type BanExpiryScheduler struct {
+	log *logrus.Entry
+	bs  *services.BanService
+}
+
+func NewBanExpiryScheduler(log *logrus.Entry, bs *services.BanService) *BanExpiryScheduler {
+	return &BanExpiryScheduler{
+		log: log,
+		bs:  bs,
+	}
+}
+
+// Start раз в минуту закрывает истекшие блокировки
+func (s *BanExpiryScheduler) Start() error {
+	c := cron.New()
+
+	if _, err := c.AddFunc("* * * * *", func() {
+		if err := s.bs.LiftExpiredBans(); err != nil {
+			s.log.Error(err)
+		}
+	}); err != nil {
+		s.log.Error("Ошибка при запуске шедулера: ", err)
+		return err
+	}
+	c.Start()
+	return nil
+}
@@ -0,0 +1,50 @@
+package mailscheduler
+
+import (
+	"fmt"
+	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+type MailScheduler struct {
+	log *logrus.Entry
+	cfg *config.MailQueueConfig
+	ms  *services.MailService
+}
+
+func NewMailScheduler(log *logrus.Entry, cfg *config.MailQueueConfig, ms *services.MailService) *MailScheduler {
+	return &MailScheduler{
+		log: log,
+		cfg: cfg,
+		ms:  ms,
+	}
+}
+
+// Start разбирает очередь писем каждые MailQueueConfig.IntervalSeconds и раз в час чистит доставленные
+// и недоставленные письма
+func (s *MailScheduler) Start() error {
+	c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+
+	if _, err := c.AddFunc(fmt.Sprintf("@every %ds", s.cfg.IntervalSeconds), func() {
+		if err := s.ms.ProcessQueue(); err != nil {
+			s.log.Error(err)
+		}
+	}); err != nil {
+		s.log.Error("Ошибка при запуске шедулера: ", err)
+		return err
+	}
+
+	if _, err := c.AddFunc("30 * * * *", func() {
+		if err := s.ms.CleanQueue(); err != nil {
+			s.log.Error(err)
+		}
+	}); err != nil {
+		s.log.Error("Ошибка при запуске шедулера: ", err)
+		return err
+	}
+
+	c.Start()
+	return nil
+}
@@ -0,0 +1,49 @@
+package outboxscheduler
+
+import (
+	"fmt"
+	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+type OutboxScheduler struct {
+	log *logrus.Entry
+	cfg *config.OutboxConfig
+	obs *services.OutboxService
+}
+
+func NewOutboxScheduler(log *logrus.Entry, cfg *config.OutboxConfig, obs *services.OutboxService) *OutboxScheduler {
+	return &OutboxScheduler{
+		log: log,
+		cfg: cfg,
+		obs: obs,
+	}
+}
+
+// Start отправляет outbox в брокер каждые OutboxConfig.IntervalSeconds и раз в час чистит отправленные сообщения
+func (s *OutboxScheduler) Start() error {
+	c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+
+	if _, err := c.AddFunc(fmt.Sprintf("@every %ds", s.cfg.IntervalSeconds), func() {
+		if err := s.obs.Relay(); err != nil {
+			s.log.Error(err)
+		}
+	}); err != nil {
+		s.log.Error("Ошибка при запуске шедулера: ", err)
+		return err
+	}
+
+	if _, err := c.AddFunc("0 * * * *", func() {
+		if err := s.obs.CleanPublished(); err != nil {
+			s.log.Error(err)
+		}
+	}); err != nil {
+		s.log.Error("Ошибка при запуске шедулера: ", err)
+		return err
+	}
+
+	c.Start()
+	return nil
+}
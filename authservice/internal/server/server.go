@@ -8,8 +8,13 @@ import (
 	"time"
 
 	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/EddyZe/foodApp/authservice/internal/datasourse/postgre"
+	"github.com/EddyZe/foodApp/authservice/internal/datasourse/redis"
 	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/ratelimit"
 	"github.com/EddyZe/foodApp/common/middleware"
+	"github.com/EddyZe/foodApp/common/pkg/permissions"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -24,7 +29,17 @@ func New(
 	mvs *services.EmailVerificationService,
 	lms *commonService.LocalizeService,
 	rp *services.ResetPasswordService,
+	as *services.AuditService,
+	is *services.InviteService,
+	cs *services.ConsentService,
+	sps *services.MailSuppressionService,
 	appInfo *config.AppInfo,
+	bounceCfg *config.MailBounceConfig,
+	transportCfg *config.MailTransportConfig,
+	rateCfg *config.RateLimitConfig,
+	problemCfg *config.ProblemConfig,
+	psql *postgre.PostgresDb,
+	red *redis.Redis,
 ) *http.Server {
 	router := gin.New()
 
@@ -37,32 +52,147 @@ func New(
 		WriteTimeout:   30 * time.Second,
 		MaxHeaderBytes: 1 << 20,
 	}
+	router.Use(middleware.Trace())
+	router.Use(middleware.Problems(responseutil.ProblemOptions{
+		Enabled:  problemCfg.Enabled,
+		TypeBase: problemCfg.TypeBase,
+	}))
 	router.Use(middleware.Logger(logger))
 	router.Use(gin.Recovery())
+	router.Use(middleware.Locale(lms))
+	router.Use(rest.AuditImpersonation(as))
 
-	auth := rest.NewAuthHandler(logger, us, ts, rs, bs, lms)
-	emailVerificationHandler := rest.NewEmailVerificationHandler(us, ts, rs, logger, ms, mvs, lms, appInfo)
-	resetPasswordHandler := rest.NewResetPasswordHandler(logger, us, ms, rp, lms, appInfo)
+	auth := rest.NewAuthHandler(logger, us, ts, rs, bs, as, cs, lms)
+	emailVerificationHandler := rest.NewEmailVerificationHandler(us, ts, rs, logger, ms, sps, mvs, lms, appInfo)
+	rateWindow := time.Duration(rateCfg.WindowSeconds) * time.Second
+	resetPasswordHandler := rest.NewResetPasswordHandler(
+		logger,
+		us,
+		ms,
+		rp,
+		lms,
+		appInfo,
+		ratelimit.New(rateCfg.ResetPasswordPerEmail, rateWindow),
+		ratelimit.New(rateCfg.ResetPasswordPerIp, rateWindow),
+	)
+	adminRoleHandler := rest.NewAdminRoleHandler(logger, rs, us, as, lms)
+	adminUserHandler := rest.NewAdminUserHandler(logger, us, rs, ts, bs, as, cs, lms)
+	banHandler := rest.NewBanHandler(
+		logger,
+		us,
+		bs,
+		as,
+		lms,
+		ratelimit.New(rateCfg.BanAppealPerEmail, rateWindow),
+		ratelimit.New(rateCfg.BanAppealPerIp, rateWindow),
+	)
+	inviteHandler := rest.NewInviteHandler(logger, is, as, lms)
+	consentHandler := rest.NewConsentHandler(logger, cs, as, lms)
+	mailHandler := rest.NewMailHandler(logger, ms, sps, as, lms, bounceCfg.WebhookToken)
+	healthHandler := rest.NewHealthHandler(psql, red)
+
+	jwtFilter := middleware.JwtFilter(ts.Secret(), lms, ts)
+	denyImpersonation := middleware.DenyImpersonation(lms)
 
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	router.GET("/ping", auth.Ping)
+	router.GET("/ready", healthHandler.Ready)
+	// перехваченные письма содержат коды и ссылки для входа, поэтому просмотр включается явно и только для mail:manage
+	if inspector := ms.Inspector(); inspector != nil && transportCfg.DevInspector {
+		logger.Warn("Включен просмотр писем /dev/mail")
+		router.Any(
+			"/dev/mail",
+			jwtFilter,
+			denyImpersonation,
+			rest.ResolvePermissions(rs),
+			middleware.RequirePermission(lms, permissions.MailManage),
+			gin.WrapH(inspector),
+		)
+	}
 
 	apiV1 := router.Group("/api/v1")
 	apiV1.POST("/sing-up", auth.Registry)
 	apiV1.POST("/login", auth.Login)
 	apiV1.POST("/refresh", auth.Refresh)
-	apiV1.POST("/logout-all", middleware.JwtFilter(ts.Secret(), lms), auth.LogoutAll)
-	apiV1.POST("/logout", middleware.JwtFilter(ts.Secret(), lms), auth.Logout)
-	apiV1.POST("/ban", middleware.JwtFilter(ts.Secret(), lms), middleware.IsAdmin(lms), auth.BanUser)
-	apiV1.POST("/unban", middleware.JwtFilter(ts.Secret(), lms), middleware.IsAdmin(lms), auth.UnBanUser)
+	apiV1.POST("/logout-all", jwtFilter, denyImpersonation, auth.LogoutAll)
+	apiV1.POST("/logout", jwtFilter, auth.Logout)
+	apiV1.DELETE("/me", jwtFilter, denyImpersonation, auth.DeleteAccount)
+	apiV1.GET("/me/export", jwtFilter, denyImpersonation, auth.ExportData)
+	apiV1.GET("/me/locale", jwtFilter, auth.GetLocale)
+	apiV1.PATCH("/me/locale", jwtFilter, denyImpersonation, auth.UpdateLocale)
+	apiV1.GET("/consent-documents", consentHandler.GetCurrentDocuments)
+	apiV1.POST(
+		"/ban",
+		jwtFilter,
+		denyImpersonation,
+		rest.ResolvePermissions(rs),
+		middleware.RequirePermission(lms, permissions.UsersBan),
+		auth.BanUser,
+	)
+	apiV1.POST(
+		"/unban",
+		jwtFilter,
+		denyImpersonation,
+		rest.ResolvePermissions(rs),
+		middleware.RequirePermission(lms, permissions.UsersUnban),
+		auth.UnBanUser,
+	)
+
+	apiV1.POST("/ban-appeals", banHandler.CreateAppeal)
 
-	apiV1.POST("/email-code", middleware.JwtFilter(ts.Secret(), lms), emailVerificationHandler.SendMailConfirmCode)
-	apiV1.POST("/confirm-email", middleware.JwtFilter(ts.Secret(), lms), emailVerificationHandler.ConfirmMail)
+	apiV1.POST("/email-code", jwtFilter, denyImpersonation, emailVerificationHandler.SendMailConfirmCode)
+	apiV1.POST("/confirm-email", jwtFilter, denyImpersonation, emailVerificationHandler.ConfirmMail)
 	apiV1.GET("/confirm-email-url", emailVerificationHandler.ConfirmEmailByUrl)
 
+	if bounceCfg.WebhookToken != "" {
+		apiV1.POST("/mail/bounces", mailHandler.IngestBounces)
+	}
+
 	apiV1.POST("/reset-password-code", resetPasswordHandler.SendCode)
 	apiV1.PATCH("/edit-password", resetPasswordHandler.EditPassword)
 
+	// из сессии имперсонации админка недоступна: токен несет разрешения пользователя, а не администратора
+	admin := apiV1.Group("/admin", jwtFilter, denyImpersonation, rest.ResolvePermissions(rs))
+	manageRoles := middleware.RequirePermission(lms, permissions.RolesManage)
+	readUsers := middleware.RequirePermission(lms, permissions.UsersRead)
+	admin.GET("/users", readUsers, adminUserHandler.GetUsers)
+	admin.GET("/users/:id", readUsers, adminUserHandler.GetUser)
+	admin.GET("/users/:id/bans", readUsers, banHandler.GetBanHistory)
+	admin.POST(
+		"/impersonate/:userId",
+		middleware.RequirePermission(lms, permissions.UsersImpersonate),
+		adminUserHandler.Impersonate,
+	)
+	admin.GET("/ban-appeals", middleware.RequirePermission(lms, permissions.UsersUnban), banHandler.GetAppeals)
+	admin.POST("/ban-appeals/:id/review", middleware.RequirePermission(lms, permissions.UsersUnban), banHandler.ReviewAppeal)
+	manageConsents := middleware.RequirePermission(lms, permissions.ConsentsManage)
+	admin.GET("/consent-documents", manageConsents, consentHandler.GetDocuments)
+	admin.POST("/consent-documents", manageConsents, consentHandler.PublishDocument)
+	manageInvites := middleware.RequirePermission(lms, permissions.InvitesManage)
+	admin.GET("/invites", manageInvites, inviteHandler.GetInvites)
+	admin.POST("/invites", manageInvites, inviteHandler.CreateInvite)
+	admin.DELETE("/invites/:id", manageInvites, inviteHandler.RevokeInvite)
+	admin.GET("/invites/:id/usages", manageInvites, inviteHandler.GetUsages)
+	manageMail := middleware.RequirePermission(lms, permissions.MailManage)
+	admin.GET("/mail-messages", manageMail, mailHandler.GetMessages)
+	admin.GET("/mail-messages/:id", manageMail, mailHandler.GetMessage)
+	admin.POST("/mail-messages/:id/retry", manageMail, mailHandler.RetryMessage)
+	admin.GET("/mail-suppressions", manageMail, mailHandler.GetSuppressions)
+	admin.DELETE("/mail-suppressions/:id", manageMail, mailHandler.DeleteSuppression)
+	admin.GET("/roles", manageRoles, adminRoleHandler.GetRoles)
+	admin.POST("/roles", manageRoles, adminRoleHandler.CreateRole)
+	admin.PUT("/roles/:id", manageRoles, adminRoleHandler.UpdateRole)
+	admin.DELETE("/roles/:id", manageRoles, adminRoleHandler.DeleteRole)
+	admin.POST("/roles/:id/permissions/:permissionId", manageRoles, adminRoleHandler.AttachPermission)
+	admin.DELETE("/roles/:id/permissions/:permissionId", manageRoles, adminRoleHandler.DetachPermission)
+	admin.GET("/permissions", manageRoles, adminRoleHandler.GetPermissions)
+	admin.POST("/permissions", manageRoles, adminRoleHandler.CreatePermission)
+	admin.PUT("/permissions/:id", manageRoles, adminRoleHandler.UpdatePermission)
+	admin.DELETE("/permissions/:id", manageRoles, adminRoleHandler.DeletePermission)
+	admin.GET("/users/:id/roles", manageRoles, adminRoleHandler.GetUserRoles)
+	admin.POST("/users/:id/roles/:roleId", manageRoles, adminRoleHandler.GrantRole)
+	admin.DELETE("/users/:id/roles/:roleId", manageRoles, adminRoleHandler.RevokeRole)
+
 	logger.Infoln("Auth service starting. Port: ", port)
 	return s
 }
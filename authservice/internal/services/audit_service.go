@@ -0,0 +1,69 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditService struct {
+	log  *logrus.Entry
+	repo *repositories.AuditRepository
+}
+
+func NewAuditService(log *logrus.Entry, repo *repositories.AuditRepository) *AuditService {
+	return &AuditService{
+		log:  log,
+		repo: repo,
+	}
+}
+
+// Record сохраняет запись в журнал аудита. Ошибка только логируется, чтобы не ломать основное действие
+func (s *AuditService) Record(
+	action string,
+	actorId, userId int64,
+	ip, userAgent string,
+	details map[string]interface{},
+) {
+	audit := entity.Audit{
+		UserId:    nullId(userId),
+		ActorId:   nullId(actorId),
+		Action:    action,
+		IpAddress: ip,
+		UserAgent: userAgent,
+	}
+
+	if len(details) > 0 {
+		data, err := json.Marshal(details)
+		if err != nil {
+			s.log.Error("ошибка преобразования деталей аудита в json: ", err)
+		} else {
+			d := string(data)
+			audit.Details = &d
+		}
+	}
+
+	s.log.Debug("запись в журнал аудита: ", action)
+	if err := s.repo.Save(&audit); err != nil {
+		s.log.Error("ошибка при сохранении записи аудита: ", err)
+	}
+}
+
+// GetByUserId последние события аудита пользователя
+func (s *AuditService) GetByUserId(userId int64, limit int) []entity.Audit {
+	res, err := s.repo.FindByUserId(userId, limit)
+	if err != nil {
+		s.log.Error("ошибка при получении событий аудита: ", err)
+		return make([]entity.Audit, 0)
+	}
+	return res
+}
+
+func nullId(id int64) sql.NullInt64 {
+	return sql.NullInt64{
+		Int64: id,
+		Valid: id != 0,
+	}
+}
@@ -3,74 +3,126 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
 	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+	"github.com/EddyZe/foodApp/common/pkg/events"
 	"github.com/sirupsen/logrus"
 	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	liftExpiredBansBatch = 1000
+	banAppealsLimit      = 100
 )
 
 type BanService struct {
-	log  *logrus.Entry
-	repo *repositories.BanRepository
-	ts   *TokenService
+	log     *logrus.Entry
+	repo    *repositories.BanRepository
+	appeals *repositories.BanAppealRepository
+	ts      *TokenService
+	obs     *OutboxService
 }
 
-func NewBanService(log *logrus.Entry, repo *repositories.BanRepository, ts *TokenService) *BanService {
+func NewBanService(
+	log *logrus.Entry,
+	repo *repositories.BanRepository,
+	appeals *repositories.BanAppealRepository,
+	ts *TokenService,
+	obs *OutboxService,
+) *BanService {
 	return &BanService{
-		log:  log,
-		repo: repo,
-		ts:   ts,
+		log:     log,
+		repo:    repo,
+		appeals: appeals,
+		ts:      ts,
+		obs:     obs,
 	}
 }
 
 // GetActiveUserBan ищет блокировки пользователя
 func (s *BanService) GetActiveUserBan(userId int64) (*entity.Ban, bool) {
+	ban, err := s.FindActiveUserBan(userId)
+	if err != nil {
+		return nil, false
+	}
+
+	return ban, true
+}
 
+// FindActiveUserBan в отличие от GetActiveUserBan возвращает ошибку: ErrNotFound, если действующей блокировки нет,
+// и ошибку базы, если проверить блокировку не удалось
+func (s *BanService) FindActiveUserBan(userId int64) (*entity.Ban, error) {
 	s.log.Debug("Поиск заблокированного пользователя в БД")
 	ban, err := s.repo.FindActiveUserBans(userId)
 	if err != nil {
-		s.log.Debug("пользователь не найден с баном: ", err)
-		return nil, false
+		if errors.Is(err, ErrNotFound) {
+			s.log.Debug("пользователь не найден с баном: ", err)
+		} else {
+			s.log.Error("ошибка при проверке блокировки пользователя: ", err)
+		}
+		return nil, err
 	}
 
-	return ban, true
+	return ban, nil
 }
 
-func (s *BanService) BanUser(userId int64, cause string, expiredAt time.Time) (*entity.Ban, error) {
-	ban := entity.Ban{
-		UserId: sql.NullInt64{
-			Int64: userId,
-			Valid: true,
-		},
-		Cause:     cause,
-		ExpiredAt: expiredAt,
+// GetBanHistory все блокировки пользователя, включая снятые
+func (s *BanService) GetBanHistory(userId int64) []entity.Ban {
+	res, err := s.repo.FindByUserId(userId)
+	if err != nil {
+		s.log.Error("ошибка при получении истории блокировок: ", err)
+		return make([]entity.Ban, 0)
+	}
+
+	return res
+}
+
+func (s *BanService) BanUser(userId, issuedBy int64, category, cause string, expiredAt time.Time) (*entity.Ban, error) {
+	ban := newBan(userId, issuedBy, category, cause)
+	ban.ExpiredAt = expiredAt
+
+	if err := s.setBan(userId, ban); err != nil {
+		s.log.Error("ошибка при блокировке пользователя: ", err)
+		return nil, err
 	}
 
-	if err := s.setBan(userId, &ban); err != nil {
+	return ban, nil
+}
+
+func (s *BanService) BanUserForever(userId, issuedBy int64, category, cause string) (*entity.Ban, error) {
+	ban := newBan(userId, issuedBy, category, cause)
+	ban.IsForever = true
+	ban.ExpiredAt = time.Now()
+
+	if err := s.setBan(userId, ban); err != nil {
 		s.log.Error("ошибка при блокировке пользователя: ", err)
 		return nil, err
 	}
 
-	return &ban, nil
+	return ban, nil
 }
 
-func (s *BanService) BanUserForever(userId int64, cause string) (*entity.Ban, error) {
-	ban := entity.Ban{
+func newBan(userId, issuedBy int64, category, cause string) *entity.Ban {
+	if category == "" {
+		category = entity.BanCategoryOther
+	}
+
+	ban := &entity.Ban{
 		UserId: sql.NullInt64{
 			Int64: userId,
 			Valid: true,
 		},
-		Cause:     cause,
-		IsForever: true,
-		ExpiredAt: time.Now(),
+		Cause:          cause,
+		ReasonCategory: category,
 	}
-
-	if err := s.setBan(userId, &ban); err != nil {
-		s.log.Error("ошибка при блокировке пользователя: ", err)
-		return nil, err
+	if issuedBy > 0 {
+		ban.IssuedBy = &issuedBy
 	}
 
-	return &ban, nil
+	return ban
 }
 
 func (s *BanService) setBan(userId int64, ban *entity.Ban) error {
@@ -82,12 +134,36 @@ func (s *BanService) setBan(userId int64, ban *entity.Ban) error {
 		s.log.Error("ошибка при создании транзакции при блокировке пользователя: ", err)
 		return err
 	}
+	defer tx.Rollback()
 
-	if err := s.ts.logoutAll(ctx, tx, userId); err != nil {
+	revoked, err := s.ts.logoutAll(ctx, tx, userId)
+	if err != nil {
 		return err
 	}
 
+	expired, err := s.repo.LiftExpiredByUserIdTx(ctx, tx, userId)
+	if err != nil {
+		return err
+	}
+	for i := range expired {
+		if err := s.unbannedEventTx(ctx, tx, &expired[i]); err != nil {
+			return err
+		}
+	}
+
+	// открытая блокировка у пользователя может быть только одна, вторую не даст вставить уникальный индекс
 	if err := s.repo.SetBanTx(ctx, tx, ban); err != nil {
+		if errors.Is(err, ErrIsExists) {
+			return ErrUserIsAlreadyBlocked.Wrap(err)
+		}
+		return err
+	}
+
+	if err := s.bannedEventTx(ctx, tx, ban); err != nil {
+		return err
+	}
+
+	if err := s.ts.revokeAccessTokens(userId, revoked); err != nil {
 		return err
 	}
 
@@ -99,11 +175,220 @@ func (s *BanService) setBan(userId int64, ban *entity.Ban) error {
 	return nil
 }
 
-func (s *BanService) UnBanUser(userId int64) bool {
-	if err := s.repo.DeleteByUserId(userId); err != nil {
+func (s *BanService) bannedEventTx(ctx context.Context, tx *sqlx.Tx, ban *entity.Ban) error {
+	payload := events.UserBanned{
+		UserId:         ban.UserId.Int64,
+		BanId:          ban.Id.Int64,
+		ReasonCategory: ban.ReasonCategory,
+		IsForever:      ban.IsForever,
+		BannedAt:       ban.CreatedAt,
+		IssuedBy:       ban.IssuedBy,
+	}
+	if !ban.IsForever {
+		payload.ExpiredAt = &ban.ExpiredAt
+	}
+
+	return s.obs.AddTx(ctx, tx, payload.UserId, &payload)
+}
+
+// UnBanUser снимает активную блокировку. Запись остается в истории с указанием, кто и почему ее снял
+func (s *BanService) UnBanUser(userId, unbannedBy int64, reason string) (*entity.Ban, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 35*time.Second)
+	defer cancel()
+
+	tx, err := s.repo.CreateTx()
+	if err != nil {
+		s.log.Error("ошибка при создании транзакции при разблокировке пользователя: ", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ban, err := s.liftBanTx(ctx, tx, userId, unbannedBy, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CommitTx(tx); err != nil {
+		s.log.Error("ошибка при комите транзакции, при разблокировке пользователя: ", err)
+		return nil, err
+	}
+
+	return ban, nil
+}
+
+func (s *BanService) liftBanTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userId, unbannedBy int64,
+	reason string,
+) (*entity.Ban, error) {
+	var by *int64
+	if unbannedBy > 0 {
+		by = &unbannedBy
+	}
+
+	bans, err := s.repo.LiftActiveByUserIdTx(ctx, tx, userId, by, reason)
+	if err != nil {
 		s.log.Error("ошибка при разблокировке пользователя: ", err)
-		return false
+		return nil, err
+	}
+	if len(bans) == 0 {
+		return nil, ErrUserIsNotBlocked
+	}
+
+	for i := range bans {
+		if err := s.unbannedEventTx(ctx, tx, &bans[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &bans[0], nil
+}
+
+// LiftExpiredBans закрывает истекшие блокировки и в той же транзакции пишет по каждой событие UserUnbanned
+func (s *BanService) LiftExpiredBans() error {
+	for {
+		n, err := s.liftExpiredBatch()
+		if err != nil {
+			s.log.Error("ошибка при снятии истекших блокировок: ", err)
+			return err
+		}
+
+		if n < liftExpiredBansBatch {
+			return nil
+		}
+	}
+}
+
+func (s *BanService) liftExpiredBatch() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 35*time.Second)
+	defer cancel()
+
+	tx, err := s.repo.CreateTx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	bans, err := s.repo.LiftExpiredTx(ctx, tx, liftExpiredBansBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range bans {
+		if err := s.unbannedEventTx(ctx, tx, &bans[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.repo.CommitTx(tx); err != nil {
+		return 0, err
+	}
+
+	return len(bans), nil
+}
+
+func (s *BanService) unbannedEventTx(ctx context.Context, tx *sqlx.Tx, ban *entity.Ban) error {
+	payload := events.UserUnbanned{
+		UserId:     ban.UserId.Int64,
+		BanId:      ban.Id.Int64,
+		UnbannedBy: ban.UnbannedBy,
+	}
+	if ban.UnbannedAt != nil {
+		payload.UnbannedAt = *ban.UnbannedAt
+	}
+	if ban.UnbanReason != nil {
+		payload.Reason = *ban.UnbanReason
+	}
+
+	return s.obs.AddTx(ctx, tx, payload.UserId, &payload)
+}
+
+// CreateAppeal создает апелляцию на текущую блокировку пользователя. На одну блокировку - одна необработанная апелляция
+func (s *BanService) CreateAppeal(userId int64, message string) (*entity.BanAppeal, error) {
+	ban, ok := s.GetActiveUserBan(userId)
+	if !ok {
+		return nil, ErrUserIsNotBlocked
+	}
+
+	if _, err := s.appeals.FindPendingByBanId(ban.Id.Int64); err == nil {
+		return nil, ErrIsExists
+	}
+
+	appeal := entity.BanAppeal{
+		BanId:   ban.Id.Int64,
+		UserId:  userId,
+		Message: message,
+		Status:  entity.AppealStatusPending,
+	}
+
+	if err := s.appeals.Save(&appeal); err != nil {
+		s.log.Error("ошибка при сохранении апелляции: ", err)
+		return nil, err
+	}
+
+	return &appeal, nil
+}
+
+// GetAppeals апелляции с указанным статусом, старые сверху
+func (s *BanService) GetAppeals(status string) []entity.BanAppeal {
+	res, err := s.appeals.FindByStatus(status, banAppealsLimit)
+	if err != nil {
+		s.log.Error("ошибка при получении апелляций: ", err)
+		return make([]entity.BanAppeal, 0)
+	}
+
+	return res
+}
+
+// ReviewAppeal рассматривает апелляцию. При одобрении блокировка снимается в той же транзакции
+func (s *BanService) ReviewAppeal(appealId, reviewerId int64, approve bool, comment string) (*entity.BanAppeal, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 35*time.Second)
+	defer cancel()
+
+	appeal, err := s.appeals.FindById(appealId)
+	if err != nil {
+		return nil, err
+	}
+	if appeal.Status != entity.AppealStatusPending {
+		return nil, ErrAppealIsReviewed
+	}
+
+	appeal.Status = entity.AppealStatusRejected
+	if approve {
+		appeal.Status = entity.AppealStatusApproved
+	}
+	appeal.ReviewedBy = &reviewerId
+	if comment != "" {
+		appeal.ReviewComment = &comment
+	}
+
+	tx, err := s.appeals.CreateTx()
+	if err != nil {
+		s.log.Error("ошибка при создании транзакции при рассмотрении апелляции: ", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.appeals.ReviewTx(ctx, tx, appeal); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrAppealIsReviewed.Wrap(err)
+		}
+		s.log.Error("ошибка при рассмотрении апелляции: ", err)
+		return nil, err
+	}
+
+	if approve {
+		if _, err := s.liftBanTx(ctx, tx, appeal.UserId, reviewerId, "appeal approved: "+comment); err != nil &&
+			!errors.Is(err, ErrUserIsNotBlocked) {
+			return nil, err
+		}
+	}
+
+	if err := s.appeals.CommitTx(tx); err != nil {
+		s.log.Error("ошибка при комите транзакции, при рассмотрении апелляции: ", err)
+		return nil, err
 	}
 
-	return true
+	return appeal, nil
 }
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"time"
+)
+
+// ConsentRequiredError пользователь не принял текущие версии документов
+type ConsentRequiredError struct {
+	Documents []entity.ConsentDocument
+}
+
+func (e *ConsentRequiredError) Error() string {
+	return errormsg.ConsentRequired
+}
+
+func (e *ConsentRequiredError) DocumentIds() []int64 {
+	return documentIds(e.Documents)
+}
+
+type ConsentService struct {
+	log  *logrus.Entry
+	repo *repositories.ConsentRepository
+}
+
+func NewConsentService(log *logrus.Entry, repo *repositories.ConsentRepository) *ConsentService {
+	return &ConsentService{
+		log:  log,
+		repo: repo,
+	}
+}
+
+// Publish публикует новую версию документа. После публикации пользователи должны принять ее при следующем входе
+func (s *ConsentService) Publish(docType, version, url string, publishedBy int64) (*entity.ConsentDocument, error) {
+	if s.repo.ExistsDocument(docType, version) {
+		return nil, ErrIsExists
+	}
+
+	doc := &entity.ConsentDocument{
+		Type:    docType,
+		Version: version,
+		Url:     url,
+	}
+	if publishedBy > 0 {
+		doc.PublishedBy = &publishedBy
+	}
+
+	if err := s.repo.SaveDocument(doc); err != nil {
+		s.log.Error("ошибка при публикации документа: ", err)
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func (s *ConsentService) GetAllDocuments() []entity.ConsentDocument {
+	docs, err := s.repo.FindAllDocuments()
+	if err != nil {
+		s.log.Error("ошибка при получении документов: ", err)
+	}
+	if docs == nil {
+		docs = make([]entity.ConsentDocument, 0)
+	}
+
+	return docs
+}
+
+func (s *ConsentService) GetCurrentDocuments() ([]entity.ConsentDocument, error) {
+	docs, err := s.repo.FindCurrentDocuments()
+	if err != nil {
+		s.log.Error("ошибка при получении текущих версий документов: ", err)
+		return nil, err
+	}
+	if docs == nil {
+		docs = make([]entity.ConsentDocument, 0)
+	}
+
+	return docs, nil
+}
+
+// AcceptCurrentTx записывает согласия при регистрации. Должны быть приняты все текущие версии документов
+func (s *ConsentService) AcceptCurrentTx(ctx context.Context, tx *sqlx.Tx, userId int64, accepted []int64) error {
+	docs, err := s.GetCurrentDocuments()
+	if err != nil {
+		return err
+	}
+
+	if err := consentRequired(docs, accepted); err != nil {
+		return err
+	}
+
+	if err := s.repo.SaveUserConsentsTx(ctx, tx, userId, documentIds(docs)); err != nil {
+		s.log.Error("ошибка при сохранении согласий: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// AcceptPending проверяет согласия при входе. Принятые в запросе документы сохраняются,
+// если остались непринятые - возвращается ConsentRequiredError
+func (s *ConsentService) AcceptPending(userId int64, accepted []int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pending, err := s.repo.FindPendingDocuments(userId)
+	if err != nil {
+		s.log.Error("ошибка при получении непринятых документов: ", err)
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err := consentRequired(pending, accepted); err != nil {
+		return err
+	}
+
+	tx, err := s.repo.CreateTx()
+	if err != nil {
+		s.log.Error("ошибка при создании транзакции при сохранении согласий: ", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.repo.SaveUserConsentsTx(ctx, tx, userId, documentIds(pending)); err != nil {
+		s.log.Error("ошибка при сохранении согласий: ", err)
+		return err
+	}
+
+	if err := s.repo.CommitTx(tx); err != nil {
+		s.log.Error("ошибка при комите транзакции, при сохранении согласий: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// RequireAccepted проверяет согласия при обновлении токенов. Принять документы можно только при входе,
+// поэтому непринятая новая версия или отозванное согласие возвращают ConsentRequiredError
+func (s *ConsentService) RequireAccepted(userId int64) error {
+	pending, err := s.repo.FindPendingDocuments(userId)
+	if err != nil {
+		s.log.Error("ошибка при получении непринятых документов: ", err)
+		return err
+	}
+
+	return consentRequired(pending, nil)
+}
+
+func (s *ConsentService) GetUserConsents(userId int64) []entity.UserConsent {
+	consents, err := s.repo.FindByUserId(userId)
+	if err != nil {
+		s.log.Error("ошибка при получении согласий пользователя: ", err)
+	}
+	if consents == nil {
+		consents = make([]entity.UserConsent, 0)
+	}
+
+	return consents
+}
+
+// consentRequired ConsentRequiredError, если среди docs есть документы, которых нет в accepted
+func consentRequired(docs []entity.ConsentDocument, accepted []int64) error {
+	if missing := notAccepted(docs, accepted); len(missing) > 0 {
+		return &ConsentRequiredError{Documents: missing}
+	}
+	return nil
+}
+
+func notAccepted(docs []entity.ConsentDocument, accepted []int64) []entity.ConsentDocument {
+	var res []entity.ConsentDocument
+	for _, doc := range docs {
+		if !slices.Contains(accepted, doc.Id) {
+			res = append(res, doc)
+		}
+	}
+	return res
+}
+
+func documentIds(docs []entity.ConsentDocument) []int64 {
+	ids := make([]int64, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.Id)
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+)
+
+func TestConsentRequired(t *testing.T) {
+	docs := []entity.ConsentDocument{
+		{Id: 1, Type: "terms", Version: "2"},
+		{Id: 2, Type: "privacy", Version: "3"},
+	}
+
+	cases := []struct {
+		name     string
+		docs     []entity.ConsentDocument
+		accepted []int64
+		missing  []int64
+	}{
+		{"все приняты", docs, []int64{1, 2}, nil},
+		{"нет документов", nil, nil, nil},
+		{"принят один", docs, []int64{2}, []int64{1}},
+		// так проверяется обновление токенов: принять документы в нем нельзя
+		{"ничего не передано", docs, nil, []int64{1, 2}},
+		{"принята старая версия", docs, []int64{7}, []int64{1, 2}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := consentRequired(tc.docs, tc.accepted)
+			if tc.missing == nil {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+
+			var consentErr *ConsentRequiredError
+			if !errors.As(err, &consentErr) {
+				t.Fatalf("err = %v, want ConsentRequiredError", err)
+			}
+			if !slices.Equal(consentErr.DocumentIds(), tc.missing) {
+				t.Fatalf("DocumentIds() = %v, want %v", consentErr.DocumentIds(), tc.missing)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"errors"
 	"github.com/EddyZe/foodApp/authservice/internal/config"
@@ -13,6 +14,16 @@ import (
 	"time"
 )
 
+// RetryLaterError сработал лимит на выдачу или ввод кода. Code - код ошибки из errormsg
+type RetryLaterError struct {
+	Code       string
+	RetryAfter time.Duration
+}
+
+func (e *RetryLaterError) Error() string {
+	return e.Code
+}
+
 type EmailVerificationService struct {
 	log  *logrus.Entry
 	cfg  *config.EmailVerificationCfg
@@ -37,11 +48,11 @@ func NewEmailVerificationCodeService(
 func (s *EmailVerificationService) Save(code *entity2.EmailVerificationCode) error {
 	c := code.Code
 	if c, _ := s.evr.FindByCode(c); c != nil {
-		return errors.New(errormsg.IsExists)
+		return ErrIsExists
 	} else {
 		if err := s.evr.Save(code); err != nil {
 			s.log.Error(err)
-			return errors.New(errormsg.ServerInternalError)
+			return err
 		}
 	}
 	return nil
@@ -55,7 +66,7 @@ func (s *EmailVerificationService) GetByCode(code string) (*entity2.EmailVerific
 	res, err := s.evr.FindByCode(code)
 	if err != nil {
 		s.log.Error(err)
-		return nil, errors.New(errormsg.NotFound)
+		return nil, err
 	}
 
 	return res, nil
@@ -65,14 +76,32 @@ func (s *EmailVerificationService) GenerateRandomCode(length int) string {
 	return codegen.GenerateRandomCode(length)
 }
 
+// GenerateAndSaveCode выдает новый код с учетом интервала между отправками и лимита кодов.
+// Лимиты проверяются в той же транзакции под блокировкой пользователя, поэтому одновременные запросы
+// не выдадут коды сверх лимита. Прежние коды пользователя аннулируются
 func (s *EmailVerificationService) GenerateAndSaveCode(userId int64, lengthCode int) (*entity2.EmailVerificationCode, error) {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	tx, err := s.evr.CreateTx()
 	if err != nil {
 		s.log.Error("Ошибка открытия транзакции при генерации кода для подтверждения email: ", err)
 		return nil, err
 	}
+	defer tx.Rollback()
+
+	stats, err := s.evr.FindIssueStatsForUpdateTx(ctx, tx, userId, s.cfg.CodeExpiredMinute, s.cfg.ResendCooldownSeconds)
+	if err != nil {
+		s.log.Error("ошибка при проверке лимитов выдачи кода: ", err)
+		return nil, err
+	}
+	if err := issueLimitError(s.cfg, stats); err != nil {
+		return nil, err
+	}
+
+	if err := s.evr.InvalidateByUserIdTx(ctx, tx, userId); err != nil {
+		s.log.Error("ошибка при аннулировании прежних кодов: ", err)
+		return nil, err
+	}
 
 	var code string
 	var codeEntity entity2.EmailVerificationCode
@@ -92,9 +121,10 @@ func (s *EmailVerificationService) GenerateAndSaveCode(userId int64, lengthCode
 			ExpiredAt: ex,
 		}
 
+		// после ошибки postgres отклоняет все запросы транзакции, поэтому повторять попытку бессмысленно
 		if err := s.evr.SaveTx(ctx, tx, &codeEntity); err != nil {
 			s.log.Error("Ошибка при сохранении кода для подтверждения почты: ", err)
-			continue
+			return nil, err
 		}
 
 		break
@@ -102,12 +132,85 @@ func (s *EmailVerificationService) GenerateAndSaveCode(userId int64, lengthCode
 
 	if err := s.evr.CommitTx(tx); err != nil {
 		s.log.Error("ошибка при комите транзакции при генерации и сохранени email code: ", err)
-		return nil, errors.New(errormsg.ServerInternalError)
+		return nil, err
 	}
 
 	return &codeEntity, nil
 }
 
+func (s *EmailVerificationService) checkIssueLimits(userId int64) error {
+	stats, err := s.evr.FindIssueStats(userId, s.cfg.CodeExpiredMinute, s.cfg.ResendCooldownSeconds)
+	if err != nil {
+		s.log.Error("ошибка при проверке лимитов выдачи кода: ", err)
+		return err
+	}
+
+	return issueLimitError(s.cfg, stats)
+}
+
+// issueLimitError RetryLaterError, если не прошел интервал между отправками или выдано MaxActiveCodes кодов за окно
+func issueLimitError(cfg *config.EmailVerificationCfg, stats *repositories.CodeIssueStats) error {
+	if stats.CooldownLeft > 0 {
+		return &RetryLaterError{
+			Code:       errormsg.CodeResendCooldown,
+			RetryAfter: time.Duration(stats.CooldownLeft) * time.Second,
+		}
+	}
+
+	if cfg.MaxActiveCodes > 0 && stats.Issued >= cfg.MaxActiveCodes {
+		return &RetryLaterError{
+			Code:       errormsg.CodeLimitExceeded,
+			RetryAfter: time.Duration(stats.WindowLeft) * time.Second,
+		}
+	}
+
+	return nil
+}
+
+// resendAfter через сколько пользователь сможет запросить новый код
+func (s *EmailVerificationService) resendAfter(userId int64) time.Duration {
+	if err := s.checkIssueLimits(userId); err != nil {
+		var retryErr *RetryLaterError
+		if errors.As(err, &retryErr) {
+			return retryErr.RetryAfter
+		}
+	}
+	return 0
+}
+
+// VerifyCode проверяет код, введенный пользователем. Неверные попытки учитываются по последнему выданному коду,
+// после MaxAttempts код аннулируется
+func (s *EmailVerificationService) VerifyCode(userId int64, codeString string) (*entity2.EmailVerificationCode, error) {
+	code, err := s.evr.FindActiveByUserId(userId)
+	if err != nil {
+		s.log.Debug("действующий код не найден: ", err)
+		return nil, ErrInvalidEmailCode
+	}
+
+	if code.IsExpired() {
+		return nil, ErrCodeExpired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(code.Code), []byte(codeString)) == 1 {
+		return code, nil
+	}
+
+	attempts, err := s.evr.AddFailedAttempt(code.Id.Int64, s.cfg.MaxAttempts)
+	if err != nil {
+		s.log.Error("ошибка при учете неверной попытки ввода кода: ", err)
+		return nil, err
+	}
+	if attempts >= s.cfg.MaxAttempts {
+		s.log.Debug("код аннулирован после неверных попыток, пользователь: ", userId)
+		return nil, &RetryLaterError{
+			Code:       errormsg.CodeAttemptsExceeded,
+			RetryAfter: s.resendAfter(userId),
+		}
+	}
+
+	return nil, ErrInvalidEmailCode
+}
+
 func (s *EmailVerificationService) GetByEmailVerifToken(token string) (*entity2.EmailVerificationCode, bool) {
 	code, err := s.evr.FindCodeByVerifiedToken(token)
 	if err != nil {
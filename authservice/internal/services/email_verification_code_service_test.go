@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+)
+
+func TestIssueLimitError(t *testing.T) {
+	cfg := &config.EmailVerificationCfg{MaxActiveCodes: 3}
+
+	cases := []struct {
+		name      string
+		cfg       *config.EmailVerificationCfg
+		stats     repositories.CodeIssueStats
+		wantCode  string
+		wantRetry time.Duration
+	}{
+		{"первый код", cfg, repositories.CodeIssueStats{}, "", 0},
+		{"ниже лимита", cfg, repositories.CodeIssueStats{Issued: 2, WindowLeft: 300}, "", 0},
+		{"интервал не прошел", cfg, repositories.CodeIssueStats{Issued: 1, CooldownLeft: 40, WindowLeft: 500}, errormsg.CodeResendCooldown, 40 * time.Second},
+		{"лимит за окно", cfg, repositories.CodeIssueStats{Issued: 3, WindowLeft: 120}, errormsg.CodeLimitExceeded, 120 * time.Second},
+		{"лимит выключен", &config.EmailVerificationCfg{}, repositories.CodeIssueStats{Issued: 100}, "", 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := issueLimitError(tc.cfg, &tc.stats)
+			if tc.wantCode == "" {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+
+			var retryErr *RetryLaterError
+			if !errors.As(err, &retryErr) {
+				t.Fatalf("err = %v, want RetryLaterError", err)
+			}
+			if retryErr.Code != tc.wantCode || retryErr.RetryAfter != tc.wantRetry {
+				t.Fatalf("got %s через %v, want %s через %v", retryErr.Code, retryErr.RetryAfter, tc.wantCode, tc.wantRetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/common/pkg/apperr"
+)
+
+// Ошибки сервисов. ErrNotFound и ErrIsExists - общие виды apperr, их же возвращают репозитории,
+// остальные - ошибки с кодом ответа. Обработчики сравнивают их через errors.Is
+var (
+	ErrNotFound               = apperr.ErrNotFound
+	ErrIsExists               = apperr.ErrConflict
+	ErrInvalidParam           = apperr.New(apperr.ErrInvalid, errormsg.InvalidParam)
+	ErrLastPasswordIsExists   = apperr.New(apperr.ErrInvalid, errormsg.LastPasswordIsExists)
+	ErrAppealIsReviewed       = apperr.New(apperr.ErrConflict, errormsg.AppealIsReviewed)
+	ErrUserIsNotBlocked       = apperr.New(apperr.ErrInvalid, errormsg.UserIsNotBlocked)
+	ErrUserIsAlreadyBlocked   = apperr.New(apperr.ErrConflict, errormsg.UserIsAlreadyBlocked)
+	ErrRoleIsProtected        = apperr.New(apperr.ErrForbidden, errormsg.RoleIsProtected)
+	ErrOwnAdminRole           = apperr.New(apperr.ErrForbidden, errormsg.OwnAdminRole)
+	ErrLastAdmin              = apperr.New(apperr.ErrConflict, errormsg.LastAdmin)
+	ErrRegistrationRestricted = apperr.New(apperr.ErrForbidden, errormsg.RegistrationRestricted)
+	ErrInvalidInviteCode      = apperr.New(apperr.ErrForbidden, errormsg.InvalidInviteCode)
+	ErrInviteRoleNotAllowed   = apperr.New(apperr.ErrForbidden, errormsg.InviteRoleNotAllowed)
+	ErrInvalidEmailCode       = apperr.New(apperr.ErrInvalid, errormsg.InvalidEmailCode)
+	ErrInvalidResetCode       = apperr.New(apperr.ErrInvalid, errormsg.InvalidResetCode)
+	ErrCodeExpired            = apperr.New(apperr.ErrInvalid, errormsg.CodeExpired)
+	ErrCodeAttemptsExceeded   = apperr.New(apperr.ErrForbidden, errormsg.CodeAttemptsExceeded)
+	ErrEmailSuppressed        = apperr.New(apperr.ErrInvalid, errormsg.EmailUndeliverable)
+)
+
+// InvalidParamError некорректное значение параметра Param. Для errors.Is это ErrInvalidParam,
+// имя параметра попадает в сообщение об ошибке
+type InvalidParamError struct {
+	Param string
+}
+
+func invalidParam(param string) error {
+	return &InvalidParamError{Param: param}
+}
+
+func (e *InvalidParamError) Error() string {
+	return errormsg.InvalidParam + ": " + e.Param
+}
+
+func (e *InvalidParamError) Unwrap() error {
+	return ErrInvalidParam
+}
+
+func (e *InvalidParamError) TemplateData() map[string]interface{} {
+	return map[string]interface{}{"param": e.Param}
+}
@@ -1,8 +1,11 @@
 package services
 
 import (
+	"context"
+
 	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
 	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,6 +31,13 @@ func (s *HistoryPasswordService) Save(userId int64, oldPassword string) error {
 	return s.repo.Save(&oldPass)
 }
 
+func (s *HistoryPasswordService) SaveTx(ctx context.Context, tx *sqlx.Tx, userId int64, oldPassword string) error {
+	return s.repo.SaveTx(ctx, tx, &entity.PasswordHistory{
+		UserId:      userId,
+		OldPassword: oldPassword,
+	})
+}
+
 func (s *HistoryPasswordService) GetLastPasswords(userId int64, limit int) []entity.PasswordHistory {
 	s.log.Debug("Получение последних паролей пользователя с ID: ", userId)
 	return s.repo.GetLastPasswords(userId, limit)
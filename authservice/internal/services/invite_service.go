@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+	"github.com/EddyZe/foodApp/common/pkg/roles"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+	"time"
+)
+
+const inviteCodeListLimit = 500
+
+type InviteService struct {
+	log  *logrus.Entry
+	repo *repositories.InviteCodeRepository
+	rs   *RoleService
+}
+
+func NewInviteService(log *logrus.Entry, repo *repositories.InviteCodeRepository, rs *RoleService) *InviteService {
+	return &InviteService{
+		log:  log,
+		repo: repo,
+		rs:   rs,
+	}
+}
+
+// Create создает инвайт-код. Выдать можно только роли, которые есть у создателя, и никогда роль администратора
+func (s *InviteService) Create(req *dto.CreateInviteCode, createdBy int64) (*dto.InviteCodeDto, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if len(req.Roles) > 0 && !inviteRolesAllowed(req.Roles, s.rs.GetRoleByUserId(createdBy)) {
+		return nil, ErrInviteRoleNotAllowed
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		s.log.Error("ошибка при генерации инвайт-кода: ", err)
+		return nil, err
+	}
+
+	invite := &entity.InviteCode{
+		Code:    code,
+		MaxUses: req.MaxUses,
+	}
+	if createdBy > 0 {
+		invite.CreatedBy = &createdBy
+	}
+	if req.ExpiredAt != nil {
+		if !req.ExpiredAt.After(time.Now()) {
+			return nil, invalidParam("expired_at")
+		}
+		// колонка хранится без часового пояса, как и остальные сроки, записываемые сервисом
+		expiredAt := req.ExpiredAt.Local()
+		invite.ExpiredAt = &expiredAt
+	}
+
+	tx, err := s.repo.CreateTx()
+	if err != nil {
+		s.log.Error("ошибка при создании транзакции при создании инвайт-кода: ", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.repo.SaveTx(ctx, tx, invite); err != nil {
+		s.log.Error("ошибка при сохранении инвайт-кода: ", err)
+		return nil, err
+	}
+
+	roleNames := make([]string, 0, len(req.Roles))
+	for _, name := range req.Roles {
+		role, err := s.rs.FindByNameTx(ctx, tx, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.SaveRoleTx(ctx, tx, invite.Id, role.Id.Int64); err != nil {
+			s.log.Error("ошибка при сохранении ролей инвайт-кода: ", err)
+			return nil, err
+		}
+		if !slices.Contains(roleNames, role.Name) {
+			roleNames = append(roleNames, role.Name)
+		}
+	}
+
+	if err := s.repo.CommitTx(tx); err != nil {
+		s.log.Error("ошибка при комите транзакции, при создании инвайт-кода: ", err)
+		return nil, err
+	}
+
+	slices.Sort(roleNames)
+	return toInviteCodeDto(invite, roleNames), nil
+}
+
+func (s *InviteService) GetAll() []dto.InviteCodeDto {
+	invites, err := s.repo.FindAll(inviteCodeListLimit)
+	if err != nil {
+		s.log.Error("ошибка при получении инвайт-кодов: ", err)
+	}
+
+	res := make([]dto.InviteCodeDto, 0, len(invites))
+	for i := range invites {
+		roleNames := make([]string, 0)
+		if invites[i].Roles != "" {
+			roleNames = strings.Split(invites[i].Roles, ",")
+		}
+		res = append(res, *toInviteCodeDto(&invites[i], roleNames))
+	}
+
+	return res
+}
+
+func (s *InviteService) GetUsages(inviteId int64) []entity.InviteCodeUsage {
+	usages, err := s.repo.FindUsages(inviteId)
+	if err != nil {
+		s.log.Error("ошибка при получении использований инвайт-кода: ", err)
+	}
+	if usages == nil {
+		usages = make([]entity.InviteCodeUsage, 0)
+	}
+
+	return usages
+}
+
+// Revoke отзывает инвайт-код, зарегистрированные по нему пользователи сохраняют роли
+func (s *InviteService) Revoke(id int64) (*entity.InviteCode, error) {
+	invite, err := s.repo.Revoke(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		s.log.Error("ошибка при отзыве инвайт-кода: ", err)
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// RedeemTx использует код в транзакции регистрации и возвращает id ролей, которые нужно выдать пользователю
+func (s *InviteService) RedeemTx(ctx context.Context, tx *sqlx.Tx, code string, userId int64) ([]int64, error) {
+	invite, err := s.repo.FindByCodeForUpdateTx(ctx, tx, normalizeInviteCode(code))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidInviteCode.Wrap(err)
+		}
+		s.log.Error("ошибка при поиске инвайт-кода: ", err)
+		return nil, err
+	}
+
+	if !invite.IsUsable(time.Now()) {
+		s.log.Debug("инвайт-код недействителен: ", invite.Id)
+		return nil, ErrInvalidInviteCode
+	}
+
+	if err := s.repo.UseTx(ctx, tx, invite.Id, userId); err != nil {
+		s.log.Error("ошибка при использовании инвайт-кода: ", err)
+		return nil, err
+	}
+
+	roleIds, err := s.repo.FindRoleIdsTx(ctx, tx, invite.Id)
+	if err != nil {
+		s.log.Error("ошибка при получении ролей инвайт-кода: ", err)
+		return nil, err
+	}
+
+	return roleIds, nil
+}
+
+func toInviteCodeDto(invite *entity.InviteCode, roleNames []string) *dto.InviteCodeDto {
+	return &dto.InviteCodeDto{
+		Id:        invite.Id,
+		Code:      invite.Code,
+		MaxUses:   invite.MaxUses,
+		UsedCount: invite.UsedCount,
+		ExpiredAt: invite.ExpiredAt,
+		CreatedBy: invite.CreatedBy,
+		CreatedAt: invite.CreatedAt,
+		RevokedAt: invite.RevokedAt,
+		Roles:     roleNames,
+	}
+}
+
+func generateInviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+func normalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// inviteRolesAllowed каждая роль приглашения должна быть у создателя. Администратора через приглашение не выдать
+func inviteRolesAllowed(requested []string, inviterRoles []entity.Role) bool {
+	for _, name := range requested {
+		if strings.EqualFold(name, roles.Admin) {
+			return false
+		}
+		if !slices.ContainsFunc(inviterRoles, func(r entity.Role) bool {
+			return strings.EqualFold(r.Name, name)
+		}) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+)
+
+func TestInviteRolesAllowed(t *testing.T) {
+	moderator := []entity.Role{{Name: "user"}, {Name: "moderator"}}
+	admin := []entity.Role{{Name: "user"}, {Name: "admin"}}
+
+	cases := []struct {
+		name      string
+		requested []string
+		inviter   []entity.Role
+		want      bool
+	}{
+		{"своя роль", []string{"moderator"}, moderator, true},
+		{"регистр не важен", []string{"Moderator"}, moderator, true},
+		{"чужая роль", []string{"support"}, moderator, false},
+		{"администратор даже у администратора", []string{"admin"}, admin, false},
+		{"роли создателя неизвестны", []string{"user"}, nil, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := inviteRolesAllowed(tc.requested, tc.inviter); got != tc.want {
+				t.Fatalf("inviteRolesAllowed(%v) = %v, want %v", tc.requested, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRegistrationAllowed(t *testing.T) {
+	cases := []struct {
+		name string
+		mode string
+		req  dto.RegisterDto
+		want bool
+	}{
+		{"open", config.RegistrationModeOpen, dto.RegisterDto{Email: "a@mail.ru"}, true},
+		{"invite без кода", config.RegistrationModeInvite, dto.RegisterDto{Email: "a@mail.ru"}, false},
+		{"invite с кодом", config.RegistrationModeInvite, dto.RegisterDto{Email: "a@mail.ru", InviteCode: "X"}, true},
+		{"domain свой домен", config.RegistrationModeDomain, dto.RegisterDto{Email: "a@Corp.ru"}, true},
+		{"domain чужой домен", config.RegistrationModeDomain, dto.RegisterDto{Email: "a@mail.ru"}, false},
+		{"неизвестный режим", "closed", dto.RegisterDto{Email: "a@mail.ru"}, false},
+		{"пустой режим", "", dto.RegisterDto{Email: "a@mail.ru"}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &UserService{reg: &config.RegistrationConfig{Mode: tc.mode, AllowedDomains: []string{"corp.ru"}}}
+			if got := s.registrationAllowed(&tc.req); got != tc.want {
+				t.Fatalf("registrationAllowed = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,58 +1,333 @@
 package services
 
 import (
-	"fmt"
+	"errors"
 	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/mailer"
+	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
-	"net/smtp"
+	"net/http"
+	"sync"
+	"time"
 )
 
+const defaultMailListLimit = 50
+
+var (
+	mailQueuedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mail_queue_enqueued_total",
+			Help: "Total messages added to the mail queue",
+		},
+		[]string{"template"},
+	)
+
+	mailSentTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mail_queue_sent_total",
+			Help: "Total messages delivered to the SMTP server",
+		},
+		[]string{"template"},
+	)
+
+	mailFailedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mail_queue_failed_total",
+			Help: "Total failed delivery attempts",
+		},
+		[]string{"template"},
+	)
+
+	mailDeadTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mail_queue_dead_total",
+			Help: "Total messages moved to dead letter after the last attempt",
+		},
+		[]string{"template"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(mailQueuedTotal, mailSentTotal, mailFailedTotal, mailDeadTotal)
+}
+
 type MailService struct {
-	log  *logrus.Entry
-	From string
-	Host string
-	Port string
-	Auth smtp.Auth
+	log      *logrus.Entry
+	From     string
+	mailer   mailer.Mailer
+	queueCfg *config.MailQueueConfig
+	renderer *mailer.Renderer
+	repo     *repositories.MailQueueRepository
+	sps      *MailSuppressionService
+}
+
+func NewMailService(
+	log *logrus.Entry,
+	from string,
+	sender mailer.Mailer,
+	queueCfg *config.MailQueueConfig,
+	renderer *mailer.Renderer,
+	repo *repositories.MailQueueRepository,
+	sps *MailSuppressionService,
+) *MailService {
+	return &MailService{
+		log:      log,
+		From:     from,
+		mailer:   sender,
+		queueCfg: queueCfg,
+		renderer: renderer,
+		repo:     repo,
+		sps:      sps,
+	}
 }
 
-func NewMailService(log *logrus.Entry, cfg *config.SmptConfig) *MailService {
-	var auth smtp.Auth
+// SendMail отправляет письмо сразу, минуя очередь
+func (s *MailService) SendMail(from string, msg *mailer.Message, to ...string) error {
+	sender := mailer.ParseAddress(from)
 
-	if cfg.Password == "" || cfg.Username == "" {
-		auth = nil
-	} else {
-		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	body, err := mailer.Build(sender, to, msg, time.Now())
+	if err != nil {
+		s.log.Error("ошибка при сборке письма: ", err)
+		return err
 	}
 
-	return &MailService{
-		log:  log,
-		Host: cfg.Host,
-		Port: cfg.Port,
-		Auth: auth,
-		From: cfg.From,
-	}
-}
-
-func (s *MailService) SendMail(from, subject, body string, to ...string) error {
-	headers := ""
-	headers += fmt.Sprintf("Subject: %s\r\n", subject)
-	headers += "MIME-Version: 1.0\r\n"
-	headers += "Content-Type: text/html; charset=\"UTF-8\"\r\n"
-	headers += "\r\n"
-	msg := headers + body
-	if err := smtp.SendMail(
-		fmt.Sprintf("%s:%s", s.Host, s.Port),
-		s.Auth,
-		from,
-		to,
-		[]byte(msg),
-	); err != nil {
+	if err := s.mailer.Send(sender.Address, to, body); err != nil {
 		s.log.Error("SMTP Send Mail Error ошибка при отпавке email:", err)
 		return err
 	}
 	return nil
 }
 
-func (s *MailService) SendMailFromApp(subject, body string, to ...string) error {
-	return s.SendMail(s.From, subject, body, to...)
+func (s *MailService) SendMailFromApp(msg *mailer.Message, to ...string) error {
+	return s.SendMail(s.From, msg, to...)
+}
+
+// Inspector просмотр отправленных писем, если используется транспорт memory. Иначе nil
+func (s *MailService) Inspector() http.Handler {
+	m := s.mailer
+	if wrapped, ok := m.(interface{ Unwrap() mailer.Mailer }); ok {
+		m = wrapped.Unwrap()
+	}
+	if memory, ok := m.(*mailer.MemoryMailer); ok {
+		return memory.Handler()
+	}
+
+	return nil
+}
+
+// Enqueue рендерит письмо name на языке lang и ставит его в очередь от имени приложения.
+// Доставкой занимается ProcessQueue, поэтому недоступность SMTP не влияет на запрос.
+// Недоставляемые адреса отбрасываются, если не осталось ни одного - ErrEmailSuppressed
+func (s *MailService) Enqueue(name, lang string, data map[string]interface{}, to ...string) (*entity.MailMessage, error) {
+	to = s.deliverable(to)
+	if len(to) == 0 {
+		s.log.Infof("письмо %s не поставлено в очередь: все адреса в списке недоставляемых", name)
+		return nil, ErrEmailSuppressed
+	}
+
+	msg, err := s.renderer.Render(name, lang, data)
+	if err != nil {
+		s.log.Errorf("ошибка при рендеринге письма %s: %v", name, err)
+		return nil, err
+	}
+
+	queued := entity.MailMessage{
+		Template:   name,
+		Sender:     s.From,
+		Recipients: to,
+		Subject:    msg.Subject,
+		HtmlBody:   msg.HTML,
+		TextBody:   msg.Text,
+	}
+	if err := s.repo.Save(&queued); err != nil {
+		s.log.Error("ошибка при сохранении письма в очередь: ", err)
+		return nil, err
+	}
+
+	mailQueuedTotal.WithLabelValues(name).Inc()
+	return &queued, nil
+}
+
+// ProcessQueue забирает пачку писем и доставляет их в queueCfg.Workers потоков.
+// Неудачная попытка откладывает письмо с экспоненциальной задержкой, после MaxAttempts письмо уходит в dead.
+// Письмо, отправка которого уже не успеет закончиться до истечения lease, возвращается в очередь неотправленным
+func (s *MailService) ProcessQueue() error {
+	lease := time.Duration(s.queueCfg.LeaseSeconds) * time.Second
+	// отправку нужно начать не позже, чем за SendTimeout до истечения lease
+	sendBefore := time.Now().Add(lease - time.Duration(s.queueCfg.SendTimeoutSeconds)*time.Second)
+	msgs, err := s.repo.Claim(s.queueCfg.BatchSize, lease)
+	if err != nil {
+		s.log.Error("ошибка при получении писем из очереди: ", err)
+		return err
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	jobs := make(chan *entity.MailMessage)
+	var wg sync.WaitGroup
+	for i := 0; i < max(s.queueCfg.Workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range jobs {
+				if time.Now().After(sendBefore) {
+					s.release(msg)
+					continue
+				}
+				s.deliver(msg)
+			}
+		}()
+	}
+
+	for i := range msgs {
+		jobs <- &msgs[i]
+	}
+	close(jobs)
+	wg.Wait()
+
+	return nil
+}
+
+func (s *MailService) deliver(msg *entity.MailMessage) {
+	// адрес мог попасть в список недоставляемых, пока письмо ждало в очереди
+	to := s.deliverable(msg.Recipients)
+	if len(to) == 0 {
+		mailDeadTotal.WithLabelValues(msg.Template).Inc()
+		if err := s.repo.MarkDead(msg.Id, "все адреса в списке недоставляемых"); err != nil {
+			s.log.Error("ошибка при переводе письма в dead: ", err)
+		}
+		return
+	}
+
+	err := s.SendMail(msg.Sender, &mailer.Message{
+		Subject: msg.Subject,
+		HTML:    msg.HtmlBody,
+		Text:    msg.TextBody,
+	}, to...)
+	if err == nil {
+		mailSentTotal.WithLabelValues(msg.Template).Inc()
+		if err := s.repo.MarkSent(msg.Id); err != nil {
+			s.log.Errorf("письмо %d отправлено, но статус не сохранен: %v", msg.Id, err)
+		}
+		return
+	}
+
+	mailFailedTotal.WithLabelValues(msg.Template).Inc()
+
+	if msg.Attempts >= s.queueCfg.MaxAttempts {
+		s.log.Errorf("письмо %d (%s) не доставлено за %d попыток: %v", msg.Id, msg.Template, msg.Attempts, err)
+		mailDeadTotal.WithLabelValues(msg.Template).Inc()
+		if err := s.repo.MarkDead(msg.Id, err.Error()); err != nil {
+			s.log.Error("ошибка при переводе письма в dead: ", err)
+		}
+		return
+	}
+
+	next := time.Now().Add(s.backoff(msg.Attempts))
+	if err := s.repo.MarkFailed(msg.Id, err.Error(), next); err != nil {
+		s.log.Error("ошибка при сохранении неудачной попытки отправки письма: ", err)
+	}
+}
+
+// deliverable адреса, которые не в списке недоставляемых
+func (s *MailService) deliverable(to []string) []string {
+	if s.sps == nil {
+		return to
+	}
+
+	return withoutSuppressed(to, s.sps.IsSuppressed)
+}
+
+func withoutSuppressed(to []string, isSuppressed func(email string) bool) []string {
+	res := make([]string, 0, len(to))
+	for _, email := range to {
+		if !isSuppressed(email) {
+			res = append(res, email)
+		}
+	}
+
+	return res
+}
+
+func (s *MailService) release(msg *entity.MailMessage) {
+	if err := s.repo.Release(msg.Id); err != nil {
+		s.log.Errorf("ошибка при возврате письма %d в очередь: %v", msg.Id, err)
+	}
+}
+
+// backoff экспоненциальная задержка перед повтором: 2с, 4с, 8с ... но не больше MaxBackoffSeconds
+func (s *MailService) backoff(attempts int) time.Duration {
+	maxBackoff := time.Duration(s.queueCfg.MaxBackoffSeconds) * time.Second
+	if attempts > 30 {
+		return maxBackoff
+	}
+
+	delay := time.Second << attempts
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+
+	return delay
+}
+
+func (s *MailService) GetById(id int64) (*entity.MailMessage, error) {
+	msg, err := s.repo.FindById(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		s.log.Error("ошибка при получении письма: ", err)
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (s *MailService) GetAll(status string, limit int) []entity.MailMessage {
+	if limit <= 0 {
+		limit = defaultMailListLimit
+	}
+
+	msgs, err := s.repo.FindAll(status, limit)
+	if err != nil {
+		s.log.Error("ошибка при получении писем: ", err)
+		return make([]entity.MailMessage, 0)
+	}
+	if msgs == nil {
+		return make([]entity.MailMessage, 0)
+	}
+
+	return msgs
+}
+
+// Retry возвращает письмо из dead в очередь
+func (s *MailService) Retry(id int64) (*entity.MailMessage, error) {
+	msg, err := s.repo.Requeue(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		s.log.Error("ошибка при повторной постановке письма в очередь: ", err)
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// CleanQueue удаляет доставленные письма старше RetentionHours и недоставленные старше DeadRetentionHours
+func (s *MailService) CleanQueue() error {
+	now := time.Now()
+	if err := s.repo.DeleteSentBefore(now.Add(-time.Duration(s.queueCfg.RetentionHours) * time.Hour)); err != nil {
+		s.log.Error("ошибка при очистке доставленных писем: ", err)
+		return err
+	}
+	if err := s.repo.DeleteDeadBefore(now.Add(-time.Duration(s.queueCfg.DeadRetentionHours) * time.Hour)); err != nil {
+		s.log.Error("ошибка при очистке недоставленных писем: ", err)
+		return err
+	}
+
+	return nil
 }
@@ -2,7 +2,9 @@ package services
 
 import (
 	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/EddyZe/foodApp/authservice/internal/mailer"
 	"github.com/sirupsen/logrus"
+	"strings"
 	"testing"
 )
 
@@ -12,11 +14,44 @@ func TestMailService(t *testing.T) {
 		Port: "1025",
 	}
 
-	mailServ := NewMailService(logrus.NewEntry(logrus.New()), &cfg)
+	sender := mailer.NewSMTPMailer(mailer.SMTPOptions{
+		Host: cfg.Host,
+		Port: cfg.Port,
+	})
+	mailServ := NewMailService(logrus.NewEntry(logrus.New()), cfg.From, sender, nil, nil, nil, nil)
 
-	err := mailServ.SendMail("test2@mail", "test", "test bodty", "test@mail")
+	err := mailServ.SendMail("test2@mail", &mailer.Message{
+		Subject: "test",
+		HTML:    "<p>test bodty</p>",
+		Text:    "test bodty",
+	}, "test@mail")
 
 	if err != nil {
 		t.Error(err)
 	}
 }
+
+func TestMailServiceMemoryTransport(t *testing.T) {
+	sender := mailer.NewMemoryMailer(10)
+	mailServ := NewMailService(logrus.NewEntry(logrus.New()), "foodApp <app@mail>", sender, nil, nil, nil, nil)
+
+	err := mailServ.SendMailFromApp(&mailer.Message{
+		Subject: "Код подтверждения",
+		HTML:    "<p>Ваш код: <b>A1B2C3D4</b></p>",
+		Text:    "Ваш код: A1B2C3D4",
+	}, "test@mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, ok := sender.Last("test@mail")
+	if !ok {
+		t.Fatal("message not captured")
+	}
+	if msg.From != "app@mail" || msg.Subject != "Код подтверждения" {
+		t.Errorf("unexpected envelope: %+v", msg)
+	}
+	if !strings.Contains(msg.Text, "A1B2C3D4") || !strings.Contains(msg.HTML, "<b>A1B2C3D4</b>") {
+		t.Errorf("code not found in message parts: %+v", msg)
+	}
+}
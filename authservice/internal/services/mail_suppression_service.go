@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/mailer"
+	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+	"github.com/sirupsen/logrus"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const defaultSuppressionListLimit = 100
+
+type MailSuppressionService struct {
+	log  *logrus.Entry
+	repo *repositories.MailSuppressionRepository
+}
+
+func NewMailSuppressionService(log *logrus.Entry, repo *repositories.MailSuppressionRepository) *MailSuppressionService {
+	return &MailSuppressionService{
+		log:  log,
+		repo: repo,
+	}
+}
+
+// maxSuppressionEmailLength длина столбца auth.mail_suppressions.email
+const maxSuppressionEmailLength = 256
+
+// Register сохраняет постоянные возвраты и жалобы одной транзакцией. Временные возвраты (ящик переполнен,
+// сервер недоступен) не блокируют адрес, с ними справляются повторы очереди. Записи с некорректным адресом
+// пропускаются, чтобы одна такая запись не отменяла остальные. Возвращает число заблокированных адресов
+func (s *MailSuppressionService) Register(bounces []mailer.Bounce) (int, error) {
+	suppressions := make([]entity.MailSuppression, 0, len(bounces))
+	for _, b := range bounces {
+		if b.Type == mailer.BounceTypeBounce && !b.Permanent {
+			s.log.Debugf("временный возврат для %s: %s", b.Email, b.Reason)
+			continue
+		}
+
+		email := strings.TrimSpace(b.Email)
+		if !validSuppressionEmail(email) {
+			s.log.Warnf("возврат с некорректным адресом %q пропущен", b.Email)
+			continue
+		}
+
+		suppression := entity.MailSuppression{
+			Email:  email,
+			Reason: b.Type,
+		}
+		if b.Reason != "" {
+			suppression.Detail = &b.Reason
+		}
+		suppressions = append(suppressions, suppression)
+	}
+	if len(suppressions) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	tx, err := s.repo.CreateTx()
+	if err != nil {
+		s.log.Error("ошибка открытия транзакции при сохранении недоставляемых адресов: ", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for i := range suppressions {
+		if err := s.repo.UpsertTx(ctx, tx, &suppressions[i]); err != nil {
+			s.log.Error("ошибка при сохранении недоставляемого адреса: ", err)
+			return 0, err
+		}
+	}
+
+	if err := s.repo.CommitTx(tx); err != nil {
+		s.log.Error("ошибка при сохранении недоставляемых адресов: ", err)
+		return 0, err
+	}
+
+	return len(suppressions), nil
+}
+
+func validSuppressionEmail(email string) bool {
+	if email == "" || len(email) > maxSuppressionEmailLength {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+
+	return err == nil && addr.Address == email
+}
+
+// IsSuppressed адрес помечен как недоставляемый. При ошибке БД письмо не блокируется
+func (s *MailSuppressionService) IsSuppressed(email string) bool {
+	res, err := s.repo.ExistsByEmail(email)
+	if err != nil {
+		s.log.Error("ошибка при проверке недоставляемого адреса: ", err)
+		return false
+	}
+
+	return res
+}
+
+func (s *MailSuppressionService) GetAll() []entity.MailSuppression {
+	res, err := s.repo.FindAll(defaultSuppressionListLimit)
+	if err != nil {
+		s.log.Error("ошибка при получении недоставляемых адресов: ", err)
+		return make([]entity.MailSuppression, 0)
+	}
+	if res == nil {
+		return make([]entity.MailSuppression, 0)
+	}
+
+	return res
+}
+
+// Delete снимает блокировку адреса, например после того как пользователь исправил ящик
+func (s *MailSuppressionService) Delete(id int64) (*entity.MailSuppression, error) {
+	res, err := s.repo.DeleteById(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		s.log.Error("ошибка при удалении недоставляемого адреса: ", err)
+		return nil, err
+	}
+
+	return res, nil
+}
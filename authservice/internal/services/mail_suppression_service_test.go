@@ -0,0 +1,41 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidSuppressionEmail(t *testing.T) {
+	cases := []struct {
+		email string
+		want  bool
+	}{
+		{"user@example.org", true},
+		{"", false},
+		{"not-an-email", false},
+		{"User <user@example.org>", false},
+		{strings.Repeat("a", 250) + "@example.org", false},
+	}
+
+	for _, tc := range cases {
+		if got := validSuppressionEmail(tc.email); got != tc.want {
+			t.Errorf("validSuppressionEmail(%q) = %v, want %v", tc.email, got, tc.want)
+		}
+	}
+}
+
+func TestWithoutSuppressed(t *testing.T) {
+	suppressed := func(email string) bool {
+		return strings.EqualFold(email, "bounced@example.org")
+	}
+
+	got := withoutSuppressed([]string{"user@example.org", "Bounced@example.org", "other@example.org"}, suppressed)
+	if want := []string{"user@example.org", "other@example.org"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := withoutSuppressed([]string{"bounced@example.org"}, suppressed); len(got) != 0 {
+		t.Errorf("got %v, want empty", got)
+	}
+}
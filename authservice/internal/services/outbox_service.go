@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+	"github.com/EddyZe/foodApp/common/pkg/events"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+const (
+	relayTimeout       = 60 * time.Second
+	relayCommitReserve = 15 * time.Second
+)
+
+type OutboxService struct {
+	log      *logrus.Entry
+	cfg      *config.OutboxConfig
+	topic    string
+	repo     *repositories.OutboxRepository
+	producer events.Producer
+}
+
+func NewOutboxService(
+	log *logrus.Entry,
+	cfg *config.OutboxConfig,
+	topic string,
+	repo *repositories.OutboxRepository,
+	producer events.Producer,
+) *OutboxService {
+	return &OutboxService{
+		log:      log,
+		cfg:      cfg,
+		topic:    topic,
+		repo:     repo,
+		producer: producer,
+	}
+}
+
+// AddTx записывает конверт события в outbox в транзакции вызывающего. Ключ - id пользователя, по нему сохраняется порядок
+func (s *OutboxService) AddTx(ctx context.Context, tx *sqlx.Tx, userId int64, ev events.Event) error {
+	env, err := events.NewEnvelope(strconv.FormatInt(userId, 10), ev)
+	if err != nil {
+		s.log.Error("ошибка при сериализации события: ", err)
+		return err
+	}
+	env.Trace = events.TraceFromContext(ctx)
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		s.log.Error("ошибка при сериализации события: ", err)
+		return err
+	}
+
+	msg := entity.OutboxMessage{
+		AggregateKey: env.Key,
+		EventType:    env.Type,
+		Topic:        s.topic,
+		Payload:      string(data),
+	}
+
+	if err := s.repo.SaveTx(ctx, tx, &msg); err != nil {
+		s.log.Error("ошибка при сохранении события в outbox: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// Relay отправляет готовые сообщения в брокер. Если отправка по ключу не удалась,
+// остальные сообщения этого ключа ждут следующего запуска, чтобы не нарушить порядок
+func (s *OutboxService) Relay() error {
+	ctx, cancel := context.WithTimeout(context.Background(), relayTimeout)
+	defer cancel()
+	// после publishDeadline новые сообщения не отправляются: остаток времени нужен на отметку и комит
+	publishDeadline := time.Now().Add(relayTimeout - relayCommitReserve)
+
+	tx, err := s.repo.CreateTx()
+	if err != nil {
+		s.log.Error("ошибка при создании транзакции отправки outbox: ", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	locked, err := s.repo.TryLockTx(ctx, tx)
+	if err != nil {
+		s.log.Error("ошибка при блокировке outbox: ", err)
+		return err
+	}
+	if !locked {
+		s.log.Debug("outbox отправляет другой экземпляр сервиса")
+		return nil
+	}
+
+	msgs, err := s.repo.FindReadyTx(ctx, tx, s.cfg.BatchSize)
+	if err != nil {
+		s.log.Error("ошибка при получении сообщений outbox: ", err)
+		return err
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	failedKeys := make(map[string]bool)
+	published := make([]int64, 0, len(msgs))
+	dead := 0
+	for _, msg := range msgs {
+		if time.Now().After(publishDeadline) {
+			s.log.Warnf("не хватило времени на пачку outbox, отправлено %d из %d", len(published), len(msgs))
+			break
+		}
+		if failedKeys[msg.AggregateKey] {
+			continue
+		}
+
+		env, err := events.ParseEnvelope([]byte(msg.Payload))
+		if err != nil {
+			// битая запись не должна блокировать остальные события пользователя, но и теряться молча не должна
+			s.log.Errorf("событие %d в outbox не разбирается и отложено в dead-letter: %v", msg.Id, err)
+			if err := s.repo.MarkDeadTx(ctx, tx, msg.Id, err.Error()); err != nil {
+				s.log.Error("ошибка при переносе сообщения outbox в dead-letter: ", err)
+				return err
+			}
+			dead++
+			continue
+		}
+
+		if err := s.producer.Publish(ctx, msg.Topic, env); err != nil {
+			s.log.Errorf("ошибка при отправке события %s (id %d): %v", msg.EventType, msg.Id, err)
+			failedKeys[msg.AggregateKey] = true
+
+			next := time.Now().Add(s.backoff(msg.Attempts))
+			if err := s.repo.MarkFailedTx(ctx, tx, msg.Id, err.Error(), next); err != nil {
+				s.log.Error("ошибка при сохранении неудачной попытки outbox: ", err)
+				return err
+			}
+			continue
+		}
+
+		published = append(published, msg.Id)
+	}
+
+	if err := s.repo.MarkPublishedTx(ctx, tx, published...); err != nil {
+		s.log.Error("ошибка при отметке отправленных сообщений outbox: ", err)
+		return err
+	}
+
+	if err := s.repo.CommitTx(tx); err != nil {
+		s.log.Error("ошибка при комите транзакции отправки outbox: ", err)
+		return err
+	}
+
+	s.log.Debugf("отправлено событий: %d, ошибок по ключам: %d, в dead-letter: %d", len(published), len(failedKeys), dead)
+	return nil
+}
+
+// backoff экспоненциальная задержка перед повтором: 1с, 2с, 4с ... но не больше MaxBackoffSeconds
+func (s *OutboxService) backoff(attempts int) time.Duration {
+	maxBackoff := time.Duration(s.cfg.MaxBackoffSeconds) * time.Second
+	if attempts > 30 {
+		return maxBackoff
+	}
+
+	delay := time.Second << attempts
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+
+	return delay
+}
+
+// CleanPublished удаляет отправленные сообщения старше срока хранения
+func (s *OutboxService) CleanPublished() error {
+	before := time.Now().Add(-time.Duration(s.cfg.RetentionHours) * time.Hour)
+	if err := s.repo.DeletePublishedBefore(before); err != nil {
+		s.log.Error("ошибка при очистке outbox: ", err)
+		return err
+	}
+
+	return nil
+}
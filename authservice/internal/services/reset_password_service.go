@@ -1,27 +1,44 @@
 package services
 
 import (
+	"context"
+	"crypto/subtle"
 	"errors"
 	"github.com/EddyZe/foodApp/authservice/internal/config"
 	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
 	"github.com/EddyZe/foodApp/authservice/internal/repositories"
 	"github.com/EddyZe/foodApp/authservice/internal/util/codegen"
-	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/authservice/internal/util/signedtoken"
+	"github.com/EddyZe/foodApp/common/pkg/apperr"
 	"github.com/sirupsen/logrus"
+	"strconv"
 	"time"
 )
 
+// resetTokenPurpose отличает токен сброса пароля от других подписанных токенов
+const resetTokenPurpose = "password_reset"
+
 type ResetPasswordService struct {
-	log  *logrus.Entry
-	cfg  *config.ResetPasswordVerificationCfg
-	repo *repositories.ResetPasswordRepository
+	log    *logrus.Entry
+	cfg    *config.ResetPasswordVerificationCfg
+	repo   *repositories.ResetPasswordRepository
+	us     *UserService
+	secret string
 }
 
-func NewResetPasswordService(log *logrus.Entry, cfg *config.ResetPasswordVerificationCfg, repo *repositories.ResetPasswordRepository) *ResetPasswordService {
+func NewResetPasswordService(
+	log *logrus.Entry,
+	cfg *config.ResetPasswordVerificationCfg,
+	repo *repositories.ResetPasswordRepository,
+	us *UserService,
+	secret string,
+) *ResetPasswordService {
 	return &ResetPasswordService{
-		log:  log,
-		repo: repo,
-		cfg:  cfg,
+		log:    log,
+		repo:   repo,
+		cfg:    cfg,
+		us:     us,
+		secret: secret,
 	}
 }
 
@@ -35,47 +52,124 @@ func (s *ResetPasswordService) Save(userId int64, code string) (*entity.ResetPas
 
 	if err := s.repo.Save(&newCode); err != nil {
 		s.log.Error("ошибка при сохрании кода сброса пароля: ", err)
-		return nil, errors.New(errormsg.IsExists)
+		return nil, err
 	}
 
 	return &newCode, nil
 }
 
-func (s *ResetPasswordService) SetIsValid(code string, b bool) error {
-	if err := s.repo.SetIsValid(code, b); err != nil {
-		s.log.Error("ошибка при обновлении статуса resetPassword: ", err)
-		return errors.New(errormsg.NotFound)
+// GenerateAndSaveCode выдает код для ввода вместе с email и подписанный одноразовый токен для ссылки из письма
+func (s *ResetPasswordService) GenerateAndSaveCode(userId int64) (*entity.ResetPasswordCode, string, error) {
+	code, err := s.Save(userId, codegen.GenerateRandomCode(8))
+	if err != nil {
+		return nil, "", err
 	}
-	return nil
+
+	token := signedtoken.Sign(
+		s.secret,
+		resetTokenPurpose,
+		strconv.FormatInt(code.Id, 10),
+		strconv.FormatInt(userId, 10),
+		strconv.FormatInt(code.ExpiredAt.Unix(), 10),
+	)
+
+	return code, token, nil
 }
 
-func (s *ResetPasswordService) DeleteByCode(code string) error {
-	if err := s.repo.DeleteByCode(code); err != nil {
-		s.log.Error("ошибка при удалении кода сбросов пароля: ", err)
-		return errors.New(errormsg.NotFound)
+// VerifyCode проверяет код пользователя. Неверные попытки учитываются по последнему выданному коду,
+// после MaxAttempts код аннулируется
+func (s *ResetPasswordService) VerifyCode(userId int64, codeString string) (*entity.ResetPasswordCode, error) {
+	code, err := s.repo.FindActiveByUserId(userId)
+	if err != nil {
+		s.log.Debug("действующий код сброса не найден: ", err)
+		return nil, ErrInvalidResetCode
+	}
+
+	if code.IsExpired() {
+		return nil, ErrCodeExpired
 	}
-	return nil
-}
 
-func (s *ResetPasswordService) GetCode(code string) (*entity.ResetPasswordCode, error) {
-	resetCode, err := s.repo.FindByCode(code)
+	if subtle.ConstantTimeCompare([]byte(code.Code), []byte(codeString)) == 1 {
+		return code, nil
+	}
+
+	attempts, err := s.repo.AddFailedAttempt(code.Id, s.cfg.MaxAttempts)
 	if err != nil {
-		s.log.Error("ошибка при поиске кода: ", err)
-		return nil, errors.New(errormsg.NotFound)
+		s.log.Error("ошибка при учете неверной попытки ввода кода сброса: ", err)
+		return nil, err
+	}
+	if attempts >= s.cfg.MaxAttempts {
+		s.log.Debug("код сброса аннулирован после неверных попыток, пользователь: ", userId)
+		return nil, ErrCodeAttemptsExceeded
+	}
+
+	return nil, ErrInvalidResetCode
+}
+
+// VerifyToken проверяет подпись токена из ссылки и что код, к которому он выпущен, еще действует
+func (s *ResetPasswordService) VerifyToken(token string) (*entity.ResetPasswordCode, error) {
+	values, ok := signedtoken.Verify(s.secret, token)
+	if !ok || len(values) != 4 || values[0] != resetTokenPurpose {
+		return nil, ErrInvalidResetCode
+	}
+
+	codeId, errId := strconv.ParseInt(values[1], 10, 64)
+	userId, errUser := strconv.ParseInt(values[2], 10, 64)
+	expiredAt, errExp := strconv.ParseInt(values[3], 10, 64)
+	if err := errors.Join(errId, errUser, errExp); err != nil {
+		return nil, ErrInvalidResetCode
 	}
-	return resetCode, nil
+
+	if time.Now().Unix() > expiredAt {
+		return nil, ErrCodeExpired
+	}
+
+	code, err := s.repo.FindById(codeId)
+	if err != nil || code.UserId != userId || !code.IsValid {
+		return nil, ErrInvalidResetCode
+	}
+
+	return code, nil
 }
 
-func (s *ResetPasswordService) GenerateAndSaveCode(userId int64) (*entity.ResetPasswordCode, error) {
-	for {
-		codeString := codegen.GenerateRandomCode(6)
-		code, err := s.Save(userId, codeString)
-		if err != nil {
-			if err.Error() == errormsg.IsExists {
-				continue
-			}
-			return nil, err
+// ResetPassword меняет пароль по проверенному коду. Код погашается в той же транзакции, что и смена пароля:
+// из одновременных запросов с одним кодом пароль сменит только первый, остальные получат ErrInvalidResetCode.
+// Остальные коды и ссылки пользователя аннулируются
+func (s *ResetPasswordService) ResetPassword(code *entity.ResetPasswordCode, newPassword string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.repo.CreateTx()
+	if err != nil {
+		s.log.Error("ошибка открытия транзакции при сбросе пароля: ", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	userId, err := s.repo.ConsumeTx(ctx, tx, code.Id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return ErrInvalidResetCode
 		}
-		return code, nil
+		s.log.Error("ошибка при погашении кода сброса пароля: ", err)
+		return err
+	}
+
+	u, err := s.us.EditPasswordTx(ctx, tx, userId, newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.InvalidateByUserIdTx(ctx, tx, userId); err != nil {
+		s.log.Error("ошибка при аннулировании кодов сброса пароля: ", err)
+		return err
+	}
+
+	if err := s.repo.CommitTx(tx); err != nil {
+		s.log.Error("ошибка при комите транзакции сброса пароля: ", err)
+		return err
 	}
+
+	s.us.removeCache(u)
+	return nil
 }
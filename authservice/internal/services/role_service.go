@@ -3,85 +3,92 @@ package services
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"github.com/EddyZe/foodApp/authservice/internal/datasourse/redis"
 	entity2 "github.com/EddyZe/foodApp/authservice/internal/domain/entity"
-	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
-
 	"github.com/EddyZe/foodApp/authservice/internal/repositories"
-	"github.com/EddyZe/foodApp/common/pkg/redisutil"
+	"github.com/EddyZe/foodApp/common/pkg/cache"
+	"github.com/EddyZe/foodApp/common/pkg/roles"
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
+	"slices"
+	"time"
 )
 
 var rolesUserid = "roles:user:id"
-var roleName = "roles:name"
+var permissionsRoleId = "permissions:role:id"
 
 type RoleService struct {
-	log   *logrus.Entry
-	redis *redis.Redis
-	repo  *repositories.RoleRepository
-	urr   *repositories.UserRoleRepository
+	log         *logrus.Entry
+	userRoles   *cache.Cache
+	permissions *cache.Cache
+	repo        *repositories.RoleRepository
+	urr         *repositories.UserRoleRepository
+	pr          *repositories.PermissionRepository
 }
 
-func NewRoleService(log *logrus.Entry, redis *redis.Redis, repo *repositories.RoleRepository, urr *repositories.UserRoleRepository) *RoleService {
+func NewRoleService(
+	log *logrus.Entry,
+	c *cache.Cache,
+	repo *repositories.RoleRepository,
+	urr *repositories.UserRoleRepository,
+	pr *repositories.PermissionRepository,
+) *RoleService {
 	return &RoleService{
-		log:   log,
-		redis: redis,
-		repo:  repo,
-		urr:   urr,
+		log:         log,
+		userRoles:   c.Namespace(rolesUserid),
+		permissions: c.Namespace(permissionsRoleId),
+		repo:        repo,
+		urr:         urr,
+		pr:          pr,
 	}
 }
 
 func (s *RoleService) GetRoleByUserId(userId int64) []entity2.Role {
-	redisKey := redisutil.GenerateKey(rolesUserid, fmt.Sprint(userId))
-	redisRes, ok := s.redis.Get(redisKey)
-	if ok {
-		var role []entity2.Role
-		if err := json.Unmarshal([]byte(redisRes), &role); err != nil {
-			s.log.Errorf("ошибка приобразования json из redis: %v", err)
-		}
-		return role
-	}
-
-	res := s.repo.GetRoleByUserId(userId)
-	if err := s.redis.Put(redisKey, res); err != nil {
-		s.log.Errorf("ошибка записи в редис: %v", err)
-	}
+	res, _ := cache.GetOrLoad(s.userRoles, fmt.Sprint(userId), func() ([]entity2.Role, error) {
+		return s.repo.GetRoleByUserId(userId), nil
+	})
+	return res
+}
 
+// GetPermissionsByRoleId возвращает разрешения роли
+func (s *RoleService) GetPermissionsByRoleId(roleId int64) []entity2.Permission {
+	res, _ := cache.GetOrLoad(s.permissions, fmt.Sprint(roleId), func() ([]entity2.Permission, error) {
+		return s.pr.GetPermissionsByRoleId(roleId), nil
+	})
 	return res
 }
 
-func (s *RoleService) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (*entity2.Role, error) {
-	redisKey := redisutil.GenerateKey(roleName, name)
-	data, ok := s.redis.Get(redisKey)
-	if ok {
-		var role entity2.Role
-		if err := json.Unmarshal([]byte(data), &role); err != nil {
-			s.log.Errorf("ошибка преобразования json из redis: %v", err)
+// GetPermissionsByRoles собирает уникальные названия разрешений по списку ролей
+func (s *RoleService) GetPermissionsByRoles(roles []entity2.Role) []string {
+	seen := make(map[string]struct{})
+	res := make([]string, 0)
+	for _, role := range roles {
+		for _, permission := range s.GetPermissionsByRoleId(role.Id.Int64) {
+			if _, ok := seen[permission.Name]; ok {
+				continue
+			}
+			seen[permission.Name] = struct{}{}
+			res = append(res, permission.Name)
 		}
-
-		return &role, nil
 	}
 
-	role, err := s.repo.FindByNameTx(ctx, tx, name)
-	if err != nil {
-		return nil, fmt.Errorf(errormsg.NotFound)
-	}
+	return res
+}
 
-	if err := s.redis.Put(redisKey, role); err != nil {
-		s.log.Errorf("ошибка записи в редис: %v", err)
-	}
+// GetPermissionsByUserId возвращает разрешения пользователя через его роли
+func (s *RoleService) GetPermissionsByUserId(userId int64) []string {
+	return s.GetPermissionsByRoles(s.GetRoleByUserId(userId))
+}
 
-	return role, nil
+// FindByNameTx читает роль в транзакции tx мимо кеша: загрузка в кеше общая для одновременных запросов,
+// и чужой запрос не должен получить результат из транзакции, которая может откатиться
+func (s *RoleService) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (*entity2.Role, error) {
+	return s.repo.FindByNameTx(ctx, tx, name)
 }
 
 func (s *RoleService) SetRole(userId, roleId sql.NullInt64) error {
 	s.log.Debug("Установка роли")
-	if err := s.redis.Del(redisutil.GenerateKey(rolesUserid, fmt.Sprint(userId.Int64))); err != nil {
-		s.log.Errorf("ошибка удаления ключа: %v", err)
-	}
+	s.removeUserRolesCache(userId.Int64)
 	if err := s.urr.SetUserRole(entity2.UserRole{
 		UserId: userId,
 		RoleId: roleId,
@@ -95,9 +102,7 @@ func (s *RoleService) SetRole(userId, roleId sql.NullInt64) error {
 
 func (s *RoleService) SetRoleTx(ctx context.Context, tx *sqlx.Tx, userId, roleId sql.NullInt64) error {
 	s.log.Debug("Установка роли")
-	if err := s.redis.Del(redisutil.GenerateKey(rolesUserid, fmt.Sprint(userId.Int64))); err != nil {
-		s.log.Errorf("ошибка удаления ключа: %v", err)
-	}
+	s.removeUserRolesCache(userId.Int64)
 	if err := s.urr.SetUserRoleTx(ctx, tx, &entity2.UserRole{
 		UserId: userId,
 		RoleId: roleId,
@@ -108,3 +113,311 @@ func (s *RoleService) SetRoleTx(ctx context.Context, tx *sqlx.Tx, userId, roleId
 	s.log.Debug("Роль установлена")
 	return nil
 }
+
+// RevokeRole снимает роль с пользователя. Администратор не может снять роль admin сам с себя,
+// и у последнего администратора ее снять нельзя
+func (s *RoleService) RevokeRole(actorId, userId, roleId int64) error {
+	role, err := s.GetById(roleId)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	tx, err := s.urr.CreateTx()
+	if err != nil {
+		s.log.Errorf("ошибка создания транзакции: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	holders, err := s.urr.LockUserIdsByRoleIdTx(ctx, tx, roleId)
+	if err != nil {
+		s.log.Errorf("ошибка при получении пользователей с ролью: %v", err)
+		return err
+	}
+	if !slices.Contains(holders, userId) {
+		return ErrNotFound
+	}
+	if err := checkRoleRevoke(role, actorId, userId, len(holders)); err != nil {
+		return err
+	}
+
+	if err := s.urr.DeleteUserRoleTx(ctx, tx, userId, roleId); err != nil {
+		s.log.Errorf("ошибка при удалении роли пользователя: %v", err)
+		return err
+	}
+	if err := s.urr.CommitTx(tx); err != nil {
+		s.log.Errorf("ошибка при комите транзакции: %v", err)
+		return err
+	}
+
+	s.removeUserRolesCache(userId)
+	return nil
+}
+
+// checkRoleRevoke holders - сколько пользователей сейчас имеют роль
+func checkRoleRevoke(role *entity2.Role, actorId, userId int64, holders int) error {
+	if role.Name != roles.Admin {
+		return nil
+	}
+	if actorId == userId {
+		return ErrOwnAdminRole
+	}
+	if holders <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// GrantRole выдает роль пользователю
+func (s *RoleService) GrantRole(userId, roleId int64) error {
+	if _, err := s.GetById(roleId); err != nil {
+		return err
+	}
+
+	if s.hasRole(userId, roleId) {
+		return ErrIsExists
+	}
+
+	return s.SetRole(
+		sql.NullInt64{Int64: userId, Valid: true},
+		sql.NullInt64{Int64: roleId, Valid: true},
+	)
+}
+
+func (s *RoleService) hasRole(userId, roleId int64) bool {
+	for _, role := range s.repo.GetRoleByUserId(userId) {
+		if role.Id.Int64 == roleId {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *RoleService) GetAll() []entity2.Role {
+	return s.repo.FindAll()
+}
+
+func (s *RoleService) GetById(id int64) (*entity2.Role, error) {
+	role, err := s.repo.FindById(id)
+	if err != nil {
+		s.log.Debugf("роль с id %v не найдена: %v", id, err)
+		return nil, err
+	}
+	return role, nil
+}
+
+// CreateRole создает новую роль
+func (s *RoleService) CreateRole(name, description string) (*entity2.Role, error) {
+	if _, err := s.repo.FindByName(name); err == nil {
+		return nil, ErrIsExists
+	}
+
+	role := entity2.Role{
+		Name:        name,
+		Description: description,
+	}
+
+	if err := s.repo.Save(&role); err != nil {
+		s.log.Errorf("ошибка при создании роли: %v", err)
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// UpdateRole изменяет роль и возвращает ее вместе с прежним состоянием. Встроенные роли переименовывать нельзя
+func (s *RoleService) UpdateRole(id int64, name, description string) (*entity2.Role, *entity2.Role, error) {
+	role, err := s.GetById(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if role.Name != name {
+		if isProtectedRole(role.Name) {
+			return nil, nil, ErrRoleIsProtected
+		}
+		if _, err := s.repo.FindByName(name); err == nil {
+			return nil, nil, ErrIsExists
+		}
+	}
+
+	old := *role
+	role.Name = name
+	role.Description = description
+
+	if err := s.repo.Update(role); err != nil {
+		s.log.Errorf("ошибка при изменении роли: %v", err)
+		return nil, nil, err
+	}
+
+	s.removeRolePermissionsCache(role.Id.Int64)
+	s.removeRoleUsersCache(role.Id.Int64)
+	return role, &old, nil
+}
+
+// DeleteRole удаляет роль. Встроенные роли удалять нельзя
+func (s *RoleService) DeleteRole(id int64) error {
+	role, err := s.GetById(id)
+	if err != nil {
+		return err
+	}
+
+	if isProtectedRole(role.Name) {
+		return ErrRoleIsProtected
+	}
+
+	userIds := s.urr.FindUserIdsByRoleId(id)
+
+	if err := s.repo.DeleteById(id); err != nil {
+		s.log.Errorf("ошибка при удалении роли: %v", err)
+		return err
+	}
+
+	s.removeRolePermissionsCache(id)
+	for _, userId := range userIds {
+		s.removeUserRolesCache(userId)
+	}
+	return nil
+}
+
+func (s *RoleService) GetAllPermissions() []entity2.Permission {
+	return s.pr.FindAll()
+}
+
+func (s *RoleService) GetPermissionById(id int64) (*entity2.Permission, error) {
+	permission, err := s.pr.FindById(id)
+	if err != nil {
+		s.log.Debugf("разрешение с id %v не найдено: %v", id, err)
+		return nil, err
+	}
+	return permission, nil
+}
+
+// CreatePermission создает новое разрешение
+func (s *RoleService) CreatePermission(name, description string) (*entity2.Permission, error) {
+	if _, err := s.pr.FindByName(name); err == nil {
+		return nil, ErrIsExists
+	}
+
+	permission := entity2.Permission{
+		Name:        name,
+		Description: description,
+	}
+
+	if err := s.pr.Save(&permission); err != nil {
+		s.log.Errorf("ошибка при создании разрешения: %v", err)
+		return nil, err
+	}
+
+	return &permission, nil
+}
+
+// UpdatePermission изменяет разрешение, возвращает его вместе с прежним состоянием и сбрасывает кеш ролей, в которые оно входит
+func (s *RoleService) UpdatePermission(id int64, name, description string) (*entity2.Permission, *entity2.Permission, error) {
+	permission, err := s.GetPermissionById(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if permission.Name != name {
+		if _, err := s.pr.FindByName(name); err == nil {
+			return nil, nil, ErrIsExists
+		}
+	}
+
+	old := *permission
+	permission.Name = name
+	permission.Description = description
+
+	if err := s.pr.Update(permission); err != nil {
+		s.log.Errorf("ошибка при изменении разрешения: %v", err)
+		return nil, nil, err
+	}
+
+	for _, roleId := range s.pr.FindRoleIdsByPermissionId(id) {
+		s.removeRolePermissionsCache(roleId)
+		s.removeRoleUsersCache(roleId)
+	}
+	return permission, &old, nil
+}
+
+// DeletePermission удаляет разрешение и сбрасывает кеш ролей, в которые оно входило
+func (s *RoleService) DeletePermission(id int64) error {
+	if _, err := s.GetPermissionById(id); err != nil {
+		return err
+	}
+
+	roleIds := s.pr.FindRoleIdsByPermissionId(id)
+
+	if err := s.pr.DeleteById(id); err != nil {
+		s.log.Errorf("ошибка при удалении разрешения: %v", err)
+		return err
+	}
+
+	for _, roleId := range roleIds {
+		s.removeRolePermissionsCache(roleId)
+		s.removeRoleUsersCache(roleId)
+	}
+	return nil
+}
+
+// AttachPermission добавляет разрешение к роли
+func (s *RoleService) AttachPermission(roleId, permissionId int64) error {
+	if _, err := s.GetById(roleId); err != nil {
+		return err
+	}
+	if _, err := s.GetPermissionById(permissionId); err != nil {
+		return err
+	}
+
+	if err := s.pr.AddToRole(roleId, permissionId); err != nil {
+		s.log.Errorf("ошибка при добавлении разрешения к роли: %v", err)
+		return err
+	}
+
+	s.removeRolePermissionsCache(roleId)
+	s.removeRoleUsersCache(roleId)
+	return nil
+}
+
+// DetachPermission убирает разрешение у роли
+func (s *RoleService) DetachPermission(roleId, permissionId int64) error {
+	if _, err := s.GetById(roleId); err != nil {
+		return err
+	}
+
+	if err := s.pr.RemoveFromRole(roleId, permissionId); err != nil {
+		s.log.Errorf("ошибка при удалении разрешения у роли: %v", err)
+		return err
+	}
+
+	s.removeRolePermissionsCache(roleId)
+	s.removeRoleUsersCache(roleId)
+	return nil
+}
+
+func (s *RoleService) removeUserRolesCache(userId int64) {
+	if err := s.userRoles.Invalidate(fmt.Sprint(userId)); err != nil {
+		s.log.Errorf("ошибка удаления ключа: %v", err)
+	}
+}
+
+// removeRoleUsersCache сбрасывает кеш ролей у всех пользователей с ролью,
+// чтобы при следующем обновлении токена claims собрались заново
+func (s *RoleService) removeRoleUsersCache(roleId int64) {
+	for _, userId := range s.urr.FindUserIdsByRoleId(roleId) {
+		s.removeUserRolesCache(userId)
+	}
+}
+
+func (s *RoleService) removeRolePermissionsCache(roleId int64) {
+	if err := s.permissions.Invalidate(fmt.Sprint(roleId)); err != nil {
+		s.log.Errorf("ошибка удаления ключа: %v", err)
+	}
+}
+
+func isProtectedRole(name string) bool {
+	return name == roles.Admin || name == roles.User
+}
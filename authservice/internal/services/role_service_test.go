@@ -0,0 +1,37 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/common/pkg/roles"
+)
+
+func TestCheckRoleRevoke(t *testing.T) {
+	admin := &entity.Role{Name: roles.Admin}
+	user := &entity.Role{Name: roles.User}
+
+	cases := []struct {
+		name    string
+		role    *entity.Role
+		actorId int64
+		userId  int64
+		holders int
+		want    error
+	}{
+		{"своя роль admin", admin, 1, 1, 3, ErrOwnAdminRole},
+		{"последний администратор", admin, 1, 2, 1, ErrLastAdmin},
+		{"один из нескольких администраторов", admin, 1, 2, 2, nil},
+		{"своя обычная роль", user, 1, 1, 1, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkRoleRevoke(tc.role, tc.actorId, tc.userId, tc.holders)
+			if !errors.Is(err, tc.want) || (tc.want == nil && err != nil) {
+				t.Errorf("ожидалось %v, получено %v", tc.want, err)
+			}
+		})
+	}
+}
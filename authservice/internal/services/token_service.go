@@ -2,16 +2,16 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"github.com/EddyZe/foodApp/authservice/internal/datasourse/redis"
 	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
 	"github.com/EddyZe/foodApp/authservice/internal/repositories"
-	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
 	"github.com/EddyZe/foodApp/authservice/internal/util/stringutils"
 	"github.com/EddyZe/foodApp/common/domain/models"
+	"github.com/EddyZe/foodApp/common/pkg/apperr"
+	"github.com/EddyZe/foodApp/common/pkg/cache"
 	"github.com/EddyZe/foodApp/common/pkg/jwtutil"
-	"github.com/EddyZe/foodApp/common/pkg/redisutil"
+	"github.com/EddyZe/foodApp/common/pkg/revocation"
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"time"
@@ -22,36 +22,54 @@ import (
 )
 
 type TokenService struct {
-	log   *logrus.Entry
-	cfg   *config.TokenConfig
-	redis *redis.Redis
-	rs    *repositories.RefreshTokenRepository
-	ar    *repositories.AccessTokenRepository
+	log    *logrus.Entry
+	cfg    *config.TokenConfig
+	redis  *redis.Redis
+	tokens *cache.Cache
+	rs     *repositories.RefreshTokenRepository
+	ar     *repositories.AccessTokenRepository
+	blr    *repositories.BlackListTokenRepository
+	roles  *RoleService
+	rc     *revocation.Checker
 }
 
 func NewTokenService(
 	cfg *config.TokenConfig,
-	redis *redis.Redis,
+	red *redis.Redis,
+	c *cache.Cache,
 	rs *repositories.RefreshTokenRepository,
 	log *logrus.Entry,
 	ar *repositories.AccessTokenRepository,
+	blr *repositories.BlackListTokenRepository,
+	roles *RoleService,
 ) *TokenService {
 	return &TokenService{
 		log:   log,
 		cfg:   cfg,
-		redis: redis,
+		redis: red,
+		tokens: c.Namespace(redis.RefreshTokenUser).
+			WithTTL(time.Duration(cfg.RefreshTokenExpirationMinute) * time.Minute),
 		rs:    rs,
 		ar:    ar,
+		blr:   blr,
+		roles: roles,
+		rc:    revocation.NewChecker(red, cfg.RevocationFailClosed),
 	}
 }
 
 // GenerateJwt генерирует jwt токен
 func (s *TokenService) GenerateJwt(claims map[string]interface{}) (string, error) {
+	return s.generateJwt(claims, time.Duration(s.cfg.TokenExpirationMinute)*time.Minute)
+}
+
+func (s *TokenService) generateJwt(claims map[string]interface{}, ttl time.Duration) (string, error) {
 	secret := s.cfg.Secret
-	expiration := time.Now().Add(time.Duration(s.cfg.TokenExpirationMinute) * time.Minute).Unix()
+	now := time.Now()
 	jwtClaims := jwt.MapClaims{
-		"ext": expiration,
-		"iat": time.Now().Unix(),
+		"ext": now.Add(ttl).Unix(),
+		"iat": now.Unix(),
+		// iat_ms нужен проверке отзыва: токен, выданный в ту же секунду после отзыва, должен остаться действительным
+		"iat_ms": now.UnixMilli(),
 	}
 
 	for key, value := range claims {
@@ -67,15 +85,25 @@ func (s *TokenService) GenerateJwt(claims map[string]interface{}) (string, error
 	return tokenString, nil
 }
 
+// GenerateClaimsByUser собирает claims пользователя. Разрешения ролей добавляются в scope, если это включено в конфиге
+func (s *TokenService) GenerateClaimsByUser(u *entity.User, roles []entity.Role) map[string]interface{} {
+	claims := models.JwtClaims{
+		Email:         u.Email,
+		EmailVerified: u.EmailIsConfirm,
+		Role:          stringutils.RoleMapString(roles),
+		Sub:           u.Id.Int64,
+		Locale:        u.PreferredLocale(),
+	}
+
+	if s.cfg.EmbedPermissions {
+		claims.Permissions = s.roles.GetPermissionsByRoles(roles)
+	}
+
+	return jwtutil.GenerateClaims(&claims)
+}
+
 func (s *TokenService) GenerateJwtByUser(u *entity.User, roles []entity.Role) (string, error) {
-	token, err := s.GenerateJwt(
-		jwtutil.GenerateClaims(&models.JwtClaims{
-			Email:         u.Email,
-			EmailVerified: u.EmailIsConfirm,
-			Role:          stringutils.RoleMapString(roles),
-			Sub:           u.Id.Int64,
-		}),
-	)
+	token, err := s.GenerateJwt(s.GenerateClaimsByUser(u, roles))
 	if err != nil {
 		s.log.Error(err)
 		return "", err
@@ -84,6 +112,55 @@ func (s *TokenService) GenerateJwtByUser(u *entity.User, roles []entity.Role) (s
 	return token, nil
 }
 
+// GenerateImpersonationToken выдает короткоживущий access токен пользователя с claim act администратора.
+// Refresh токен не выдается: по истечении срока администратор запрашивает новый токен
+func (s *TokenService) GenerateImpersonationToken(u *entity.User, roles []entity.Role, actorId int64) (*entity.AccessToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	claims := models.JwtClaims{
+		Email:         u.Email,
+		EmailVerified: u.EmailIsConfirm,
+		Role:          stringutils.RoleMapString(roles),
+		Sub:           u.Id.Int64,
+		Locale:        u.PreferredLocale(),
+		Actor:         actorId,
+	}
+	if s.cfg.EmbedPermissions {
+		claims.Permissions = s.roles.GetPermissionsByRoles(roles)
+	}
+
+	ttl := time.Duration(s.cfg.ImpersonationExpirationMinute) * time.Minute
+	token, err := s.generateJwt(jwtutil.GenerateClaims(&claims), ttl)
+	if err != nil {
+		s.log.Error("ошибка генерации токена имперсонации: ", err)
+		return nil, err
+	}
+
+	tx, err := s.ar.CreateTx()
+	if err != nil {
+		s.log.Error("ошибка создания транзакции: ", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	at := entity.AccessToken{
+		Token:     token,
+		ExpiredAt: time.Now().Add(ttl),
+	}
+	if err := s.ar.SaveTx(ctx, tx, &at); err != nil {
+		s.log.Error("ошибка сохранения токена имперсонации: ", err)
+		return nil, err
+	}
+
+	if err := s.ar.CommitTx(tx); err != nil {
+		s.log.Error("ошибка при комите транзакции при сохранении токена имперсонации: ", err)
+		return nil, err
+	}
+
+	return &at, nil
+}
+
 func (s *TokenService) GenerateUUID() string {
 	s.log.Debug("Генерация токена")
 	refreshToken := uuid.New().String()
@@ -91,31 +168,10 @@ func (s *TokenService) GenerateUUID() string {
 }
 
 func (s *TokenService) ValidateRefreshToken(refreshToken string) bool {
-	redisKey := redisutil.GenerateKey(redis.RefreshTokenUser, refreshToken)
-	jsonToken, ok := s.redis.Get(redisKey)
-	if !ok {
-		jt, err := s.rs.FindByToken(refreshToken)
-		if err != nil {
-			return false
-		}
-		data, err := json.Marshal(&jt)
-		if err != nil {
-			s.log.Error("ошибка при преобразовании json refresh token")
-			return false
-		}
-		jsonToken = string(data)
-
-		ex := time.Duration(int64(s.cfg.RefreshTokenExpirationMinute)) * time.Minute
-
-		if err := s.redis.PutEx(redisKey, jsonToken, ex); err != nil {
-			s.log.Error("Ошибка при сохранеии в редис")
-		}
-
-	}
-
-	var res entity.RefreshToken
-	if err := json.Unmarshal([]byte(jsonToken), &res); err != nil {
-		s.log.Error("ошибка при преобразовании json refresh token", err)
+	res, err := cache.GetOrLoad(s.tokens, refreshToken, func() (*entity.RefreshToken, error) {
+		return s.rs.FindByToken(refreshToken)
+	})
+	if err != nil {
 		return false
 	}
 
@@ -166,13 +222,9 @@ func (s *TokenService) SaveRefreshAndAccessToken(userId int64, accessToken, refr
 		return nil, nil, err
 	}
 
-	s.log.Debug("сохранение токена в редис")
-	redisKey := redisutil.GenerateKey(redis.RefreshTokenUser, refreshToken)
-
-	ex := time.Duration(int64(s.cfg.RefreshTokenExpirationMinute)) * time.Minute
-
-	if err := s.redis.PutEx(redisKey, rt, ex); err != nil {
-		s.log.Error("ошибка при сохранении токена в редис ", err)
+	s.log.Debug("сохранение токена в кеш")
+	if err := s.tokens.Set(refreshToken, rt); err != nil {
+		s.log.Error("ошибка при сохранении токена в кеш ", err)
 	}
 
 	return &at, &rt, err
@@ -189,32 +241,19 @@ func (s *TokenService) ReplaceTokens(refreshToken string, claims map[string]inte
 		return nil, nil, err
 	}
 
-	var token *entity.RefreshToken
-
-	rediskey := redisutil.GenerateKey(redis.RefreshTokenUser, refreshToken)
-
-	s.log.Debug("поиск рефрешь токена в редисе")
-	if jsonToken, ok := s.redis.Get(rediskey); ok {
-		if err := json.Unmarshal([]byte(jsonToken), &token); err != nil {
-			s.log.Error("Ошибка при конвертации json refresh token redis", err)
-			t, err := s.rs.FindByTokenTx(ctx, tx, refreshToken)
-			if err != nil {
-				s.log.Error("Refresh token не найден в базе")
-				return nil, nil, errors.New(errormsg.NotFound)
-			}
-			token = t
-		}
-		if err := s.redis.Del(rediskey); err != nil {
-			s.log.Error("ошибка удаления токена из редис ", err)
-		}
+	if err := s.tokens.Invalidate(refreshToken); err != nil {
+		s.log.Error("ошибка удаления токена из кеша ", err)
 	}
 
-	s.log.Debug("Поиск в редисе завершен")
-
-	token, err = s.rs.FindByTokenTx(ctx, tx, refreshToken)
+	token, err := s.rs.FindByTokenTx(ctx, tx, refreshToken)
 	if err != nil {
-		s.log.Error("refresh token не найден в базе")
-		return nil, nil, errors.New(errormsg.NotFound)
+		// токен уже заменен параллельным запросом или отозван
+		if errors.Is(err, ErrNotFound) {
+			s.log.Debug("refresh токен не найден в базе")
+			return nil, nil, err
+		}
+		s.log.Error("ошибка при поиске refresh токена: ", err)
+		return nil, nil, err
 	}
 
 	s.log.Debug("удаляем связанные access токены")
@@ -247,23 +286,12 @@ func (s *TokenService) ReplaceTokens(refreshToken string, claims map[string]inte
 }
 
 func (s *TokenService) IsRevokeRefreshToken(refreshToken string, isRevoke bool) error {
-	redisKey := redisutil.GenerateKey(redis.RefreshTokenUser, refreshToken)
-	var res *entity.RefreshToken
-	if jsonData, ok := s.redis.Get(redisKey); ok {
-		if err := json.Unmarshal([]byte(jsonData), &res); err != nil {
-			s.log.Error("ошибка десириализации json refresh token", err)
-		}
-		if err := s.redis.Del(redisKey); err != nil {
-			s.log.Error("ошибка удаления из редис refresh token", err)
-		}
-	}
-	if res == nil {
-		r, err := s.rs.FindByToken(refreshToken)
-		if err != nil {
-			s.log.Error("<UNK> <UNK> <UNK> refresh token", err)
-			return errors.New(errormsg.NotFound)
-		}
-		res = r
+	res, err := cache.GetOrLoad(s.tokens, refreshToken, func() (*entity.RefreshToken, error) {
+		return s.rs.FindByToken(refreshToken)
+	})
+	if err != nil {
+		s.log.Error("ошибка при поиске refresh token: ", err)
+		return err
 	}
 
 	res.IsRevoke = isRevoke
@@ -272,10 +300,8 @@ func (s *TokenService) IsRevokeRefreshToken(refreshToken string, isRevoke bool)
 		return err
 	}
 
-	ex := time.Duration(int64(s.cfg.RefreshTokenExpirationMinute)) * time.Minute
-
-	if err := s.redis.PutEx(redisKey, res, ex); err != nil {
-		s.log.Error("ошибка при добавлении в редис refresh token", err)
+	if err := s.tokens.Set(refreshToken, res); err != nil {
+		s.log.Error("ошибка при добавлении в кеш refresh token", err)
 	}
 	return nil
 }
@@ -289,6 +315,7 @@ func (s *TokenService) Logout(accessToken string) error {
 		s.log.Error("Ошибка при открытии транзакции: ", err)
 		return err
 	}
+	defer tx.Rollback()
 
 	refreshtoken, err := s.rs.RemoveByAccessTokenTx(
 		ctx,
@@ -300,9 +327,8 @@ func (s *TokenService) Logout(accessToken string) error {
 		return err
 	}
 
-	redisKey := redisutil.GenerateKey(redis.RefreshTokenUser, refreshtoken)
-	if err := s.redis.Del(redisKey); err != nil {
-		s.log.Error("ошибка удаления refresh token из redis", err)
+	if err := s.tokens.Invalidate(refreshtoken); err != nil {
+		s.log.Error("ошибка удаления refresh token из кеша", err)
 	}
 
 	if err := s.ar.DeleteByTokenTx(
@@ -321,6 +347,42 @@ func (s *TokenService) Logout(accessToken string) error {
 	return nil
 }
 
+// EndImpersonation завершает сессию имперсонации. У токена имперсонации нет refresh токена,
+// поэтому он удаляется и попадает в черный список до истечения срока
+func (s *TokenService) EndImpersonation(accessToken string, expiredAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
+	defer cancel()
+
+	tx, err := s.ar.CreateTx()
+	if err != nil {
+		s.log.Error("Ошибка при открытии транзакции: ", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.ar.DeleteByTokenTx(ctx, tx, accessToken); err != nil {
+		s.log.Error("ошибка при удалении токена имперсонации: ", err)
+		return err
+	}
+
+	revoked := []entity.BlackListToken{{Token: accessToken, ExpiredAt: expiredAt}}
+	if err := s.blr.SaveAllTx(ctx, tx, revoked); err != nil {
+		s.log.Error("ошибка при добавлении токена имперсонации в черный список: ", err)
+		return err
+	}
+
+	if err := s.blacklistAccessTokens(revoked); err != nil {
+		return err
+	}
+
+	if err := s.ar.CommitTx(tx); err != nil {
+		s.log.Error("ошибка при комите транзакции при завершении имперсонации: ", err)
+		return err
+	}
+
+	return nil
+}
+
 func (s *TokenService) LogoutAll(userid int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
 	defer cancel()
@@ -330,59 +392,125 @@ func (s *TokenService) LogoutAll(userid int64) error {
 		s.log.Error("Ошибка при создании транзакции при удалении всех токенов: ", err)
 		return err
 	}
-	if err := s.logoutAll(ctx, tx, userid); err != nil {
+	defer tx.Rollback()
+
+	revoked, err := s.logoutAll(ctx, tx, userid)
+	if err != nil {
 		s.log.Error("ошибка при вызоде из всех устройст: ", err)
 		return err
 	}
 
+	if err := s.revokeAccessTokens(userid, revoked); err != nil {
+		return err
+	}
+
 	if err := s.rs.CommitTx(tx); err != nil {
 		s.log.Error("ошибка при комите транзацкии при удалении токенов: ", err)
 		return err
 	}
+
 	return nil
 }
 
 func (s *TokenService) LogoutAllTx(ctx context.Context, tx *sqlx.Tx, userid int64) error {
-	if err := s.logoutAll(ctx, tx, userid); err != nil {
+	revoked, err := s.logoutAll(ctx, tx, userid)
+	if err != nil {
 		s.log.Error("ошибка при выхода пользователя из системы: ", err)
 		return err
 	}
 
+	if err := s.revokeAccessTokens(userid, revoked); err != nil {
+		return err
+	}
+
 	if err := s.rs.CommitTx(tx); err != nil {
 		s.log.Error("ошибка при комите транзацкии при удалении токенов: ", err)
 		return err
 	}
+
 	return nil
 }
 
-func (s *TokenService) logoutAll(ctx context.Context, tx *sqlx.Tx, userid int64) error {
+// logoutAll удаляет все refresh и access токены пользователя в транзакции и заносит access токены в черный список.
+// До комита нужно вызвать revokeAccessTokens, чтобы отзыв увидели JwtFilter других сервисов
+func (s *TokenService) logoutAll(ctx context.Context, tx *sqlx.Tx, userid int64) ([]entity.BlackListToken, error) {
 	tokens, err := s.rs.RemoveAllRefreshTokensUserTx(ctx, tx, userid)
 	if err != nil {
 		s.log.Error("ошибка удаления refresh токенов: ", err)
-		return err
+		return nil, err
 	}
 
-	s.log.Info(tokens)
-
 	var ids []int64
 
-	if len(tokens) > 0 {
-		for _, token := range tokens {
+	for _, token := range tokens {
+		if err := s.tokens.Invalidate(token.Token); err != nil {
+			s.log.Error("ошибка удаления refresh token из кеша: ", err)
+		}
+		if token.AccessTokenId.Valid {
 			ids = append(ids, token.AccessTokenId.Int64)
-			redisKey := redisutil.GenerateKey(redis.RefreshTokenUser, token.Token)
-			if err := s.redis.Del(redisKey); err != nil {
-				continue
-			}
 		}
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	accessTokens, err := s.ar.DeleteByIdsReturningTx(ctx, tx, ids...)
+	if err != nil {
+		s.log.Error("ошибка при удалении access токеновт", err)
+		return nil, err
+	}
+
+	blackList := make([]entity.BlackListToken, 0, len(accessTokens))
+	for _, token := range accessTokens {
+		if token.ExpiredAt.Before(time.Now()) {
+			continue
+		}
+		blackList = append(blackList, entity.BlackListToken{
+			Token:     token.Token,
+			ExpiredAt: token.ExpiredAt,
+		})
+	}
 
-		if err := s.ar.DeleteByIdsTx(ctx, tx, ids...); err != nil {
-			s.log.Error("ошибка при удалении access токеновт", err)
-			return err
+	if err := s.blr.SaveAllTx(ctx, tx, blackList); err != nil {
+		s.log.Error("ошибка при добавлении access токенов в черный список: ", err)
+		return nil, err
+	}
+
+	return blackList, nil
+}
+
+// revokeAccessTokens публикует отзыв в redis: время отзыва всех токенов пользователя (unix ms) и черный список токенов.
+// Запись не откладывается до восстановления redis, так как отложенная запись пропадет при перезапуске, а пока
+// отзыв не опубликован, JwtFilter других сервисов принимают токены. Вызывается до комита: при ошибке транзакция
+// откатывается, и операцию можно повторить. Лишний отзыв при неудачном комите только заставит обновить токены
+func (s *TokenService) revokeAccessTokens(userId int64, tokens []entity.BlackListToken) error {
+	ex := time.Duration(s.cfg.TokenExpirationMinute) * time.Minute
+	if err := s.redis.PutExNow(revocation.UserKey(userId), time.Now().UnixMilli(), ex); err != nil {
+		s.log.Error("ошибка при сохранении отзыва токенов пользователя в редис: ", err)
+		return apperr.Unavailable(err)
+	}
+
+	return s.blacklistAccessTokens(tokens)
+}
+
+// blacklistAccessTokens публикует в redis черный список access токенов
+func (s *TokenService) blacklistAccessTokens(tokens []entity.BlackListToken) error {
+	for _, token := range tokens {
+		if err := s.redis.PutExNow(revocation.TokenKey(token.Token), true, time.Until(token.ExpiredAt)); err != nil {
+			s.log.Error("ошибка при добавлении access токена в черный список редис: ", err)
+			return apperr.Unavailable(err)
 		}
 	}
+
 	return nil
 }
 
+// IsRevoked реализует middleware.RevocationChecker
+func (s *TokenService) IsRevoked(token string, claims *models.JwtClaims) bool {
+	return s.rc.IsRevoked(token, claims)
+}
+
 func (s *TokenService) GetAccessToken(token string) (*entity.AccessToken, bool) {
 	tok, err := s.ar.FindByToken(token)
 	if err != nil {
@@ -393,6 +521,16 @@ func (s *TokenService) GetAccessToken(token string) (*entity.AccessToken, bool)
 	return tok, true
 }
 
+// GetActiveSessions активные (не истекшие и не отозванные) refresh токены пользователя
+func (s *TokenService) GetActiveSessions(userId int64) []entity.RefreshToken {
+	tokens, err := s.rs.FindActiveByUserId(userId)
+	if err != nil {
+		s.log.Error("ошибка при получении сессий пользователя: ", err)
+		return make([]entity.RefreshToken, 0)
+	}
+	return tokens
+}
+
 func (s *TokenService) Secret() string {
 	return s.cfg.Secret
 }
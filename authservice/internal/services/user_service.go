@@ -2,49 +2,76 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/EddyZe/foodApp/authservice/internal/config"
 	"github.com/EddyZe/foodApp/authservice/internal/datasourse/redis"
 	"github.com/EddyZe/foodApp/authservice/internal/domain/dto"
 	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
-	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
 	"github.com/EddyZe/foodApp/authservice/internal/util/passencoder"
+	"strings"
 	"time"
 
 	"github.com/EddyZe/foodApp/authservice/internal/repositories"
-	"github.com/EddyZe/foodApp/common/pkg/redisutil"
+	"github.com/EddyZe/foodApp/common/pkg/cache"
+	"github.com/EddyZe/foodApp/common/pkg/events"
 	"github.com/EddyZe/foodApp/common/pkg/roles"
+	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 )
 
 type UserService struct {
-	log   *logrus.Entry
-	redis *redis.Redis
-	rs    *RoleService
-	ur    *repositories.UserRepository
-	hps   *HistoryPasswordService
+	log     *logrus.Entry
+	byId    *cache.Cache
+	byEmail *cache.Cache
+	rs      *RoleService
+	ts      *TokenService
+	ur      *repositories.UserRepository
+	hps     *HistoryPasswordService
+	obs     *OutboxService
+	is      *InviteService
+	cs      *ConsentService
+	reg     *config.RegistrationConfig
 }
 
 func NewUserService(
 	log *logrus.Entry,
-	redis *redis.Redis,
+	c *cache.Cache,
 	rs *RoleService,
+	ts *TokenService,
 	ur *repositories.UserRepository,
 	hps *HistoryPasswordService,
+	obs *OutboxService,
+	is *InviteService,
+	cs *ConsentService,
+	reg *config.RegistrationConfig,
 ) *UserService {
 	return &UserService{
-		log:   log,
-		redis: redis,
-		rs:    rs,
-		ur:    ur,
-		hps:   hps,
+		log:     log,
+		byId:    c.Namespace(redis.UserIdKeys),
+		byEmail: c.Namespace(redis.UserEmailKeys),
+		rs:      rs,
+		ts:      ts,
+		ur:      ur,
+		hps:     hps,
+		obs:     obs,
+		is:      is,
+		cs:      cs,
+		reg:     reg,
 	}
 }
 
 // CreateUser функция создает пользователя
 func (s *UserService) CreateUser(dto *dto.RegisterDto) (*entity.User, error) {
 	s.log.Debug("Создание пользователя")
+	if !s.registrationAllowed(dto) {
+		s.log.Debug("Регистрация запрещена режимом: ", s.reg.Mode)
+		return nil, ErrRegistrationRestricted
+	}
+
 	s.log.Debug("Хеширование пароля")
 	passwordHash, err := passencoder.PasswordHash(dto.Password)
 	if err != nil {
@@ -65,10 +92,11 @@ func (s *UserService) CreateUser(dto *dto.RegisterDto) (*entity.User, error) {
 		s.log.Errorf("Ошибка при открытии транзакции: %v", err)
 		return nil, err
 	}
+	defer tx.Rollback()
 
 	s.log.Debug("Поиск пользователь с таким же email: ", dto.Email)
 	if _, err := s.ur.FindByEmailTx(ctx, tx, dto.Email); err == nil {
-		return nil, errors.New(errormsg.IsExists)
+		return nil, ErrIsExists
 	}
 
 	s.log.Debug("Сохранение пользователя")
@@ -90,6 +118,37 @@ func (s *UserService) CreateUser(dto *dto.RegisterDto) (*entity.User, error) {
 		return nil, err
 	}
 
+	if dto.InviteCode != "" {
+		s.log.Debug("Использование инвайт-кода: ", dto.Email)
+		roleIds, err := s.is.RedeemTx(ctx, tx, dto.InviteCode, newUser.Id.Int64)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, roleId := range roleIds {
+			if roleId == role.Id.Int64 {
+				continue
+			}
+			if err := s.rs.SetRoleTx(ctx, tx, newUser.Id, sql.NullInt64{Int64: roleId, Valid: true}); err != nil {
+				s.log.Errorf("Ошибка при установки роли из инвайт-кода: %v", err)
+				return nil, err
+			}
+		}
+	}
+
+	s.log.Debug("Сохранение согласий с документами: ", dto.Email)
+	if err := s.cs.AcceptCurrentTx(ctx, tx, newUser.Id.Int64, dto.AcceptedDocuments); err != nil {
+		return nil, err
+	}
+
+	if err := s.obs.AddTx(ctx, tx, newUser.Id.Int64, &events.UserRegistered{
+		UserId:       newUser.Id.Int64,
+		Email:        newUser.Email,
+		RegisteredAt: newUser.CreatedAt,
+	}); err != nil {
+		return nil, err
+	}
+
 	s.log.Debug("Отправка комита в БД ")
 	if err := s.ur.CommitTx(tx); err != nil {
 		s.log.Errorf("Ошибка при комите транзакции: %v", err)
@@ -101,142 +160,353 @@ func (s *UserService) CreateUser(dto *dto.RegisterDto) (*entity.User, error) {
 	return &newUser, nil
 }
 
+// registrationAllowed проверяет режим регистрации. Сам инвайт-код проверяется в транзакции создания пользователя.
+// Неизвестный режим закрывает регистрацию
+func (s *UserService) registrationAllowed(dto *dto.RegisterDto) bool {
+	switch s.reg.Mode {
+	case config.RegistrationModeOpen:
+		return true
+	case config.RegistrationModeInvite:
+		return dto.InviteCode != ""
+	case config.RegistrationModeDomain:
+		if dto.InviteCode != "" {
+			return true
+		}
+		_, domain, ok := strings.Cut(dto.Email, "@")
+		if !ok {
+			return false
+		}
+		for _, allowed := range s.reg.AllowedDomains {
+			if strings.EqualFold(strings.TrimSpace(allowed), domain) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
 func (s *UserService) HasEmailExists(email string) bool {
-	redisKey := redisutil.GenerateKey(redis.UserEmailKeys, email)
-	if _, ok := s.redis.Get(redisKey); ok {
+	if _, ok := cache.Get[*entity.User](s.byEmail, email); ok {
 		return true
 	}
 	return s.ur.HasEmailExists(email)
 }
 
 func (s *UserService) GetByEmail(email string) (*entity.User, bool) {
-	redisKey := redisutil.GenerateKey(redis.UserEmailKeys, email)
-	jsonDataUser, ok := s.redis.Get(redisKey)
-	if ok {
-		var res entity.User
-		if err := json.Unmarshal([]byte(jsonDataUser), &res); err != nil {
-			s.log.Error("ошибка преобразования json из редис:", err)
-		}
-		return &res, true
-	}
-
-	u, err := s.ur.FindByEmail(email)
+	u, err := cache.GetOrLoad(s.byEmail, email, func() (*entity.User, error) {
+		return s.ur.FindByEmail(email)
+	})
 	if err != nil {
 		s.log.Errorf("email не найден: %v", err)
 		return nil, false
 	}
 
-	s.updateCache(u)
 	return u, true
 }
 
 func (s *UserService) GetByRefreshToken(refreshToken string) (*entity.User, error) {
 	u, err := s.ur.FindByRefreshToken(refreshToken)
 	if err != nil {
-		s.log.Errorf("пользователь с таким токеном не найден: %v", err)
-		return nil, errors.New(errormsg.NotFound)
+		if errors.Is(err, ErrNotFound) {
+			s.log.Debug("пользователь с таким refresh токеном не найден")
+			return nil, err
+		}
+		s.log.Error("ошибка при поиске пользователя по refresh токену: ", err)
+		return nil, err
 	}
 
 	return u, nil
 }
 
 func (s *UserService) GetById(id int64) (*entity.User, error) {
-	redisKey := redisutil.GenerateKey(redis.UserIdKeys, fmt.Sprint(id))
-	if jsonDataUser, ok := s.redis.Get(redisKey); ok {
-		var res entity.User
-		if err := json.Unmarshal([]byte(jsonDataUser), &res); err == nil {
-			return &res, nil
-		}
-	}
-
-	u, err := s.ur.FindById(id)
+	u, err := cache.GetOrLoad(s.byId, fmt.Sprint(id), func() (*entity.User, error) {
+		return s.ur.FindById(id)
+	})
 	if err != nil {
 		s.log.Debugf("пользователь с таким id не найден: %v", err)
-		return nil, errors.New(errormsg.NotFound)
-	}
-
-	if err := s.redis.Put(redisKey, u); err != nil {
-		s.log.Errorf("ошибка при созранении в редис пользователя: %v", err)
+		return nil, err
 	}
 
 	return u, nil
 }
 
 func (s *UserService) SetEmailConfirmed(userId int64, b bool) (*entity.User, error) {
-	updateUser, err := s.ur.SetEmailIsConfirm(userId, b)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.ur.CreateTx()
+	if err != nil {
+		s.log.Errorf("Ошибка при открытии транзакции: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	updateUser, err := s.ur.SetEmailIsConfirmTx(ctx, tx, userId, b)
 	if err != nil {
 		s.log.Errorf("ошибка при обновлении статуса подтверждения email: %v", err)
 		return nil, err
 	}
 
-	s.removeCache(updateUser)
+	if b {
+		if err := s.obs.AddTx(ctx, tx, userId, &events.UserEmailConfirmed{
+			UserId:      userId,
+			Email:       updateUser.Email,
+			ConfirmedAt: time.Now(),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.ur.CommitTx(tx); err != nil {
+		s.log.Errorf("Ошибка при комите транзакции: %v", err)
+		return nil, err
+	}
+
 	s.updateCache(updateUser)
 
 	return updateUser, nil
 }
 
-func (s *UserService) EditPassword(userId int64, newPassword string) error {
-	currentUser, err := s.GetById(userId)
+// SetLocale сохраняет предпочитаемый язык пользователя. Пустая строка сбрасывает выбор
+func (s *UserService) SetLocale(userId int64, locale string) (*entity.User, error) {
+	var value *string
+	if locale != "" {
+		value = &locale
+	}
+
+	u, err := s.ur.SetLocale(userId, value)
 	if err != nil {
-		s.log.Errorf("ошибка при изменении пароля пользователя: %v", err)
-		return errors.New(errormsg.NotFound)
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		s.log.Errorf("ошибка при сохранении языка пользователя: %v", err)
+		return nil, err
+	}
+
+	s.updateCache(u)
+	return u, nil
+}
+
+// DeleteUser удаляет аккаунт. Выход со всех устройств, удаление и событие UserDeleted - одна транзакция
+func (s *UserService) DeleteUser(userId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.ur.CreateTx()
+	if err != nil {
+		s.log.Errorf("Ошибка при открытии транзакции: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	revoked, err := s.ts.logoutAll(ctx, tx, userId)
+	if err != nil {
+		s.log.Errorf("ошибка при выходе со всех устройств перед удалением пользователя: %v", err)
+		return err
 	}
 
-	s.removeCache(currentUser)
+	deleted, err := s.ur.DeleteByIdTx(ctx, tx, userId)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return err
+		}
+		s.log.Errorf("ошибка при удалении пользователя: %v", err)
+		return err
+	}
+
+	if err := s.obs.AddTx(ctx, tx, userId, &events.UserDeleted{
+		UserId:    userId,
+		DeletedAt: time.Now(),
+	}); err != nil {
+		return err
+	}
+
+	if err := s.ts.revokeAccessTokens(userId, revoked); err != nil {
+		return err
+	}
+
+	if err := s.ur.CommitTx(tx); err != nil {
+		s.log.Errorf("Ошибка при комите транзакции: %v", err)
+		return err
+	}
+
+	s.removeCache(deleted)
+	s.rs.removeUserRolesCache(userId)
+
+	return nil
+}
+
+// EditPasswordTx меняет пароль в транзакции вызывающего. Строка пользователя блокируется до конца транзакции,
+// поэтому одновременные смены пароля выполняются по очереди. Кеш сбрасывает вызывающий после комита
+func (s *UserService) EditPasswordTx(ctx context.Context, tx *sqlx.Tx, userId int64, newPassword string) (*entity.User, error) {
+	currentUser, err := s.ur.FindByIdForUpdateTx(ctx, tx, userId)
+	if err != nil {
+		s.log.Errorf("ошибка при изменении пароля пользователя: %v", err)
+		return nil, err
+	}
 
 	currentPassword := currentUser.Password
 	lastPassword := s.hps.GetLastPassword(userId)
 
 	if passencoder.CheckEqualsPassword(newPassword, currentPassword) || passencoder.CheckEqualsPassword(newPassword, lastPassword.OldPassword) {
 		s.log.Debug("Пароль равен текущему или последнему изменненному")
-		return errors.New(errormsg.LastPasswordIsExists)
+		return nil, ErrLastPasswordIsExists
 	}
 
 	newPasswordHash, err := passencoder.PasswordHash(newPassword)
 	if err != nil {
 		s.log.Errorf("ошибка при генерации хеша пароля: %v", err)
-		return err
+		return nil, err
 	}
 
-	currentUser.Password = newPasswordHash
-	currentUser.UpdatedAt = time.Now()
-
-	if err := s.hps.Save(userId, currentPassword); err != nil {
+	if err := s.hps.SaveTx(ctx, tx, userId, currentPassword); err != nil {
 		s.log.Error("ошибка при сохранеии текущего пароля в истории: ", err)
+		return nil, err
 	}
 
-	if err := s.ur.EditPassword(userId, newPasswordHash); err != nil {
+	updated, err := s.ur.EditPasswordTx(ctx, tx, userId, newPasswordHash)
+	if err != nil {
 		s.log.Errorf("ошибка при обновлении пароля: %v", err)
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// userCursor позиция в списке пользователей: значение сортируемой колонки и id последней строки
+type userCursor struct {
+	Value string `json:"v,omitempty"`
+	Id    int64  `json:"id"`
+}
+
+const defaultUsersPageLimit = 20
+
+// Search ищет пользователей по фильтрам с keyset-пагинацией
+func (s *UserService) Search(filter *dto.UserSearchFilter) (*dto.UserPage, error) {
+	params := repositories.UserSearchParams{
+		EmailPrefix:    strings.TrimSpace(filter.Email),
+		EmailConfirmed: filter.EmailConfirmed,
+		Role:           filter.Role,
+		Banned:         filter.Banned,
+		CreatedFrom:    filter.CreatedFrom,
+		CreatedTo:      filter.CreatedTo,
+		SortColumn:     filter.Sort,
+		Desc:           filter.Order == "desc",
+		Limit:          filter.Limit,
+	}
+	if params.SortColumn == "" {
+		params.SortColumn = "id"
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultUsersPageLimit
+	}
+
+	if filter.Cursor != "" {
+		if err := decodeUserCursor(filter.Cursor, &params); err != nil {
+			s.log.Debug("невалидный курсор: ", err)
+			return nil, invalidParam("cursor")
+		}
+	}
+
+	// берем на одну строку больше, чтобы понять, есть ли следующая страница
+	params.Limit++
+	users, err := s.ur.Search(&params)
+	if err != nil {
+		s.log.Errorf("ошибка при поиске пользователей: %v", err)
+		return nil, err
+	}
+	params.Limit--
+
+	page := dto.UserPage{
+		Items: make([]dto.UserDto, 0, len(users)),
+	}
+
+	if len(users) > params.Limit {
+		users = users[:params.Limit]
+		page.NextCursor = encodeUserCursor(params.SortColumn, &users[len(users)-1])
+	}
+
+	for _, u := range users {
+		roles := make([]string, 0)
+		if u.Roles != "" {
+			roles = strings.Split(u.Roles, ",")
+		}
+		page.Items = append(page.Items, dto.UserDto{
+			Id:             u.Id,
+			Email:          u.Email,
+			EmailIsConfirm: u.EmailIsConfirm,
+			Roles:          roles,
+			IsBanned:       u.IsBanned,
+			CreatedAt:      u.CreatedAt,
+			UpdatedAt:      u.UpdatedAt,
+		})
+	}
+
+	return &page, nil
+}
+
+func encodeUserCursor(sortColumn string, last *entity.UserSummary) string {
+	cursor := userCursor{Id: last.Id}
+	switch sortColumn {
+	case "email":
+		cursor.Value = last.Email
+	case "created_at":
+		cursor.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(raw string, params *repositories.UserSearchParams) error {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
 		return err
 	}
-	s.updateCache(currentUser)
+
+	var cursor userCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return err
+	}
+	if cursor.Id <= 0 {
+		return errors.New("пустой id в курсоре")
+	}
+
+	params.AfterId = cursor.Id
+	switch params.SortColumn {
+	case "email":
+		params.AfterValue = cursor.Value
+	case "created_at":
+		t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return err
+		}
+		params.AfterValue = t
+	}
+
 	return nil
 }
 
+// updateCache вызывается после записи в базу. Запись увеличивает версию ключей, поэтому
+// параллельная загрузка, начатая до изменения, уже не перезапишет кеш старыми данными
 func (s *UserService) updateCache(u *entity.User) {
-	rediskey2 := redisutil.GenerateKey(redis.UserEmailKeys, u.Email)
-	redisKey := redisutil.GenerateKey(redis.UserIdKeys, fmt.Sprint(u.Id.Int64))
-	if err := s.redis.Put(redisKey, u); err != nil {
-		s.log.Errorf("ошибка при сохранении в redis при изменении статуса email: %v", err)
+	if err := s.byId.Set(fmt.Sprint(u.Id.Int64), u); err != nil {
+		s.log.Errorf("ошибка при сохранении пользователя в кеш: %v", err)
 	}
 
-	if err := s.redis.Put(rediskey2, u); err != nil {
-		s.log.Errorf("ошибка при сохранении в redis при изменении статуса email: %v", err)
+	if err := s.byEmail.Set(u.Email, u); err != nil {
+		s.log.Errorf("ошибка при сохранении пользователя в кеш: %v", err)
 	}
 }
 
 func (s *UserService) removeCache(u *entity.User) {
-	rediskey2 := redisutil.GenerateKey(redis.UserEmailKeys, u.Email)
-	redisKey := redisutil.GenerateKey(redis.UserIdKeys, fmt.Sprint(u.Id.Int64))
-	if _, ok := s.redis.Get(redisKey); ok {
-		if err := s.redis.Del(redisKey); err != nil {
-			s.log.Debugf("%v ключ не удален из редис: %v", redisKey, err)
-		}
+	if err := s.byId.Invalidate(fmt.Sprint(u.Id.Int64)); err != nil {
+		s.log.Debugf("пользователь %v не удален из кеша: %v", u.Id.Int64, err)
 	}
 
-	if _, ok := s.redis.Get(rediskey2); ok {
-		if err := s.redis.Del(rediskey2); err != nil {
-			s.log.Debugf("%v ключ не удален из редис: %v", rediskey2, err)
-		}
+	if err := s.byEmail.Invalidate(u.Email); err != nil {
+		s.log.Debugf("%v не удален из кеша: %v", u.Email, err)
 	}
 }
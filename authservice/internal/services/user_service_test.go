@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/repositories"
+)
+
+func TestUserCursor(t *testing.T) {
+	last := &entity.UserSummary{
+		Id:        42,
+		Email:     "ivan@mail.ru",
+		CreatedAt: time.Date(2025, 7, 1, 10, 30, 0, 123456000, time.UTC),
+	}
+
+	cases := []struct {
+		sortColumn string
+		wantValue  interface{}
+	}{
+		{"id", nil},
+		{"email", "ivan@mail.ru"},
+		{"created_at", last.CreatedAt},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.sortColumn, func(t *testing.T) {
+			params := repositories.UserSearchParams{SortColumn: tc.sortColumn}
+			if err := decodeUserCursor(encodeUserCursor(tc.sortColumn, last), &params); err != nil {
+				t.Fatal(err)
+			}
+			if params.AfterId != last.Id {
+				t.Errorf("id: ожидалось %d, получено %d", last.Id, params.AfterId)
+			}
+			if params.AfterValue != tc.wantValue {
+				t.Errorf("значение: ожидалось %v, получено %v", tc.wantValue, params.AfterValue)
+			}
+		})
+	}
+}
+
+func TestUserCursorInvalid(t *testing.T) {
+	for _, raw := range []string{"не base64", "bnVsbA", "eyJpZCI6MH0", "eyJ2IjoieCIsImlkIjoxfQ"} {
+		params := repositories.UserSearchParams{SortColumn: "created_at"}
+		if err := decodeUserCursor(raw, &params); err == nil {
+			t.Errorf("курсор %q принят", raw)
+		}
+	}
+}
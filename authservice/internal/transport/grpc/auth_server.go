@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	authv1 "github.com/EddyZe/foodApp/common/api/auth/v1"
+	"github.com/EddyZe/foodApp/common/pkg/apperr"
+	"github.com/EddyZe/foodApp/common/pkg/jwtutil"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// AuthServer реализация authv1.AuthServiceServer поверх тех же сервисов, что и REST API
+type AuthServer struct {
+	authv1.UnimplementedAuthServiceServer
+	log *logrus.Entry
+	us  *services.UserService
+	rs  *services.RoleService
+	bs  *services.BanService
+	ts  *services.TokenService
+}
+
+func NewAuthServer(
+	log *logrus.Entry,
+	us *services.UserService,
+	rs *services.RoleService,
+	bs *services.BanService,
+	ts *services.TokenService,
+) *AuthServer {
+	return &AuthServer{
+		log: log,
+		us:  us,
+		rs:  rs,
+		bs:  bs,
+		ts:  ts,
+	}
+}
+
+func (s *AuthServer) ValidateToken(_ context.Context, req *authv1.ValidateTokenRequest) (*authv1.ValidateTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	claims, ok := jwtutil.ParseToken(req.GetToken(), s.ts.Secret())
+	if !ok || s.ts.IsRevoked(req.GetToken(), claims) {
+		return &authv1.ValidateTokenResponse{Valid: false}, nil
+	}
+
+	return &authv1.ValidateTokenResponse{
+		Valid: true,
+		Claims: &authv1.Claims{
+			Sub:           claims.Sub,
+			Email:         claims.Email,
+			EmailVerified: claims.EmailVerified,
+			Roles:         claims.Role,
+			Permissions:   claims.Permissions,
+			Actor:         claims.Actor,
+			IssuedAt:      timestamppb.New(time.Unix(claims.Iat, 0)),
+			ExpiresAt:     timestamppb.New(time.Unix(claims.Ext, 0)),
+		},
+	}, nil
+}
+
+func (s *AuthServer) GetUserById(_ context.Context, req *authv1.GetUserByIdRequest) (*authv1.GetUserByIdResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	u, err := s.us.GetById(req.GetUserId())
+	if err != nil {
+		return nil, statusError(err, "user not found")
+	}
+
+	return &authv1.GetUserByIdResponse{
+		User: &authv1.User{
+			Id:             u.Id.Int64,
+			Email:          u.Email,
+			EmailConfirmed: u.EmailIsConfirm,
+			CreatedAt:      timestamppb.New(u.CreatedAt),
+			UpdatedAt:      timestamppb.New(u.UpdatedAt),
+		},
+	}, nil
+}
+
+func (s *AuthServer) GetUserRoles(_ context.Context, req *authv1.GetUserRolesRequest) (*authv1.GetUserRolesResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	userRoles := s.rs.GetRoleByUserId(req.GetUserId())
+	res := &authv1.GetUserRolesResponse{
+		Roles:       make([]*authv1.Role, 0, len(userRoles)),
+		Permissions: s.rs.GetPermissionsByRoles(userRoles),
+	}
+	for _, role := range userRoles {
+		res.Roles = append(res.Roles, &authv1.Role{
+			Id:          role.Id.Int64,
+			Name:        role.Name,
+			Description: role.Description,
+		})
+	}
+
+	return res, nil
+}
+
+func (s *AuthServer) IsBanned(_ context.Context, req *authv1.IsBannedRequest) (*authv1.IsBannedResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	// при ошибке базы ответ "не заблокирован" пропустил бы заблокированного пользователя
+	ban, err := s.bs.FindActiveUserBan(req.GetUserId())
+	if err != nil {
+		if apperr.KindOf(err) == apperr.ErrNotFound {
+			return &authv1.IsBannedResponse{Banned: false}, nil
+		}
+		return nil, statusError(err, "ban not found")
+	}
+
+	res := &authv1.IsBannedResponse{
+		Banned:         true,
+		IsForever:      ban.IsForever,
+		ReasonCategory: ban.ReasonCategory,
+		Cause:          ban.Cause,
+	}
+	if !ban.IsForever {
+		res.ExpiredAt = timestamppb.New(ban.ExpiredAt)
+	}
+
+	return res, nil
+}
+
+func (s *AuthServer) RevokeUserSessions(_ context.Context, req *authv1.RevokeUserSessionsRequest) (*authv1.RevokeUserSessionsResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	if err := s.ts.LogoutAll(req.GetUserId()); err != nil {
+		return nil, statusError(err, "failed to revoke sessions")
+	}
+
+	return &authv1.RevokeUserSessionsResponse{}, nil
+}
+
+// statusError gRPC статус по виду ошибки apperr, msg - сообщение для NotFound
+func statusError(err error, msg string) error {
+	switch apperr.KindOf(err) {
+	case apperr.ErrNotFound:
+		return status.Error(codes.NotFound, msg)
+	case apperr.ErrUnavailable:
+		return status.Error(codes.Unavailable, "service unavailable")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
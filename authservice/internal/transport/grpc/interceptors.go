@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+var (
+	grpcRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			// не grpc_server_*: эти имена занимает go-grpc-prometheus
+			Name: "auth_grpc_requests_total",
+			Help: "Total gRPC requests",
+		},
+		[]string{"method", "code"},
+	)
+
+	grpcRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "auth_grpc_request_duration_seconds",
+			Help:    "gRPC request duration",
+			Buckets: []float64{0.005, 0.01, 0.05, 0.1, 0.3, 0.5, 1, 2},
+		},
+		[]string{"method"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(grpcRequestsTotal, grpcRequestDuration)
+}
+
+// MetricsInterceptor считает запросы по методам и кодам ответа
+func MetricsInterceptor() ggrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		grpcRequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		grpcRequestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return resp, err
+	}
+}
+
+// LoggerInterceptor пишет в лог вызов и код ответа
+func LoggerInterceptor(log *logrus.Entry) ggrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if !strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			log.Infoln("gRPC: ", info.FullMethod, status.Code(err))
+		}
+		return resp, err
+	}
+}
+
+// AuthInterceptor пропускает только вызовы со служебным токеном в metadata authorization: Bearer <token>.
+// Проверка здоровья доступна без токена
+func AuthInterceptor(log *logrus.Entry, serviceTokens []string) ggrpc.UnaryServerInterceptor {
+	if len(serviceTokens) == 0 {
+		log.Warn("Служебные токены gRPC не заданы: все вызовы, кроме проверки здоровья, будут отклонены")
+	}
+
+	return func(ctx context.Context, req any, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(ctx, req)
+		}
+
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing metadata")
+		}
+
+		values := md.Get("authorization")
+		if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+			return nil, status.Error(codes.Unauthenticated, "missing service token")
+		}
+
+		token := strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+		for _, serviceToken := range serviceTokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(serviceToken)) == 1 {
+				return handler(ctx, req)
+			}
+		}
+
+		return nil, status.Error(codes.Unauthenticated, "invalid service token")
+	}
+}
@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	interceptor := AuthInterceptor(log, []string{"first", "second"})
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	tests := []struct {
+		name   string
+		method string
+		auth   []string
+		want   codes.Code
+	}{
+		{"без metadata", "/auth.v1.AuthService/GetUser", nil, codes.Unauthenticated},
+		{"без Bearer", "/auth.v1.AuthService/GetUser", []string{"first"}, codes.Unauthenticated},
+		{"чужой токен", "/auth.v1.AuthService/GetUser", []string{"Bearer other"}, codes.Unauthenticated},
+		{"первый токен", "/auth.v1.AuthService/GetUser", []string{"Bearer first"}, codes.OK},
+		{"второй токен", "/auth.v1.AuthService/GetUser", []string{"Bearer second"}, codes.OK},
+		{"проверка здоровья без токена", healthServicePrefix + "Check", nil, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.auth != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.auth[0]))
+			}
+			_, err := interceptor(ctx, nil, &ggrpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthInterceptorWithoutTokens(t *testing.T) {
+	interceptor := AuthInterceptor(logrus.NewEntry(logrus.New()), nil)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "))
+	_, err := interceptor(ctx, nil, &ggrpc.UnaryServerInfo{FullMethod: "/auth.v1.AuthService/GetUser"},
+		func(ctx context.Context, req any) (any, error) { return "ok", nil })
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("code = %v, want Unauthenticated", status.Code(err))
+	}
+}
@@ -0,0 +1,123 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/EddyZe/foodApp/authservice/internal/config"
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	authv1 "github.com/EddyZe/foodApp/common/api/auth/v1"
+	"github.com/sirupsen/logrus"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ErrTLSNotConfigured не заданы сертификат и ключ, а работа без TLS явно не разрешена
+var ErrTLSNotConfigured = errors.New("для gRPC не задан TLS сертификат: укажите AUTH_GRPC_TLS_CERT_FILE и AUTH_GRPC_TLS_KEY_FILE или разрешите AUTH_GRPC_INSECURE")
+
+// healthPingTimeout сколько ждать ответа postgres при обновлении статуса здоровья
+const healthPingTimeout = 2 * time.Second
+
+// dbPinger проверка соединения с postgres
+type dbPinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Server gRPC сервер, статус здоровья которого следует за доступностью postgres
+type Server struct {
+	*ggrpc.Server
+	log    *logrus.Entry
+	db     dbPinger
+	health *health.Server
+	stop   chan struct{}
+}
+
+// New gRPC сервер с AuthService и стандартной проверкой здоровья. Без TLS сервер создается, только если это явно разрешено
+func New(
+	logger *logrus.Entry,
+	cfg *config.GrpcConfig,
+	db dbPinger,
+	us *services.UserService,
+	rs *services.RoleService,
+	bs *services.BanService,
+	ts *services.TokenService,
+) (*Server, error) {
+	opts := []ggrpc.ServerOption{
+		ggrpc.ChainUnaryInterceptor(
+			MetricsInterceptor(),
+			LoggerInterceptor(logger),
+			AuthInterceptor(logger, cfg.ServiceTokens),
+		),
+	}
+
+	switch {
+	case cfg.TLSCertFile != "" && cfg.TLSKeyFile != "":
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, ggrpc.Creds(creds))
+	case cfg.Insecure:
+		logger.Warn("gRPC сервер запущен без TLS: служебные токены передаются открытым текстом")
+	default:
+		return nil, ErrTLSNotConfigured
+	}
+
+	s := &Server{
+		Server: ggrpc.NewServer(opts...),
+		log:    logger,
+		db:     db,
+		health: health.NewServer(),
+		stop:   make(chan struct{}),
+	}
+
+	authv1.RegisterAuthServiceServer(s.Server, NewAuthServer(logger, us, rs, bs, ts))
+	grpc_health_v1.RegisterHealthServer(s.Server, s.health)
+
+	s.checkHealth()
+	go s.watchHealth(time.Duration(cfg.HealthCheckSeconds) * time.Second)
+
+	return s, nil
+}
+
+// GracefulStop останавливает проверку здоровья и дожидается завершения текущих вызовов
+func (s *Server) GracefulStop() {
+	close(s.stop)
+	s.health.Shutdown()
+	s.Server.GracefulStop()
+}
+
+func (s *Server) watchHealth(interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.checkHealth()
+		}
+	}
+}
+
+// checkHealth без postgres сервис не обслуживает запросы. Недоступность redis, как и в /ready, на статус не влияет
+func (s *Server) checkHealth() {
+	ctx, cancel := context.WithTimeout(context.Background(), healthPingTimeout)
+	defer cancel()
+
+	status := grpc_health_v1.HealthCheckResponse_SERVING
+	if err := s.db.PingContext(ctx); err != nil {
+		s.log.Warn("gRPC health: postgres недоступен: ", err)
+		status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(authv1.AuthService_ServiceDesc.ServiceName, status)
+}
@@ -0,0 +1,60 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/EddyZe/foodApp/authservice/internal/config"
+	authv1 "github.com/EddyZe/foodApp/common/api/auth/v1"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type fakePinger struct {
+	err error
+}
+
+func (p *fakePinger) PingContext(_ context.Context) error {
+	return p.err
+}
+
+func TestNewRequiresTLS(t *testing.T) {
+	_, err := New(logrus.NewEntry(logrus.New()), &config.GrpcConfig{}, &fakePinger{}, nil, nil, nil, nil)
+	if !errors.Is(err, ErrTLSNotConfigured) {
+		t.Fatalf("err = %v, want ErrTLSNotConfigured", err)
+	}
+}
+
+func TestHealthFollowsDatabase(t *testing.T) {
+	db := &fakePinger{}
+	s, err := New(logrus.NewEntry(logrus.New()), &config.GrpcConfig{Insecure: true, HealthCheckSeconds: 3600},
+		db, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.GracefulStop()
+
+	check := func(want grpc_health_v1.HealthCheckResponse_ServingStatus) {
+		t.Helper()
+		for _, service := range []string{"", authv1.AuthService_ServiceDesc.ServiceName} {
+			resp, err := s.health.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != want {
+				t.Fatalf("service %q: status = %v, want %v", service, resp.Status, want)
+			}
+		}
+	}
+
+	check(grpc_health_v1.HealthCheckResponse_SERVING)
+
+	db.err = errors.New("connection refused")
+	s.checkHealth()
+	check(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	db.err = nil
+	s.checkHealth()
+	check(grpc_health_v1.HealthCheckResponse_SERVING)
+}
@@ -0,0 +1,383 @@
+package rest
+
+import (
+	"errors"
+	authDto "github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/auditaction"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/EddyZe/foodApp/common/pkg/validate"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type AdminRoleHandler struct {
+	log  *logrus.Entry
+	rs   *services.RoleService
+	us   *services.UserService
+	as   *services.AuditService
+	lms  *localizer.LocalizeService
+	errs *responseutil.ErrorRegistry
+}
+
+func NewAdminRoleHandler(
+	log *logrus.Entry,
+	rs *services.RoleService,
+	us *services.UserService,
+	as *services.AuditService,
+	lms *localizer.LocalizeService,
+) *AdminRoleHandler {
+	return &AdminRoleHandler{
+		log:  log,
+		rs:   rs,
+		us:   us,
+		as:   as,
+		lms:  lms,
+		errs: newErrorRegistry(log, lms),
+	}
+}
+
+// GetRoles список ролей с их разрешениями
+func (h *AdminRoleHandler) GetRoles(c *gin.Context) {
+	roles := h.rs.GetAll()
+	res := make([]authDto.RoleWithPermissions, 0, len(roles))
+	for _, role := range roles {
+		permissions := make([]string, 0)
+		for _, p := range h.rs.GetPermissionsByRoleId(role.Id.Int64) {
+			permissions = append(permissions, p.Name)
+		}
+
+		res = append(res, authDto.RoleWithPermissions{
+			Id:          role.Id.Int64,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: permissions,
+		})
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, res)
+}
+
+// CreateRole создание роли
+func (h *AdminRoleHandler) CreateRole(c *gin.Context) {
+	var roleDto authDto.RoleDto
+
+	if verr, ok := validate.IsValidBody(c, &roleDto, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+		return
+	}
+
+	role, err := h.rs.CreateRole(roleDto.Name, roleDto.Description)
+	if err != nil {
+		h.roleErrorResponse(c, err)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.RoleCreate, 0, map[string]interface{}{
+		"role_id": role.Id.Int64,
+		"name":    role.Name,
+	})
+
+	responseutil.SuccessResponse(c, http.StatusCreated, role)
+}
+
+// UpdateRole изменение роли
+func (h *AdminRoleHandler) UpdateRole(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	id, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	var roleDto authDto.RoleDto
+	if verr, ok := validate.IsValidBody(c, &roleDto, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+		return
+	}
+
+	role, old, err := h.rs.UpdateRole(id, roleDto.Name, roleDto.Description)
+	if err != nil {
+		h.roleErrorResponse(c, err)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.RoleUpdate, 0, map[string]interface{}{
+		"role_id":         role.Id.Int64,
+		"name":            role.Name,
+		"description":     role.Description,
+		"old_name":        old.Name,
+		"old_description": old.Description,
+	})
+
+	responseutil.SuccessResponse(c, http.StatusOK, role)
+}
+
+// DeleteRole удаление роли
+func (h *AdminRoleHandler) DeleteRole(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	id, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.rs.DeleteRole(id); err != nil {
+		h.roleErrorResponse(c, err)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.RoleDelete, 0, map[string]interface{}{
+		"role_id": id,
+	})
+
+	responseutil.SuccessResponse(c, http.StatusOK, nil)
+}
+
+// GetPermissions список всех разрешений
+func (h *AdminRoleHandler) GetPermissions(c *gin.Context) {
+	responseutil.SuccessResponse(c, http.StatusOK, h.rs.GetAllPermissions())
+}
+
+// CreatePermission создание разрешения
+func (h *AdminRoleHandler) CreatePermission(c *gin.Context) {
+	var permissionDto authDto.PermissionDto
+
+	if verr, ok := validate.IsValidBody(c, &permissionDto, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+		return
+	}
+
+	permission, err := h.rs.CreatePermission(permissionDto.Name, permissionDto.Description)
+	if err != nil {
+		h.permissionErrorResponse(c, err)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.PermissionCreate, 0, map[string]interface{}{
+		"permission_id": permission.Id.Int64,
+		"name":          permission.Name,
+	})
+
+	responseutil.SuccessResponse(c, http.StatusCreated, permission)
+}
+
+// UpdatePermission изменение разрешения
+func (h *AdminRoleHandler) UpdatePermission(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	id, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	var permissionDto authDto.PermissionDto
+	if verr, ok := validate.IsValidBody(c, &permissionDto, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+		return
+	}
+
+	permission, old, err := h.rs.UpdatePermission(id, permissionDto.Name, permissionDto.Description)
+	if err != nil {
+		h.permissionErrorResponse(c, err)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.PermissionUpdate, 0, map[string]interface{}{
+		"permission_id":   permission.Id.Int64,
+		"name":            permission.Name,
+		"description":     permission.Description,
+		"old_name":        old.Name,
+		"old_description": old.Description,
+	})
+
+	responseutil.SuccessResponse(c, http.StatusOK, permission)
+}
+
+// DeletePermission удаление разрешения
+func (h *AdminRoleHandler) DeletePermission(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	id, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.rs.DeletePermission(id); err != nil {
+		h.permissionErrorResponse(c, err)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.PermissionDelete, 0, map[string]interface{}{
+		"permission_id": id,
+	})
+
+	responseutil.SuccessResponse(c, http.StatusOK, nil)
+}
+
+// AttachPermission добавление разрешения к роли
+func (h *AdminRoleHandler) AttachPermission(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	roleId, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+	permissionId, ok := idParam(c, h.lms, lang, "permissionId")
+	if !ok {
+		return
+	}
+
+	if err := h.rs.AttachPermission(roleId, permissionId); err != nil {
+		h.rolePermissionErrorResponse(c, err, roleId)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.PermissionAttach, 0, map[string]interface{}{
+		"role_id":       roleId,
+		"permission_id": permissionId,
+	})
+
+	responseutil.SuccessResponse(c, http.StatusOK, nil)
+}
+
+// DetachPermission удаление разрешения у роли
+func (h *AdminRoleHandler) DetachPermission(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	roleId, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+	permissionId, ok := idParam(c, h.lms, lang, "permissionId")
+	if !ok {
+		return
+	}
+
+	if err := h.rs.DetachPermission(roleId, permissionId); err != nil {
+		h.rolePermissionErrorResponse(c, err, roleId)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.PermissionDetach, 0, map[string]interface{}{
+		"role_id":       roleId,
+		"permission_id": permissionId,
+	})
+
+	responseutil.SuccessResponse(c, http.StatusOK, nil)
+}
+
+// GetUserRoles роли пользователя
+func (h *AdminRoleHandler) GetUserRoles(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	userId, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	if !h.userExists(c, userId, lang) {
+		return
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, h.rs.GetRoleByUserId(userId))
+}
+
+// GrantRole выдача роли пользователю
+func (h *AdminRoleHandler) GrantRole(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	userId, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+	roleId, ok := idParam(c, h.lms, lang, "roleId")
+	if !ok {
+		return
+	}
+
+	if !h.userExists(c, userId, lang) {
+		return
+	}
+
+	if err := h.rs.GrantRole(userId, roleId); err != nil {
+		h.errs.
+			WithMessage(services.ErrNotFound, localizer.RoleNotFound, "Role not found").
+			WithMessage(services.ErrIsExists, localizer.UserRoleIsExists, "The user already has this role").
+			Respond(c, err, nil)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.UserRoleGrant, userId, map[string]interface{}{
+		"role_id": roleId,
+	})
+
+	responseutil.SuccessResponse(c, http.StatusOK, h.rs.GetRoleByUserId(userId))
+}
+
+// RevokeRole снятие роли с пользователя
+func (h *AdminRoleHandler) RevokeRole(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	userId, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+	roleId, ok := idParam(c, h.lms, lang, "roleId")
+	if !ok {
+		return
+	}
+
+	if !h.userExists(c, userId, lang) {
+		return
+	}
+
+	var actorId int64
+	if claims, ok := currentClaims(c); ok {
+		actorId = claims.Sub
+	}
+
+	if err := h.rs.RevokeRole(actorId, userId, roleId); err != nil {
+		h.roleErrorResponse(c, err)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.UserRoleRevoke, userId, map[string]interface{}{
+		"role_id": roleId,
+	})
+
+	responseutil.SuccessResponse(c, http.StatusOK, h.rs.GetRoleByUserId(userId))
+}
+
+func (h *AdminRoleHandler) userExists(c *gin.Context, userId int64, lang string) bool {
+	if _, err := h.us.GetById(userId); err != nil {
+		msg := h.lms.GetMessage(
+			localizer.UserNotFound,
+			lang,
+			"User not found",
+			nil,
+		)
+		responseutil.ErrorResponse(c, http.StatusNotFound, errormsg.NotFound, msg)
+		return false
+	}
+	return true
+}
+
+// rolePermissionErrorResponse разбирает, что именно не найдено: роль или разрешение
+func (h *AdminRoleHandler) rolePermissionErrorResponse(c *gin.Context, err error, roleId int64) {
+	if errors.Is(err, services.ErrNotFound) {
+		if _, roleErr := h.rs.GetById(roleId); roleErr == nil {
+			h.permissionErrorResponse(c, err)
+			return
+		}
+	}
+	h.roleErrorResponse(c, err)
+}
+
+func (h *AdminRoleHandler) roleErrorResponse(c *gin.Context, err error) {
+	h.errs.
+		WithMessage(services.ErrNotFound, localizer.RoleNotFound, "Role not found").
+		WithMessage(services.ErrIsExists, localizer.RoleIsExists, "A role with this name already exists").
+		Respond(c, err, nil)
+}
+
+func (h *AdminRoleHandler) permissionErrorResponse(c *gin.Context, err error) {
+	h.errs.
+		WithMessage(services.ErrNotFound, localizer.PermissionNotFound, "Permission not found").
+		WithMessage(services.ErrIsExists, localizer.PermissionIsExists, "A permission with this name already exists").
+		Respond(c, err, nil)
+}
@@ -0,0 +1,208 @@
+package rest
+
+import (
+	authDto "github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/auditaction"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/authservice/internal/util/stringutils"
+	"github.com/EddyZe/foodApp/common/domain/models"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/EddyZe/foodApp/common/pkg/roles"
+	"github.com/EddyZe/foodApp/common/pkg/validate"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"slices"
+)
+
+const userDetailAuditLimit = 20
+
+type AdminUserHandler struct {
+	log  *logrus.Entry
+	us   *services.UserService
+	rs   *services.RoleService
+	ts   *services.TokenService
+	bs   *services.BanService
+	as   *services.AuditService
+	cs   *services.ConsentService
+	lms  *localizer.LocalizeService
+	errs *responseutil.ErrorRegistry
+}
+
+func NewAdminUserHandler(
+	log *logrus.Entry,
+	us *services.UserService,
+	rs *services.RoleService,
+	ts *services.TokenService,
+	bs *services.BanService,
+	as *services.AuditService,
+	cs *services.ConsentService,
+	lms *localizer.LocalizeService,
+) *AdminUserHandler {
+	return &AdminUserHandler{
+		log:  log,
+		us:   us,
+		rs:   rs,
+		ts:   ts,
+		bs:   bs,
+		as:   as,
+		cs:   cs,
+		lms:  lms,
+		errs: newErrorRegistry(log, lms),
+	}
+}
+
+// GetUsers список пользователей с поиском, фильтрами и keyset-пагинацией
+func (h *AdminUserHandler) GetUsers(c *gin.Context) {
+	var filter authDto.UserSearchFilter
+
+	if verr, ok := validate.IsValidQuery(c, &filter, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidParam, verr.Message, verr.Details()...)
+		return
+	}
+
+	page, err := h.us.Search(&filter)
+	if err != nil {
+		h.errs.Respond(c, err, nil)
+		return
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, page)
+}
+
+// GetUser карточка пользователя: роли, активные сессии, текущая блокировка, согласия и последние события аудита
+func (h *AdminUserHandler) GetUser(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	userId, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	u, err := h.us.GetById(userId)
+	if err != nil {
+		msg := h.lms.GetMessage(
+			localizer.UserNotFound,
+			lang,
+			"User not found",
+			nil,
+		)
+		responseutil.ErrorResponse(c, http.StatusNotFound, errormsg.NotFound, msg)
+		return
+	}
+
+	userRoles := h.rs.GetRoleByUserId(userId)
+	ban, isBanned := h.bs.GetActiveUserBan(userId)
+
+	roleNames := stringutils.RoleMapString(userRoles)
+	if roleNames == nil {
+		roleNames = make([]string, 0)
+	}
+
+	sessions := make([]authDto.SessionDto, 0)
+	for _, token := range h.ts.GetActiveSessions(userId) {
+		sessions = append(sessions, authDto.SessionDto{
+			Id:        token.Id.Int64,
+			IssueAt:   token.IssueAt,
+			ExpiredAt: token.ExpiredAt,
+		})
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, &authDto.AdminUserDetail{
+		User: authDto.UserDto{
+			Id:             u.Id.Int64,
+			Email:          u.Email,
+			EmailIsConfirm: u.EmailIsConfirm,
+			Roles:          roleNames,
+			IsBanned:       isBanned,
+			CreatedAt:      u.CreatedAt,
+			UpdatedAt:      u.UpdatedAt,
+		},
+		Roles:       userRoles,
+		Sessions:    sessions,
+		Ban:         ban,
+		AuditEvents: h.as.GetByUserId(userId, userDetailAuditLimit),
+		Consents:    h.cs.GetUserConsents(userId),
+	})
+}
+
+// Impersonate выдает администратору короткоживущий токен от имени пользователя. Администраторов имперсонировать нельзя
+func (h *AdminUserHandler) Impersonate(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	userId, ok := idParam(c, h.lms, lang, "userId")
+	if !ok {
+		return
+	}
+
+	claims, ok := currentClaims(c)
+	if !ok {
+		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+		return
+	}
+
+	u, err := h.us.GetById(userId)
+	if err != nil {
+		msg := h.lms.GetMessage(
+			localizer.UserNotFound,
+			lang,
+			"User not found",
+			nil,
+		)
+		responseutil.ErrorResponse(c, http.StatusNotFound, errormsg.NotFound, msg)
+		return
+	}
+
+	userRoles := h.rs.GetRoleByUserId(userId)
+	userPermissions := h.rs.GetPermissionsByRoles(userRoles)
+	_, isBanned := h.bs.GetActiveUserBan(userId)
+	if !impersonationAllowed(claims, userId, userRoles, userPermissions, isBanned) {
+		msg := h.lms.GetMessage(
+			localizer.ImpersonationNotAllowed,
+			lang,
+			"This user cannot be impersonated",
+			nil,
+		)
+		responseutil.ErrorResponse(c, http.StatusForbidden, errormsg.ImpersonationNotAllowed, msg)
+		return
+	}
+
+	token, err := h.ts.GenerateImpersonationToken(u, userRoles, claims.Sub)
+	if err != nil {
+		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "Server Error")
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.ImpersonationStart, userId, map[string]interface{}{
+		"access_token_id": token.Id.Int64,
+		"expired_at":      token.ExpiredAt,
+	})
+	responseutil.SuccessResponse(c, http.StatusOK, &authDto.ImpersonationTokenDto{
+		AccessToken: token.Token,
+		ExpiresAt:   token.ExpiredAt,
+		UserId:      userId,
+		ActorId:     claims.Sub,
+	})
+}
+
+// impersonationAllowed имперсонировать нельзя себя, администраторов и заблокированных пользователей,
+// а также начинать имперсонацию из сессии имперсонации. Токен имперсонации несет разрешения пользователя,
+// поэтому у пользователя не должно быть разрешений, которых нет у самого администратора
+func impersonationAllowed(claims *models.JwtClaims, userId int64, userRoles []entity.Role, userPermissions []string, isBanned bool) bool {
+	return !claims.IsImpersonated() &&
+		userId != claims.Sub &&
+		!isBanned &&
+		!slices.Contains(stringutils.RoleMapString(userRoles), roles.Admin) &&
+		permissionsSubset(userPermissions, claims.Permissions)
+}
+
+// permissionsSubset все разрешения из sub есть в set
+func permissionsSubset(sub, set []string) bool {
+	for _, p := range sub {
+		if !slices.Contains(set, p) {
+			return false
+		}
+	}
+	return true
+}
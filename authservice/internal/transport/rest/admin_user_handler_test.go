@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/common/domain/models"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/permissions"
+	"github.com/EddyZe/foodApp/common/pkg/roles"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestAdminUserRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	log := logrus.NewEntry(logrus.New())
+	lms := localizer.NewLocalizeService(log, "../../../locales")
+	h := NewAdminUserHandler(log, nil, nil, nil, nil, nil, nil, lms)
+
+	r := gin.New()
+	r.GET("/admin/users", h.GetUsers)
+	r.GET("/admin/users/:id", h.GetUser)
+	return r
+}
+
+// Невалидные запросы отклоняются до обращения к сервисам
+func TestAdminUserHandlerInvalidRequest(t *testing.T) {
+	r := newTestAdminUserRouter()
+
+	for _, url := range []string{
+		"/admin/users?limit=101",
+		"/admin/users?sort=password",
+		"/admin/users?order=up",
+		"/admin/users?banned=maybe",
+		"/admin/users?created_from=yesterday",
+		"/admin/users/abc",
+		"/admin/users/-1",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: ожидался код 400, получен %d", url, w.Code)
+			continue
+		}
+		var body struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+		if body.Error.Code != errormsg.InvalidParam {
+			t.Errorf("%s: ожидалась ошибка %s, получена %s", url, errormsg.InvalidParam, body.Error.Code)
+		}
+	}
+}
+
+func TestImpersonationAllowed(t *testing.T) {
+	admin := &models.JwtClaims{Sub: 1, Permissions: []string{permissions.UsersImpersonate, permissions.UsersRead}}
+	userRoles := []entity.Role{{Name: roles.User}}
+	adminRoles := []entity.Role{{Name: roles.User}, {Name: roles.Admin}}
+
+	cases := []struct {
+		name            string
+		claims          *models.JwtClaims
+		userId          int64
+		userRoles       []entity.Role
+		userPermissions []string
+		isBanned        bool
+		want            bool
+	}{
+		{"обычный пользователь", admin, 2, userRoles, nil, false, true},
+		{"разрешения есть у администратора", admin, 2, userRoles, []string{permissions.UsersRead}, false, true},
+		{"разрешения шире, чем у администратора", admin, 2, userRoles, []string{permissions.RolesManage}, false, false},
+		{"заблокированный пользователь", admin, 2, userRoles, nil, true, false},
+		{"администратор", admin, 2, adminRoles, nil, false, false},
+		{"сам себя", admin, 1, userRoles, nil, false, false},
+		{"из сессии имперсонации", &models.JwtClaims{Sub: 3, Actor: 1}, 2, userRoles, nil, false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := impersonationAllowed(tc.claims, tc.userId, tc.userRoles, tc.userPermissions, tc.isBanned); got != tc.want {
+				t.Errorf("ожидалось %v, получено %v", tc.want, got)
+			}
+		})
+	}
+}
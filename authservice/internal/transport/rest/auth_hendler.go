@@ -1,9 +1,11 @@
 package rest
 
 import (
+	"errors"
 	authDto "github.com/EddyZe/foodApp/authservice/internal/domain/dto"
 	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
 	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/auditaction"
 	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
 	"github.com/EddyZe/foodApp/authservice/internal/util/passencoder"
 	"github.com/EddyZe/foodApp/authservice/internal/util/stringutils"
@@ -14,18 +16,24 @@ import (
 	"github.com/EddyZe/foodApp/common/pkg/validate"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"math"
 	"net/http"
 	"strings"
 	"time"
 )
 
+const dataExportAuditLimit = 1000
+
 type AuthHandler struct {
-	us  *services.UserService
-	ts  *services.TokenService
-	rs  *services.RoleService
-	log *logrus.Entry
-	bs  *services.BanService
-	lms *localizer.LocalizeService
+	us   *services.UserService
+	ts   *services.TokenService
+	rs   *services.RoleService
+	log  *logrus.Entry
+	bs   *services.BanService
+	as   *services.AuditService
+	cs   *services.ConsentService
+	lms  *localizer.LocalizeService
+	errs *responseutil.ErrorRegistry
 }
 
 func NewAuthHandler(
@@ -34,15 +42,20 @@ func NewAuthHandler(
 	ts *services.TokenService,
 	rs *services.RoleService,
 	bs *services.BanService,
+	as *services.AuditService,
+	cs *services.ConsentService,
 	lms *localizer.LocalizeService,
 ) *AuthHandler {
 	return &AuthHandler{
-		us:  us,
-		log: log,
-		ts:  ts,
-		rs:  rs,
-		bs:  bs,
-		lms: lms,
+		us:   us,
+		log:  log,
+		ts:   ts,
+		rs:   rs,
+		bs:   bs,
+		as:   as,
+		cs:   cs,
+		lms:  lms,
+		errs: newErrorRegistry(log, lms),
 	}
 }
 
@@ -54,38 +67,34 @@ func (h *AuthHandler) Ping(c *gin.Context) {
 // Registry регистрация пользователя
 func (h *AuthHandler) Registry(c *gin.Context) {
 	var registerDto authDto.RegisterDto
-	lang := c.GetHeader("Accept-Language")
+	lang := localizer.FromContext(c)
 
-	if msg, ok := validate.IsValidBody(c, &registerDto, h.lms); !ok {
-		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, msg)
+	if verr, ok := validate.IsValidBody(c, &registerDto, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
 		return
 	}
 
 	user, err := h.us.CreateUser(&registerDto)
 	if err != nil {
-		if err.Error() == errormsg.IsExists {
-			msg := h.lms.GetMessage(
-				localizer.UserIsExists,
-				lang,
-				"The user already exists",
-				nil,
-			)
-			responseutil.ErrorResponse(c, http.StatusBadRequest, err.Error(), msg)
+		var consentErr *services.ConsentRequiredError
+		if errors.As(err, &consentErr) {
+			h.consentRequiredResponse(c, consentErr, lang)
 		} else {
-			h.log.Error(err)
-			responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "Server Error")
+			h.errs.
+				With(services.ErrIsExists, responseutil.ErrorSpec{
+					Status:    http.StatusBadRequest,
+					Code:      errormsg.IsExists,
+					MessageId: localizer.UserIsExists,
+					Default:   "The user already exists",
+				}).
+				Respond(c, err, nil)
 		}
 
 		return
 	}
 
 	userRoles := h.rs.GetRoleByUserId(user.Id.Int64)
-	token, err := h.ts.GenerateJwt(jwtutil.GenerateClaims(&models.JwtClaims{
-		Email:         user.Email,
-		EmailVerified: user.EmailIsConfirm,
-		Role:          stringutils.RoleMapString(userRoles),
-		Sub:           user.Id.Int64,
-	}))
+	token, err := h.ts.GenerateJwtByUser(user, userRoles)
 	if err != nil {
 		h.log.Error(err)
 	}
@@ -110,10 +119,10 @@ func (h *AuthHandler) Registry(c *gin.Context) {
 // Login авторизирует пользователя
 func (h *AuthHandler) Login(c *gin.Context) {
 	var loginDto authDto.LoginDto
-	lang := c.GetHeader("Accept-Language")
+	lang := localizer.FromContext(c)
 
-	if msg, ok := validate.IsValidBody(c, &loginDto, h.lms); !ok {
-		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, msg)
+	if verr, ok := validate.IsValidBody(c, &loginDto, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
 		return
 	}
 
@@ -140,6 +149,16 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if err := h.cs.AcceptPending(u.Id.Int64, loginDto.AcceptedDocuments); err != nil {
+		var consentErr *services.ConsentRequiredError
+		if errors.As(err, &consentErr) {
+			h.consentRequiredResponse(c, consentErr, lang)
+			return
+		}
+		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "Server Error")
+		return
+	}
+
 	userRoles := h.rs.GetRoleByUserId(u.Id.Int64)
 
 	token, err := h.ts.GenerateJwtByUser(u, userRoles)
@@ -167,7 +186,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 // Refresh заменяет авторизационные токены
 func (h *AuthHandler) Refresh(c *gin.Context) {
-	lang := c.GetHeader("Accept-Language")
+	lang := localizer.FromContext(c)
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
 		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
@@ -189,8 +208,7 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 
 	u, err := h.us.GetByRefreshToken(token)
 	if err != nil {
-		h.log.Error(err)
-		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+		h.refreshErrorResponse(c, err, lang)
 		return
 	}
 
@@ -199,17 +217,21 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
+	if err := h.cs.RequireAccepted(u.Id.Int64); err != nil {
+		var consentErr *services.ConsentRequiredError
+		if errors.As(err, &consentErr) {
+			h.consentRequiredResponse(c, consentErr, lang)
+			return
+		}
+		h.errs.Respond(c, err, nil)
+		return
+	}
+
 	userRoles := h.rs.GetRoleByUserId(u.Id.Int64)
 
-	access, refreshToken, err := h.ts.ReplaceTokens(token, jwtutil.GenerateClaims(&models.JwtClaims{
-		Email:         u.Email,
-		EmailVerified: u.EmailIsConfirm,
-		Role:          stringutils.RoleMapString(userRoles),
-		Sub:           u.Id.Int64,
-	}))
+	access, refreshToken, err := h.ts.ReplaceTokens(token, h.ts.GenerateClaimsByUser(u, userRoles))
 	if err != nil {
-		h.log.Error(err)
-		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "Server Error")
+		h.refreshErrorResponse(c, err, lang)
 		return
 	}
 
@@ -221,15 +243,35 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	})
 }
 
+// refreshErrorResponse токен, которого нет в базе (отозван или уже заменен), - 401,
+// остальные ошибки (например, недоступность базы) отдаются по реестру, чтобы клиент не терял сессию
+func (h *AuthHandler) refreshErrorResponse(c *gin.Context, err error, lang string) {
+	if errors.Is(err, services.ErrNotFound) {
+		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+		return
+	}
+	h.errs.Respond(c, err, nil)
+}
+
 // Logout удаляет токены
 func (h *AuthHandler) Logout(c *gin.Context) {
-	lang := c.GetHeader("Accept-Language")
+	lang := localizer.FromContext(c)
 	token, ok := jwtutil.ExtractBearerTokenHeader(c)
 	if !ok {
 		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
 		return
 	}
 
+	if claims, ok := currentClaims(c); ok && claims.IsImpersonated() {
+		if err := h.ts.EndImpersonation(token, time.Unix(claims.Ext, 0)); err != nil {
+			h.errs.Respond(c, err, nil)
+			return
+		}
+		recordAudit(h.as, c, auditaction.ImpersonationEnd, claims.Sub, nil)
+		responseutil.SuccessResponse(c, http.StatusOK, nil)
+		return
+	}
+
 	if err := h.ts.Logout(token); err != nil {
 		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
 		return
@@ -241,7 +283,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 // LogoutAll удаляет все токены пользователя
 func (h *AuthHandler) LogoutAll(c *gin.Context) {
 
-	lang := c.GetHeader("Accept-Language")
+	lang := localizer.FromContext(c)
 	claims, ok := c.Get("claims")
 	if !ok {
 		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
@@ -256,14 +298,180 @@ func (h *AuthHandler) LogoutAll(c *gin.Context) {
 	userId := claimsMap.Sub
 
 	if err := h.ts.LogoutAll(userId); err != nil {
-		h.log.Error(err)
-		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "Server Error")
+		h.errs.Respond(c, err, nil)
+		return
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, nil)
+}
+
+// DeleteAccount удаляет аккаунт текущего пользователя после подтверждения паролем
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	var req authDto.DeleteAccount
+
+	if verr, ok := validate.IsValidBody(c, &req, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+		return
+	}
+
+	claims, ok := currentClaims(c)
+	if !ok {
+		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+		return
+	}
+
+	u, err := h.us.GetById(claims.Sub)
+	if err != nil || !passencoder.CheckEqualsPassword(req.Password, u.Password) {
+		msg := h.lms.GetMessage(
+			localizer.InvalidEmailOrPassword,
+			lang,
+			"Invalid email or password",
+			nil,
+		)
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidEmailOrPassword, msg)
 		return
 	}
 
+	if err := h.us.DeleteUser(u.Id.Int64); err != nil {
+		h.errs.Respond(c, err, nil)
+		return
+	}
+
+	// пользователя уже нет, поэтому ссылки на него в журнале пустые, а id и email - в деталях
+	h.as.Record(auditaction.AccountDelete, 0, 0, c.ClientIP(), c.Request.UserAgent(), map[string]interface{}{
+		"user_id": u.Id.Int64,
+		"email":   u.Email,
+	})
+
 	responseutil.SuccessResponse(c, http.StatusOK, nil)
 }
 
+// ExportData выгрузка данных пользователя: профиль, сессии, блокировки, согласия и журнал аудита
+func (h *AuthHandler) ExportData(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	claims, ok := currentClaims(c)
+	if !ok {
+		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+		return
+	}
+
+	u, err := h.us.GetById(claims.Sub)
+	if err != nil {
+		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+		return
+	}
+
+	roleNames := stringutils.RoleMapString(h.rs.GetRoleByUserId(u.Id.Int64))
+	if roleNames == nil {
+		roleNames = make([]string, 0)
+	}
+	_, isBanned := h.isBan(u.Id.Int64)
+
+	sessions := make([]authDto.SessionDto, 0)
+	for _, token := range h.ts.GetActiveSessions(u.Id.Int64) {
+		sessions = append(sessions, authDto.SessionDto{
+			Id:        token.Id.Int64,
+			IssueAt:   token.IssueAt,
+			ExpiredAt: token.ExpiredAt,
+		})
+	}
+
+	bans := make([]authDto.BanDto, 0)
+	for _, ban := range h.bs.GetBanHistory(u.Id.Int64) {
+		bans = append(bans, toBanDto(&ban))
+	}
+
+	recordAudit(h.as, c, auditaction.DataExport, u.Id.Int64, nil)
+	responseutil.SuccessResponse(c, http.StatusOK, &authDto.UserDataExport{
+		User: authDto.UserDto{
+			Id:             u.Id.Int64,
+			Email:          u.Email,
+			EmailIsConfirm: u.EmailIsConfirm,
+			Roles:          roleNames,
+			IsBanned:       isBanned,
+			CreatedAt:      u.CreatedAt,
+			UpdatedAt:      u.UpdatedAt,
+		},
+		Sessions:    sessions,
+		Bans:        bans,
+		Consents:    h.cs.GetUserConsents(u.Id.Int64),
+		AuditEvents: h.as.GetByUserId(u.Id.Int64, dataExportAuditLimit),
+		ExportedAt:  time.Now(),
+	})
+}
+
+// GetLocale выбранный пользователем язык и список доступных языков
+func (h *AuthHandler) GetLocale(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	claims, ok := currentClaims(c)
+	if !ok {
+		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+		return
+	}
+
+	u, err := h.us.GetById(claims.Sub)
+	if err != nil {
+		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+		return
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, &authDto.LocaleDto{
+		Locale:    u.PreferredLocale(),
+		Available: h.lms.Languages(),
+	})
+}
+
+// UpdateLocale сохраняет предпочитаемый язык. Он попадает в новые токены и используется в письмах,
+// если язык не выбран в запросе явно
+func (h *AuthHandler) UpdateLocale(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	var req authDto.UpdateLocale
+
+	if verr, ok := validate.IsValidBody(c, &req, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+		return
+	}
+
+	claims, ok := currentClaims(c)
+	if !ok {
+		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+		return
+	}
+
+	locale := ""
+	if req.Locale != "" {
+		locale, ok = h.lms.Match(req.Locale)
+		if !ok {
+			msg := h.lms.GetMessage(
+				localizer.InvalidParam,
+				lang,
+				"Invalid parameter locale",
+				map[string]interface{}{
+					"param": "locale",
+				},
+			)
+			responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidParam, msg)
+			return
+		}
+	}
+
+	u, err := h.us.SetLocale(claims.Sub, locale)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+			return
+		}
+		h.errs.Respond(c, err, nil)
+		return
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, &authDto.LocaleDto{
+		Locale:    u.PreferredLocale(),
+		Available: h.lms.Languages(),
+	})
+}
+
 // isBan проверка блокировки пользователя
 func (h *AuthHandler) isBan(userId int64) (*entity.Ban, bool) {
 	if ban, ok := h.bs.GetActiveUserBan(userId); ok {
@@ -272,6 +480,26 @@ func (h *AuthHandler) isBan(userId int64) (*entity.Ban, bool) {
 	return nil, false
 }
 
+// consentRequiredResponse ответ CONSENT_REQUIRED с документами, которые нужно принять
+func (h *AuthHandler) consentRequiredResponse(c *gin.Context, err *services.ConsentRequiredError, lang string) {
+	msg := h.lms.GetMessage(
+		localizer.ConsentRequired,
+		lang,
+		"You need to accept the current terms of service and privacy policy",
+		nil,
+	)
+	responseutil.ErrorResponse(
+		c,
+		http.StatusForbidden,
+		errormsg.ConsentRequired,
+		msg,
+		&authDto.ConsentRequiredDto{
+			DocumentIds: err.DocumentIds(),
+			Documents:   err.Documents,
+		},
+	)
+}
+
 // banResponse отправляет сообщение с ответом, что пользователь заблокирован
 func (h *AuthHandler) banResponse(c *gin.Context, ban *entity.Ban, lang string) {
 	msg := h.getMsgToBan(ban, lang)
@@ -284,11 +512,11 @@ func (h *AuthHandler) banResponse(c *gin.Context, ban *entity.Ban, lang string)
 }
 
 func (h *AuthHandler) BanUser(c *gin.Context) {
-	lang := c.GetHeader("Accept-Language")
+	lang := localizer.FromContext(c)
 	var userBan *authDto.BanUser
 
-	if msg, ok := validate.IsValidBody(c, &userBan, h.lms); !ok {
-		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, msg)
+	if verr, ok := validate.IsValidBody(c, &userBan, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
 		return
 	}
 	if userBan.Days == 0 {
@@ -306,40 +534,63 @@ func (h *AuthHandler) BanUser(c *gin.Context) {
 		return
 	}
 
-	if userBan.IsForever {
-		ban, err := h.bs.BanUserForever(userBan.UserId, userBan.Cause)
-		if err != nil {
-			responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "Server Error")
-			return
-		}
-		responseutil.SuccessResponse(c, http.StatusOK, ban)
-		return
+	var issuedBy int64
+	if claims, ok := currentClaims(c); ok {
+		issuedBy = claims.Sub
 	}
 
-	expiredAt := time.Now().Add(time.Duration(userBan.Days) * 24 * time.Hour)
-
-	ban, err := h.bs.BanUser(userBan.UserId, userBan.Cause, expiredAt)
+	var ban *entity.Ban
+	var err error
+	if userBan.IsForever {
+		ban, err = h.bs.BanUserForever(userBan.UserId, issuedBy, userBan.ReasonCategory, userBan.Cause)
+	} else {
+		expiredAt := time.Now().Add(time.Duration(userBan.Days) * 24 * time.Hour)
+		ban, err = h.bs.BanUser(userBan.UserId, issuedBy, userBan.ReasonCategory, userBan.Cause, expiredAt)
+	}
 	if err != nil {
-		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "Server Error")
+		h.errs.Respond(c, err, nil)
 		return
 	}
 
+	recordAudit(h.as, c, auditaction.UserBan, userBan.UserId, map[string]interface{}{
+		"ban_id":          ban.Id.Int64,
+		"reason_category": ban.ReasonCategory,
+		"cause":           ban.Cause,
+		"is_forever":      ban.IsForever,
+	})
 	responseutil.SuccessResponse(c, http.StatusOK, ban)
 }
 
 func (h *AuthHandler) UnBanUser(c *gin.Context) {
 	var unban authDto.UnBanUser
 
-	if msg, ok := validate.IsValidBody(c, &unban, h.lms); !ok {
-		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, msg)
+	if verr, ok := validate.IsValidBody(c, &unban, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
 		return
 	}
 
-	if !h.bs.UnBanUser(unban.UserId) {
-		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "server Error")
+	var unbannedBy int64
+	if claims, ok := currentClaims(c); ok {
+		unbannedBy = claims.Sub
+	}
+
+	ban, err := h.bs.UnBanUser(unban.UserId, unbannedBy, unban.Reason)
+	if err != nil {
+		h.errs.
+			With(services.ErrUserIsNotBlocked, responseutil.ErrorSpec{
+				Status:    http.StatusNotFound,
+				Code:      errormsg.UserIsNotBlocked,
+				MessageId: localizer.UserIsNotBlocked,
+				Default:   "User is not blocked",
+			}).
+			Respond(c, err, nil)
 		return
 	}
 
+	recordAudit(h.as, c, auditaction.UserUnban, unban.UserId, map[string]interface{}{
+		"ban_id": ban.Id.Int64,
+		"reason": unban.Reason,
+	})
 	responseutil.SuccessResponse(c, http.StatusOK, nil)
 }
 
@@ -352,7 +603,15 @@ func (h *AuthHandler) getMsgToBan(ban *entity.Ban, lang string) string {
 			"forever",
 			nil)
 	} else {
-		expired = ban.ExpiredAt.Format("02-01-2006 15:04:05")
+		expired = h.lms.GetPluralMessage(
+			localizer.AccountBanDaysLeft,
+			lang,
+			int64(math.Ceil(time.Until(ban.ExpiredAt).Hours()/24)),
+			"{{.banExpired}} ({{.count}} day left)",
+			"{{.banExpired}} ({{.count}} days left)",
+			map[string]interface{}{
+				"banExpired": ban.ExpiredAt.Format("02-01-2006 15:04:05"),
+			})
 	}
 
 	msg := h.lms.GetMessage(
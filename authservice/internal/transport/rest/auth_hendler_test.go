@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/common/pkg/apperr"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Выход из сессии только для токена, которого нет в базе. Сбой базы не должен разлогинивать клиента
+func TestRefreshErrorResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logrus.NewEntry(logrus.New())
+	lms := localizer.NewLocalizeService(log, "../../../locales")
+	h := &AuthHandler{log: log, lms: lms, errs: newErrorRegistry(log, lms)}
+
+	cases := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"токен не найден", fmt.Errorf("поиск токена: %w", services.ErrNotFound), http.StatusUnauthorized},
+		{"база недоступна", apperr.Unavailable(errors.New("connection refused")), http.StatusServiceUnavailable},
+		{"неизвестная ошибка", errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/refresh", nil)
+
+			h.refreshErrorResponse(c, tc.err, "en")
+			if w.Code != tc.status {
+				t.Errorf("код %d, ожидался %d", w.Code, tc.status)
+			}
+		})
+	}
+}
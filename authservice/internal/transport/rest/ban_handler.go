@@ -0,0 +1,188 @@
+package rest
+
+import (
+	authDto "github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/auditaction"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/authservice/internal/util/passencoder"
+	"github.com/EddyZe/foodApp/authservice/internal/util/ratelimit"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/EddyZe/foodApp/common/pkg/validate"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+type BanHandler struct {
+	log  *logrus.Entry
+	us   *services.UserService
+	bs   *services.BanService
+	as   *services.AuditService
+	lms  *localizer.LocalizeService
+	errs *responseutil.ErrorRegistry
+	// appealsByEmail и appealsByIp ограничивают проверки пароля при подаче апелляции
+	appealsByEmail *ratelimit.Limiter
+	appealsByIp    *ratelimit.Limiter
+}
+
+func NewBanHandler(
+	log *logrus.Entry,
+	us *services.UserService,
+	bs *services.BanService,
+	as *services.AuditService,
+	lms *localizer.LocalizeService,
+	appealsByEmail *ratelimit.Limiter,
+	appealsByIp *ratelimit.Limiter,
+) *BanHandler {
+	return &BanHandler{
+		log:            log,
+		us:             us,
+		bs:             bs,
+		as:             as,
+		lms:            lms,
+		errs:           newErrorRegistry(log, lms),
+		appealsByEmail: appealsByEmail,
+		appealsByIp:    appealsByIp,
+	}
+}
+
+// CreateAppeal апелляция на блокировку. Заблокированный пользователь не может войти, поэтому подтверждает себя email и паролем
+func (h *BanHandler) CreateAppeal(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	var req authDto.CreateBanAppeal
+
+	if verr, ok := validate.IsValidBody(c, &req, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+		return
+	}
+
+	if rateLimited(
+		c,
+		h.lms,
+		lang,
+		rateLimit{h.appealsByIp, c.ClientIP()},
+		rateLimit{h.appealsByEmail, strings.ToLower(req.Email)},
+	) {
+		return
+	}
+
+	u, ok := h.us.GetByEmail(req.Email)
+	if !ok || !passencoder.CheckEqualsPassword(req.Password, u.Password) {
+		msg := h.lms.GetMessage(
+			localizer.InvalidEmailOrPassword,
+			lang,
+			"Invalid email or password",
+			nil,
+		)
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidEmailOrPassword, msg)
+		return
+	}
+
+	appeal, err := h.bs.CreateAppeal(u.Id.Int64, req.Message)
+	if err != nil {
+		h.errs.
+			WithMessage(services.ErrIsExists, localizer.BanAppealIsExists, "The appeal has already been submitted and is awaiting review").
+			Respond(c, err, nil)
+		return
+	}
+
+	h.as.Record(
+		auditaction.BanAppealCreate,
+		u.Id.Int64,
+		u.Id.Int64,
+		c.ClientIP(),
+		c.Request.UserAgent(),
+		map[string]interface{}{
+			"appeal_id": appeal.Id,
+			"ban_id":    appeal.BanId,
+		},
+	)
+	responseutil.SuccessResponse(c, http.StatusCreated, appeal)
+}
+
+// GetAppeals список апелляций. По умолчанию только ожидающие рассмотрения
+func (h *BanHandler) GetAppeals(c *gin.Context) {
+	var filter authDto.BanAppealFilter
+
+	if verr, ok := validate.IsValidQuery(c, &filter, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidParam, verr.Message, verr.Details()...)
+		return
+	}
+	if _, ok := c.GetQuery("status"); !ok {
+		filter.Status = entity.AppealStatusPending
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, h.bs.GetAppeals(filter.Status))
+}
+
+// ReviewAppeal одобряет или отклоняет апелляцию
+func (h *BanHandler) ReviewAppeal(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	appealId, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	var req authDto.ReviewBanAppeal
+	if verr, ok := validate.IsValidBody(c, &req, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+		return
+	}
+
+	var reviewerId int64
+	if claims, ok := currentClaims(c); ok {
+		reviewerId = claims.Sub
+	}
+
+	appeal, err := h.bs.ReviewAppeal(appealId, reviewerId, *req.Approve, req.Comment)
+	if err != nil {
+		h.errs.
+			WithMessage(services.ErrNotFound, localizer.BanAppealNotFound, "Appeal not found").
+			Respond(c, err, nil)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.BanAppealReview, appeal.UserId, map[string]interface{}{
+		"appeal_id": appeal.Id,
+		"ban_id":    appeal.BanId,
+		"status":    appeal.Status,
+		"comment":   req.Comment,
+	})
+	responseutil.SuccessResponse(c, http.StatusOK, appeal)
+}
+
+// GetBanHistory история блокировок пользователя
+func (h *BanHandler) GetBanHistory(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	userId, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	bans := h.bs.GetBanHistory(userId)
+	res := make([]authDto.BanDto, 0, len(bans))
+	for _, ban := range bans {
+		res = append(res, toBanDto(&ban))
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, res)
+}
+
+func toBanDto(ban *entity.Ban) authDto.BanDto {
+	return authDto.BanDto{
+		Id:             ban.Id.Int64,
+		IsForever:      ban.IsForever,
+		CreatedAt:      ban.CreatedAt,
+		ExpiredAt:      ban.ExpiredAt,
+		Cause:          ban.Cause,
+		ReasonCategory: ban.ReasonCategory,
+		IssuedBy:       ban.IssuedBy,
+		UnbannedAt:     ban.UnbannedAt,
+		UnbannedBy:     ban.UnbannedBy,
+		UnbanReason:    ban.UnbanReason,
+	}
+}
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/authservice/internal/util/ratelimit"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Апелляция проверяет пароль, поэтому попытки ограничены по email и по IP до обращения к сервисам
+func TestCreateAppealRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logrus.NewEntry(logrus.New())
+	lms := localizer.NewLocalizeService(log, "../../../locales")
+
+	cases := []struct {
+		name    string
+		exhaust func(byEmail, byIp *ratelimit.Limiter)
+	}{
+		{"по email без учета регистра", func(byEmail, _ *ratelimit.Limiter) { byEmail.Allow("ivan@mail.ru") }},
+		{"по IP", func(_, byIp *ratelimit.Limiter) { byIp.Allow("192.0.2.1") }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			byEmail := ratelimit.New(1, time.Minute)
+			byIp := ratelimit.New(1, time.Minute)
+			tc.exhaust(byEmail, byIp)
+
+			h := NewBanHandler(log, nil, nil, nil, lms, byEmail, byIp)
+			r := gin.New()
+			r.POST("/ban-appeals", h.CreateAppeal)
+
+			req := httptest.NewRequest(
+				http.MethodPost,
+				"/ban-appeals",
+				strings.NewReader(`{"email":"Ivan@mail.ru","password":"password","message":"Прошу разблокировать"}`),
+			)
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = "192.0.2.1:5000"
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusTooManyRequests {
+				t.Fatalf("ожидался код 429, получен %d: %s", w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), errormsg.TooManyRequests) {
+				t.Errorf("в ответе нет кода %s: %s", errormsg.TooManyRequests, w.Body.String())
+			}
+			if w.Header().Get("Retry-After") == "" {
+				t.Error("нет заголовка Retry-After")
+			}
+		})
+	}
+}
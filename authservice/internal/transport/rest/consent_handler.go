@@ -0,0 +1,83 @@
+package rest
+
+import (
+	authDto "github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/auditaction"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/EddyZe/foodApp/common/pkg/validate"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type ConsentHandler struct {
+	log  *logrus.Entry
+	cs   *services.ConsentService
+	as   *services.AuditService
+	lms  *localizer.LocalizeService
+	errs *responseutil.ErrorRegistry
+}
+
+func NewConsentHandler(
+	log *logrus.Entry,
+	cs *services.ConsentService,
+	as *services.AuditService,
+	lms *localizer.LocalizeService,
+) *ConsentHandler {
+	return &ConsentHandler{
+		log:  log,
+		cs:   cs,
+		as:   as,
+		lms:  lms,
+		errs: newErrorRegistry(log, lms),
+	}
+}
+
+// GetCurrentDocuments текущие версии документов, которые нужно принять при регистрации
+func (h *ConsentHandler) GetCurrentDocuments(c *gin.Context) {
+	docs, err := h.cs.GetCurrentDocuments()
+	if err != nil {
+		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "Server Error")
+		return
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, docs)
+}
+
+// GetDocuments все опубликованные версии документов
+func (h *ConsentHandler) GetDocuments(c *gin.Context) {
+	responseutil.SuccessResponse(c, http.StatusOK, h.cs.GetAllDocuments())
+}
+
+// PublishDocument публикация новой версии документа
+func (h *ConsentHandler) PublishDocument(c *gin.Context) {
+	var req authDto.PublishConsentDocument
+
+	if verr, ok := validate.IsValidBody(c, &req, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+		return
+	}
+
+	var publishedBy int64
+	if claims, ok := currentClaims(c); ok {
+		publishedBy = claims.Sub
+	}
+
+	doc, err := h.cs.Publish(req.Type, req.Version, req.Url, publishedBy)
+	if err != nil {
+		h.errs.
+			WithMessage(services.ErrIsExists, localizer.ConsentDocumentIsExists, "This document version has already been published").
+			Respond(c, err, nil)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.ConsentPublish, 0, map[string]interface{}{
+		"document_id": doc.Id,
+		"type":        doc.Type,
+		"version":     doc.Version,
+	})
+	responseutil.SuccessResponse(c, http.StatusCreated, doc)
+}
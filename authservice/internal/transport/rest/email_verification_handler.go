@@ -1,9 +1,11 @@
 package rest
 
 import (
+	"errors"
 	"fmt"
 	"github.com/EddyZe/foodApp/authservice/internal/config"
 	authDto "github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/authservice/internal/mailer"
 	"github.com/EddyZe/foodApp/authservice/internal/services"
 	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
 	"github.com/EddyZe/foodApp/common/domain/models"
@@ -22,8 +24,10 @@ type EmailVerificationHandler struct {
 	rs           *services.RoleService
 	log          *logrus.Entry
 	sendMailServ *services.MailService
+	sps          *services.MailSuppressionService
 	mvs          *services.EmailVerificationService
 	lms          *localizer.LocalizeService
+	errs         *responseutil.ErrorRegistry
 	appInfo      *config.AppInfo
 }
 
@@ -33,6 +37,7 @@ func NewEmailVerificationHandler(
 	rs *services.RoleService,
 	log *logrus.Entry,
 	sendMailServ *services.MailService,
+	sps *services.MailSuppressionService,
 	mvs *services.EmailVerificationService,
 	lms *localizer.LocalizeService,
 	appInfo *config.AppInfo,
@@ -43,15 +48,17 @@ func NewEmailVerificationHandler(
 		rs:           rs,
 		log:          log,
 		sendMailServ: sendMailServ,
+		sps:          sps,
 		mvs:          mvs,
 		lms:          lms,
+		errs:         newErrorRegistry(log, lms),
 		appInfo:      appInfo,
 	}
 }
 
 // SendMailConfirmCode отправляет письмо для подтверждения почты
 func (h *EmailVerificationHandler) SendMailConfirmCode(c *gin.Context) {
-	lang := c.GetHeader("Accept-Language")
+	lang := localizer.FromContext(c)
 
 	claims, ok := c.Get("claims")
 	if !ok {
@@ -74,17 +81,35 @@ func (h *EmailVerificationHandler) SendMailConfirmCode(c *gin.Context) {
 
 	u, err := h.us.GetById(userId)
 	if err != nil {
-		if err.Error() == errormsg.NotFound {
+		if errors.Is(err, services.ErrNotFound) {
 			responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
 			return
 		}
+		h.errs.Respond(c, err, nil)
+		return
+	}
 
-		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "server Internal Error")
+	// на адрес уже был постоянный возврат или жалоба: письмо не дойдет, пользователь должен сменить почту
+	if h.sps.IsSuppressed(u.Email) {
+		msg := h.lms.GetMessage(
+			localizer.EmailUndeliverable,
+			lang,
+			"Mail to this address cannot be delivered",
+			map[string]interface{}{
+				"email": u.Email,
+			},
+		)
+		responseutil.ErrorResponse(c, http.StatusUnprocessableEntity, errormsg.EmailUndeliverable, msg)
 		return
 	}
 
 	code, err := h.mvs.GenerateAndSaveCode(u.Id.Int64, 8)
 	if err != nil {
+		var retryErr *services.RetryLaterError
+		if errors.As(err, &retryErr) {
+			retryLaterResponse(c, h.lms, lang, retryErr)
+			return
+		}
 		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "server Internal Error")
 		return
 	}
@@ -99,33 +124,19 @@ func (h *EmailVerificationHandler) SendMailConfirmCode(c *gin.Context) {
 	}
 	url := fmt.Sprintf("%s/confirm-email-url?token=%s&code=%s", h.appInfo.AppUrl, urlToken, code.Code)
 
-	body := h.lms.GetMessage(
-		localizer.SendVerifiedCodeBody,
+	if _, err := h.sendMailServ.Enqueue(
+		mailer.TemplateConfirmEmail,
 		lang,
-		fmt.Sprintf("Send this is code: %s", code.Code),
 		map[string]interface{}{
 			"appName":        h.appInfo.AppName,
 			"url":            url,
 			"appSupportLink": h.appInfo.SupportLink,
 			"code":           code.Code,
+			"expiresMinutes": expiresMinutes(code.ExpiredAt),
 		},
-	)
-
-	subject := h.lms.GetMessage(
-		localizer.SendVerifiedCodeSubject,
-		lang,
-		"Confirm Your Email",
-		map[string]interface{}{
-			"appName": h.appInfo.AppName,
-		},
-	)
-
-	if err := h.sendMailServ.SendMailFromApp(
-		subject,
-		body,
 		u.Email,
 	); err != nil {
-		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "server error")
+		h.errs.Respond(c, err, map[string]interface{}{"email": u.Email})
 		return
 	}
 
@@ -134,10 +145,7 @@ func (h *EmailVerificationHandler) SendMailConfirmCode(c *gin.Context) {
 
 // ConfirmEmailByUrl подтверждение почты по ссылке из письма
 func (h *EmailVerificationHandler) ConfirmEmailByUrl(c *gin.Context) {
-	lang := c.GetHeader("Accept-language")
-	if lang == "" {
-		lang = "en"
-	}
+	lang := localizer.FromContext(c)
 	tokenString := c.Query("token")
 	if tokenString == "" {
 		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
@@ -214,7 +222,7 @@ func (h *EmailVerificationHandler) ConfirmEmailByUrl(c *gin.Context) {
 
 // ConfirmMail подтверждение почты по коду
 func (h *EmailVerificationHandler) ConfirmMail(c *gin.Context) {
-	lang := c.GetHeader("Accept-Language")
+	lang := localizer.FromContext(c)
 	token, ok := jwtutil.ExtractBearerTokenHeader(c)
 	if !ok {
 		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
@@ -239,19 +247,22 @@ func (h *EmailVerificationHandler) ConfirmMail(c *gin.Context) {
 	}
 
 	var ce authDto.ConfirmEmail
-	if msg, ok := validate.IsValidBody(c, &ce, h.lms); !ok {
-		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, msg)
-		return
-	}
-
-	code, ok := h.mvs.FindCode(ce.Code)
-	if !ok || code.Code != ce.Code || !code.IsVerified {
-		h.responseInvalidCode(c, lang)
+	if verr, ok := validate.IsValidBody(c, &ce, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
 		return
 	}
 
-	if code.IsExpired() {
-		h.responseExpiredCode(c, lang)
+	code, err := h.mvs.VerifyCode(claimsMap.Sub, ce.Code)
+	if err != nil {
+		var retryErr *services.RetryLaterError
+		switch {
+		case errors.As(err, &retryErr):
+			retryLaterResponse(c, h.lms, lang, retryErr)
+		default:
+			h.errs.
+				WithMessage(services.ErrCodeExpired, localizer.ExpiredEmailCode, "The code has expired!").
+				Respond(c, err, nil)
+		}
 		return
 	}
 
@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/common/pkg/apperr"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/sirupsen/logrus"
+)
+
+// newErrorRegistry общие ответы на ошибки сервисов. Ошибки проверяются по порядку, поэтому ошибки
+// с кодом стоят раньше видов apperr, к которым относятся. Если в обработчике ошибка значит что-то
+// конкретное (ErrNotFound роли или апелляции), сообщение уточняется через WithMessage
+func newErrorRegistry(log *logrus.Entry, lms *localizer.LocalizeService) *responseutil.ErrorRegistry {
+	r := responseutil.NewErrorRegistry(log, lms, responseutil.ErrorSpec{
+		Status:    http.StatusInternalServerError,
+		Code:      errormsg.ServerInternalError,
+		MessageId: localizer.ServerError,
+		Default:   "Server Error",
+	})
+
+	for _, e := range []struct {
+		err  error
+		spec responseutil.ErrorSpec
+	}{
+		{services.ErrRoleIsProtected, responseutil.ErrorSpec{
+			Status: http.StatusBadRequest, Code: errormsg.RoleIsProtected,
+			MessageId: localizer.RoleIsProtected, Default: "The built-in role cannot be renamed or deleted",
+		}},
+		{services.ErrOwnAdminRole, responseutil.ErrorSpec{
+			Status: http.StatusForbidden, Code: errormsg.OwnAdminRole,
+			MessageId: localizer.OwnAdminRole, Default: "You cannot revoke your own admin role",
+		}},
+		{services.ErrLastAdmin, responseutil.ErrorSpec{
+			Status: http.StatusConflict, Code: errormsg.LastAdmin,
+			MessageId: localizer.LastAdmin, Default: "The last administrator cannot lose the admin role",
+		}},
+		{services.ErrUserIsNotBlocked, responseutil.ErrorSpec{
+			Status: http.StatusBadRequest, Code: errormsg.UserIsNotBlocked,
+			MessageId: localizer.UserIsNotBlocked, Default: "User is not blocked",
+		}},
+		{services.ErrUserIsAlreadyBlocked, responseutil.ErrorSpec{
+			Status: http.StatusConflict, Code: errormsg.UserIsAlreadyBlocked,
+			MessageId: localizer.UserIsBlocked, Default: "User is blocked",
+		}},
+		{services.ErrAppealIsReviewed, responseutil.ErrorSpec{
+			Status: http.StatusConflict, Code: errormsg.AppealIsReviewed,
+			MessageId: localizer.BanAppealIsReviewed, Default: "The appeal has already been reviewed",
+		}},
+		{services.ErrRegistrationRestricted, responseutil.ErrorSpec{
+			Status: http.StatusForbidden, Code: errormsg.RegistrationRestricted,
+			MessageId: localizer.RegistrationRestricted, Default: "Registration is currently closed",
+		}},
+		{services.ErrInvalidInviteCode, responseutil.ErrorSpec{
+			Status: http.StatusForbidden, Code: errormsg.InvalidInviteCode,
+			MessageId: localizer.InvalidInviteCode, Default: "The invite code is invalid or has expired",
+		}},
+		{services.ErrInviteRoleNotAllowed, responseutil.ErrorSpec{
+			Status: http.StatusForbidden, Code: errormsg.InviteRoleNotAllowed,
+			MessageId: localizer.InviteRoleNotAllowed, Default: "You can only invite with roles you hold, except administrator",
+		}},
+		{services.ErrInvalidEmailCode, responseutil.ErrorSpec{
+			Status: http.StatusBadRequest, Code: errormsg.InvalidEmailCode,
+			MessageId: localizer.InvalidEmailCode, Default: "Invalid code. Please check the entered code.",
+		}},
+		{services.ErrInvalidResetCode, responseutil.ErrorSpec{
+			Status: http.StatusBadRequest, Code: errormsg.InvalidResetCode,
+			MessageId: localizer.InvalidResetPasswordCode, Default: "Invalid code",
+		}},
+		{services.ErrCodeExpired, responseutil.ErrorSpec{
+			Status: http.StatusBadRequest, Code: errormsg.CodeExpired,
+			MessageId: localizer.CodeExpired, Default: "Code expired",
+		}},
+		{services.ErrCodeAttemptsExceeded, responseutil.ErrorSpec{
+			Status: http.StatusTooManyRequests, Code: errormsg.CodeAttemptsExceeded,
+			MessageId: localizer.CodeNoLongerValid, Default: "Too many wrong attempts, the code is no longer valid",
+		}},
+		{services.ErrEmailSuppressed, responseutil.ErrorSpec{
+			Status: http.StatusUnprocessableEntity, Code: errormsg.EmailUndeliverable,
+			MessageId: localizer.EmailUndeliverable, Default: "Mail to this address cannot be delivered",
+		}},
+		{services.ErrLastPasswordIsExists, responseutil.ErrorSpec{
+			Status: http.StatusBadRequest, Code: errormsg.LastPasswordIsExists,
+			MessageId: localizer.LastPasswords, Default: "The new password should not be equal to the last two",
+		}},
+		{services.ErrInvalidParam, responseutil.ErrorSpec{
+			Status: http.StatusBadRequest, Code: errormsg.InvalidParam,
+			MessageId: localizer.InvalidParam, Default: "Invalid parameter {{.param}}",
+		}},
+		// виды apperr идут последними: ошибки с кодом выше относятся к ним же
+		{services.ErrNotFound, responseutil.ErrorSpec{
+			Status: http.StatusNotFound, Code: errormsg.NotFound,
+			MessageId: localizer.NotFound, Default: "Not found",
+		}},
+		{services.ErrIsExists, responseutil.ErrorSpec{
+			Status: http.StatusConflict, Code: errormsg.IsExists,
+			MessageId: localizer.IsExists, Default: "Already exists",
+		}},
+		{apperr.ErrForbidden, responseutil.ErrorSpec{
+			Status: http.StatusForbidden, Code: errormsg.Forbidden,
+			MessageId: localizer.Forbidden, Default: "Not enough rights",
+		}},
+		{apperr.ErrInvalid, responseutil.ErrorSpec{
+			Status: http.StatusBadRequest, Code: errormsg.InvalidParam,
+			MessageId: localizer.InvalidRequest, Default: "Invalid request",
+		}},
+		{apperr.ErrUnavailable, responseutil.ErrorSpec{
+			Status: http.StatusServiceUnavailable, Code: errormsg.ServiceUnavailable,
+			MessageId: localizer.ServiceUnavailable, Default: "The service is temporarily unavailable",
+		}},
+	} {
+		r.Register(e.err, e.spec)
+	}
+
+	return r
+}
@@ -0,0 +1,57 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/common/domain/dto"
+	"github.com/EddyZe/foodApp/common/pkg/apperr"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestErrorRegistryMessages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logrus.NewEntry(logrus.New())
+	errs := newErrorRegistry(log, localizer.NewLocalizeService(log, "../../../locales"))
+
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		want   string
+	}{
+		{"параметр из ошибки", &services.InvalidParamError{Param: "cursor"}, http.StatusBadRequest, "Invalid parameter cursor"},
+		{"общая ошибка валидации", apperr.Invalid(nil), http.StatusBadRequest, "Invalid request"},
+		{"попытки ввода кода", services.ErrCodeAttemptsExceeded, http.StatusTooManyRequests, "Too many wrong attempts, the code is no longer valid"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			localizer.SetContext(c, "en", false)
+
+			errs.Respond(c, tc.err, nil)
+
+			var resp struct {
+				Error dto.APIError `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tc.status || resp.Error.Message != tc.want {
+				t.Fatalf("%d %q, ожидалось %d %q", w.Code, resp.Error.Message, tc.status, tc.want)
+			}
+			if strings.Contains(resp.Error.Message, "<no value>") {
+				t.Errorf("в сообщении не подставлены данные: %q", resp.Error.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"context"
+	authDto "github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// readyPingTimeout сколько ждать ответа postgres. Probe kubernetes по умолчанию ждет секунду,
+// зависший ping должен успеть превратиться в 503, а не в таймаут probe
+const readyPingTimeout = 800 * time.Millisecond
+
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+type availability interface {
+	Available() bool
+}
+
+type HealthHandler struct {
+	db    pinger
+	redis availability
+}
+
+func NewHealthHandler(db pinger, redis availability) *HealthHandler {
+	return &HealthHandler{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// Ready readiness probe. Без postgres сервис не готов (503). Без redis он работает в деградированном
+// режиме и остается готовым, иначе при сбое redis из балансировки выпадут все реплики разом
+func (h *HealthHandler) Ready(c *gin.Context) {
+	res := authDto.Health{
+		Status: authDto.HealthOk,
+		Checks: map[string]string{
+			"postgres": authDto.HealthUp,
+			"redis":    authDto.HealthUp,
+		},
+	}
+	status := http.StatusOK
+
+	if !h.redis.Available() {
+		res.Checks["redis"] = authDto.HealthDown
+		res.Status = authDto.HealthDegraded
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readyPingTimeout)
+	defer cancel()
+	if err := h.db.PingContext(ctx); err != nil {
+		res.Checks["postgres"] = authDto.HealthDown
+		res.Status = authDto.HealthDown
+		status = http.StatusServiceUnavailable
+	}
+
+	responseutil.SuccessResponse(c, status, res)
+}
@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hangingDb ping, который отвечает только по истечении контекста
+type hangingDb struct{}
+
+func (hangingDb) PingContext(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+type redisUp struct{}
+
+func (redisUp) Available() bool { return true }
+
+func TestReadyPingTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ready", NewHealthHandler(hangingDb{}, redisUp{}).Ready)
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("код %d, ожидался 503", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 2*readyPingTimeout {
+		t.Errorf("ответ через %v, ping должен прерываться по таймауту", elapsed)
+	}
+}
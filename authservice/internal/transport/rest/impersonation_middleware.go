@@ -0,0 +1,33 @@
+package rest
+
+import (
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/auditaction"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditImpersonation пишет в журнал аудита каждый запрос, выполненный с токеном имперсонации.
+// Claims проверяются после обработки запроса, поэтому middleware можно подключать глобально до JwtFilter
+func AuditImpersonation(as *services.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		claims, ok := currentClaims(c)
+		if !ok || !claims.IsImpersonated() {
+			return
+		}
+
+		as.Record(
+			auditaction.ImpersonatedRequest,
+			claims.Actor,
+			claims.Sub,
+			c.ClientIP(),
+			c.Request.UserAgent(),
+			map[string]interface{}{
+				"method": c.Request.Method,
+				"path":   c.FullPath(),
+				"status": c.Writer.Status(),
+			},
+		)
+	}
+}
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	authDto "github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/auditaction"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/EddyZe/foodApp/common/pkg/validate"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type InviteHandler struct {
+	log  *logrus.Entry
+	is   *services.InviteService
+	as   *services.AuditService
+	lms  *localizer.LocalizeService
+	errs *responseutil.ErrorRegistry
+}
+
+func NewInviteHandler(
+	log *logrus.Entry,
+	is *services.InviteService,
+	as *services.AuditService,
+	lms *localizer.LocalizeService,
+) *InviteHandler {
+	return &InviteHandler{
+		log:  log,
+		is:   is,
+		as:   as,
+		lms:  lms,
+		errs: newErrorRegistry(log, lms),
+	}
+}
+
+// GetInvites список инвайт-кодов с числом использований
+func (h *InviteHandler) GetInvites(c *gin.Context) {
+	responseutil.SuccessResponse(c, http.StatusOK, h.is.GetAll())
+}
+
+// CreateInvite создание инвайт-кода с лимитом использований, сроком действия и ролями по умолчанию
+func (h *InviteHandler) CreateInvite(c *gin.Context) {
+	var req authDto.CreateInviteCode
+
+	if verr, ok := validate.IsValidBody(c, &req, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+		return
+	}
+
+	var createdBy int64
+	if claims, ok := currentClaims(c); ok {
+		createdBy = claims.Sub
+	}
+
+	invite, err := h.is.Create(&req, createdBy)
+	if err != nil {
+		h.errs.
+			WithMessage(services.ErrNotFound, localizer.RoleNotFound, "Role not found").
+			Respond(c, err, nil)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.InviteCreate, 0, map[string]interface{}{
+		"invite_id":  invite.Id,
+		"max_uses":   invite.MaxUses,
+		"expired_at": invite.ExpiredAt,
+		"roles":      invite.Roles,
+	})
+	responseutil.SuccessResponse(c, http.StatusCreated, invite)
+}
+
+// RevokeInvite отзыв инвайт-кода
+func (h *InviteHandler) RevokeInvite(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	id, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	invite, err := h.is.Revoke(id)
+	if err != nil {
+		h.errs.
+			WithMessage(services.ErrNotFound, localizer.InviteCodeNotFound, "Invite code not found").
+			Respond(c, err, nil)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.InviteRevoke, 0, map[string]interface{}{
+		"invite_id":  invite.Id,
+		"used_count": invite.UsedCount,
+	})
+	responseutil.SuccessResponse(c, http.StatusOK, invite)
+}
+
+// GetUsages пользователи, зарегистрированные по инвайт-коду
+func (h *InviteHandler) GetUsages(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	id, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, h.is.GetUsages(id))
+}
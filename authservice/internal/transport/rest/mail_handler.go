@@ -0,0 +1,188 @@
+package rest
+
+import (
+	"crypto/subtle"
+	authDto "github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/authservice/internal/mailer"
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/auditaction"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/EddyZe/foodApp/common/pkg/validate"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxReportSize ограничение размера письма-отчета о возврате
+const maxReportSize = 10 << 20
+
+type MailHandler struct {
+	log         *logrus.Entry
+	ms          *services.MailService
+	sps         *services.MailSuppressionService
+	as          *services.AuditService
+	lms         *localizer.LocalizeService
+	errs        *responseutil.ErrorRegistry
+	bounceToken string
+}
+
+func NewMailHandler(
+	log *logrus.Entry,
+	ms *services.MailService,
+	sps *services.MailSuppressionService,
+	as *services.AuditService,
+	lms *localizer.LocalizeService,
+	bounceToken string,
+) *MailHandler {
+	return &MailHandler{
+		log:         log,
+		ms:          ms,
+		sps:         sps,
+		as:          as,
+		lms:         lms,
+		errs:        newErrorRegistry(log, lms),
+		bounceToken: bounceToken,
+	}
+}
+
+// GetMessages последние письма в очереди, можно отфильтровать по статусу
+func (h *MailHandler) GetMessages(c *gin.Context) {
+	var filter authDto.MailMessageFilter
+
+	if verr, ok := validate.IsValidQuery(c, &filter, h.lms); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidParam, verr.Message, verr.Details()...)
+		return
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, h.ms.GetAll(filter.Status, filter.Limit))
+}
+
+// GetMessage статус доставки письма
+func (h *MailHandler) GetMessage(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	id, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	msg, err := h.ms.GetById(id)
+	if err != nil {
+		h.mailErrorResponse(c, err)
+		return
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, msg)
+}
+
+// RetryMessage возвращает недоставленное письмо (dead) в очередь
+func (h *MailHandler) RetryMessage(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	id, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	msg, err := h.ms.Retry(id)
+	if err != nil {
+		h.mailErrorResponse(c, err)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.MailRetry, 0, map[string]interface{}{
+		"mail_id":  msg.Id,
+		"template": msg.Template,
+	})
+	responseutil.SuccessResponse(c, http.StatusOK, msg)
+}
+
+// IngestBounces прием возвратов и жалоб от почтового провайдера. Тело - JSON BounceReport
+// или письмо-отчет целиком (Content-Type: message/rfc822) из ящика возвратов
+func (h *MailHandler) IngestBounces(c *gin.Context) {
+	lang := localizer.FromContext(c)
+
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if h.bounceToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.bounceToken)) != 1 {
+		responseutil.ErrorResponse(c, http.StatusUnauthorized, errormsg.Unauthorized, unauthMsg(h.lms, lang))
+		return
+	}
+
+	var bounces []mailer.Bounce
+	if c.ContentType() == "message/rfc822" {
+		data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxReportSize))
+		if err != nil {
+			responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, "Invalid body")
+			return
+		}
+
+		bounces, err = mailer.ParseReport(data)
+		if err != nil {
+			h.log.Warn("письмо-отчет не разобрано: ", err)
+			responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, "Invalid body")
+			return
+		}
+	} else {
+		var report authDto.BounceReport
+		if verr, ok := validate.IsValidBody(c, &report, h.lms); !ok {
+			responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
+			return
+		}
+
+		for _, ev := range report.Events {
+			bounces = append(bounces, mailer.Bounce{
+				Email:     ev.Email,
+				Type:      ev.Type,
+				Permanent: ev.Permanent,
+				Reason:    ev.Reason,
+			})
+		}
+	}
+
+	suppressed, err := h.sps.Register(bounces)
+	if err != nil {
+		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "Server Error")
+		return
+	}
+
+	responseutil.SuccessResponse(c, http.StatusOK, &authDto.BounceReportResult{
+		Received:   len(bounces),
+		Suppressed: suppressed,
+	})
+}
+
+// GetSuppressions адреса, на которые письма не отправляются
+func (h *MailHandler) GetSuppressions(c *gin.Context) {
+	responseutil.SuccessResponse(c, http.StatusOK, h.sps.GetAll())
+}
+
+// DeleteSuppression снимает блокировку адреса
+func (h *MailHandler) DeleteSuppression(c *gin.Context) {
+	lang := localizer.FromContext(c)
+	id, ok := idParam(c, h.lms, lang, "id")
+	if !ok {
+		return
+	}
+
+	suppression, err := h.sps.Delete(id)
+	if err != nil {
+		h.errs.
+			WithMessage(services.ErrNotFound, localizer.MailSuppressionNotFound, "Address is not in the suppression list").
+			Respond(c, err, nil)
+		return
+	}
+
+	recordAudit(h.as, c, auditaction.MailSuppressionDelete, 0, map[string]interface{}{
+		"email":  suppression.Email,
+		"reason": suppression.Reason,
+	})
+	responseutil.SuccessResponse(c, http.StatusOK, suppression)
+}
+
+func (h *MailHandler) mailErrorResponse(c *gin.Context, err error) {
+	h.errs.
+		WithMessage(services.ErrNotFound, localizer.MailMessageNotFound, "Mail message not found").
+		Respond(c, err, nil)
+}
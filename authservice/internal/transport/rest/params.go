@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/authservice/internal/util/ratelimit"
+	"github.com/EddyZe/foodApp/common/domain/models"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// idParam достает числовой параметр из пути. При ошибке сразу отправляет ответ
+func idParam(c *gin.Context, ls *localizer.LocalizeService, lang, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		msg := ls.GetMessage(
+			localizer.InvalidParam,
+			lang,
+			"Invalid parameter "+name,
+			map[string]interface{}{
+				"param": name,
+			},
+		)
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidParam, msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// retryLaterResponse ответ 429 с временем ожидания в сообщении и заголовке Retry-After
+func retryLaterResponse(c *gin.Context, ls *localizer.LocalizeService, lang string, err *services.RetryLaterError) {
+	seconds := int64(math.Ceil(err.RetryAfter.Seconds()))
+	messageIds := map[string]string{
+		errormsg.CodeResendCooldown:   localizer.CodeResendCooldown,
+		errormsg.CodeLimitExceeded:    localizer.CodeLimitExceeded,
+		errormsg.CodeAttemptsExceeded: localizer.CodeAttemptsExceeded,
+		errormsg.TooManyRequests:      localizer.TooManyRequests,
+	}
+
+	msg := ls.GetPluralMessage(
+		messageIds[err.Code],
+		lang,
+		seconds,
+		"Try again in {{.count}} second",
+		"Try again in {{.count}} seconds",
+		nil,
+	)
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	responseutil.ErrorResponse(c, http.StatusTooManyRequests, err.Code, msg)
+}
+
+// rateLimit лимитер и ключ, по которому учитывается запрос
+type rateLimit struct {
+	limiter *ratelimit.Limiter
+	key     string
+}
+
+// rateLimited учитывает запрос во всех лимитерах и отвечает 429, если хотя бы один лимит исчерпан
+func rateLimited(c *gin.Context, ls *localizer.LocalizeService, lang string, limits ...rateLimit) bool {
+	var retryAfter time.Duration
+	for _, l := range limits {
+		if ok, wait := l.limiter.Allow(l.key); !ok {
+			retryAfter = max(retryAfter, wait)
+		}
+	}
+	if retryAfter == 0 {
+		return false
+	}
+
+	retryLaterResponse(c, ls, lang, &services.RetryLaterError{
+		Code:       errormsg.TooManyRequests,
+		RetryAfter: retryAfter,
+	})
+	return true
+}
+
+// currentClaims возвращает claims, которые положил JwtFilter
+func currentClaims(c *gin.Context) (*models.JwtClaims, bool) {
+	claims, ok := c.Get("claims")
+	if !ok {
+		return nil, false
+	}
+
+	claimsMap, ok := claims.(*models.JwtClaims)
+	return claimsMap, ok
+}
+
+// recordAudit пишет действие текущего пользователя в журнал аудита. В сессии имперсонации автор - администратор
+func recordAudit(as *services.AuditService, c *gin.Context, action string, userId int64, details map[string]interface{}) {
+	var actorId int64
+	if claims, ok := currentClaims(c); ok {
+		actorId = claims.Sub
+		if claims.IsImpersonated() {
+			actorId = claims.Actor
+		}
+	}
+
+	as.Record(action, actorId, userId, c.ClientIP(), c.Request.UserAgent(), details)
+}
+
+// expiresMinutes сколько минут осталось до expiredAt, округление вверх. Используется в письмах
+func expiresMinutes(expiredAt time.Time) int {
+	return int(math.Ceil(time.Until(expiredAt).Minutes()))
+}
@@ -0,0 +1,28 @@
+package rest
+
+import (
+	"github.com/EddyZe/foodApp/authservice/internal/services"
+	"github.com/EddyZe/foodApp/common/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ResolvePermissions подгружает разрешения пользователя из ролей, если их нет в токене
+// (например, при выключенном JWT_EMBED_PERMISSIONS). Должен вызываться после JwtFilter
+func ResolvePermissions(rs *services.RoleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.Get("claims")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		claimsMap, ok := claims.(*models.JwtClaims)
+		if !ok || len(claimsMap.Permissions) > 0 {
+			c.Next()
+			return
+		}
+
+		claimsMap.Permissions = rs.GetPermissionsByUserId(claimsMap.Sub)
+		c.Next()
+	}
+}
@@ -1,25 +1,51 @@
 package rest
 
 import (
+	"errors"
+	"fmt"
 	"github.com/EddyZe/foodApp/authservice/internal/config"
 	"github.com/EddyZe/foodApp/authservice/internal/domain/dto"
+	"github.com/EddyZe/foodApp/authservice/internal/domain/entity"
+	"github.com/EddyZe/foodApp/authservice/internal/mailer"
 	"github.com/EddyZe/foodApp/authservice/internal/services"
 	"github.com/EddyZe/foodApp/authservice/internal/util/errormsg"
+	"github.com/EddyZe/foodApp/authservice/internal/util/ratelimit"
 	"github.com/EddyZe/foodApp/common/pkg/localizer"
 	"github.com/EddyZe/foodApp/common/pkg/responseutil"
 	"github.com/EddyZe/foodApp/common/pkg/validate"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
+const (
+	// resetQueueSize сколько запросов сброса ждут обработки. При переполнении запрос отбрасывается
+	resetQueueSize = 256
+	// resetWorkers сколько запросов сброса обрабатывается одновременно
+	resetWorkers = 2
+)
+
+// resetRequest запрос кода сброса, который обрабатывается в фоне
+type resetRequest struct {
+	email string
+	lang  string
+	// explicitLang язык выбран в запросе (?lang или cookie) и важнее языка из профиля
+	explicitLang bool
+}
+
 type ResetPasswordHandler struct {
-	log     *logrus.Entry
-	us      *services.UserService
-	ms      *services.MailService
-	rp      *services.ResetPasswordService
-	ls      *localizer.LocalizeService
-	appInfo *config.AppInfo
+	log      *logrus.Entry
+	us       *services.UserService
+	ms       *services.MailService
+	rp       *services.ResetPasswordService
+	ls       *localizer.LocalizeService
+	errs     *responseutil.ErrorRegistry
+	appInfo  *config.AppInfo
+	byEmail  *ratelimit.Limiter
+	byIp     *ratelimit.Limiter
+	requests chan resetRequest
 }
 
 func NewResetPasswordHandler(
@@ -29,126 +55,137 @@ func NewResetPasswordHandler(
 	rp *services.ResetPasswordService,
 	ls *localizer.LocalizeService,
 	appInfo *config.AppInfo,
+	byEmail, byIp *ratelimit.Limiter,
 ) *ResetPasswordHandler {
-	return &ResetPasswordHandler{
-		log:     log,
-		ms:      ms,
-		rp:      rp,
-		ls:      ls,
-		appInfo: appInfo,
-		us:      us,
+	h := &ResetPasswordHandler{
+		log:      log,
+		ms:       ms,
+		rp:       rp,
+		ls:       ls,
+		errs:     newErrorRegistry(log, ls),
+		appInfo:  appInfo,
+		us:       us,
+		byEmail:  byEmail,
+		byIp:     byIp,
+		requests: make(chan resetRequest, resetQueueSize),
+	}
+	for i := 0; i < resetWorkers; i++ {
+		go h.processRequests()
 	}
+	return h
 }
 
+// SendCode отправляет код и ссылку для сброса пароля. Ответ всегда 200, чтобы по нему нельзя было узнать,
+// зарегистрирован ли email: поиск пользователя, выдача кода и письмо выполняются в фоне, а обработчик
+// для любого адреса только ставит запрос в очередь
 func (h *ResetPasswordHandler) SendCode(c *gin.Context) {
 	var resPassDto dto.ResetPassword
 
-	lang := c.GetHeader("Accept-Language")
+	lang := localizer.FromContext(c)
 
-	if msg, ok := validate.IsValidBody(c, &resPassDto, h.ls); !ok {
-		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, msg)
+	if verr, ok := validate.IsValidBody(c, &resPassDto, h.ls); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
 		return
 	}
 
-	user, ok := h.us.GetByEmail(resPassDto.Email)
-	if !ok {
-		msg := h.ls.GetMessage(
-			localizer.UserNotFoundByEmail,
-			lang,
-			"User not found",
-			map[string]interface{}{
-				"email": resPassDto.Email,
-			},
-		)
-		responseutil.ErrorResponse(c, http.StatusNotFound, errormsg.NotFound, msg)
-		return
-	}
-
-	code, err := h.rp.GenerateAndSaveCode(user.Id.Int64)
-	if err != nil {
-		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "server error")
+	if rateLimited(
+		c,
+		h.ls,
+		lang,
+		rateLimit{h.byIp, c.ClientIP()},
+		rateLimit{h.byEmail, strings.ToLower(resPassDto.Email)},
+	) {
 		return
 	}
 
-	subject := h.ls.GetMessage(
-		localizer.ResetPasswordSubject,
-		lang,
-		"Reset password",
-		map[string]interface{}{
-			"appName": h.appInfo.AppName,
-		})
-
-	letter := h.ls.GetMessage(
-		localizer.ResetPasswordEmail,
-		lang,
-		"Enter code: "+code.Code,
-		map[string]interface{}{
-			"appName":        h.appInfo.AppName,
-			"appSupportLink": h.appInfo.SupportLink,
-			"code":           code.Code,
-		})
-
-	if err := h.ms.SendMailFromApp(subject, letter, resPassDto.Email); err != nil {
-		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "server error")
-		return
+	select {
+	case h.requests <- resetRequest{email: resPassDto.Email, lang: lang, explicitLang: localizer.IsExplicit(c)}:
+	default:
+		h.log.Warn("очередь запросов сброса пароля переполнена, запрос отброшен")
 	}
 
 	responseutil.SuccessResponse(c, http.StatusOK, nil)
 }
 
-func (h *ResetPasswordHandler) EditPassword(c *gin.Context) {
-	var enterCode dto.EnterCodeResetPassword
+func (h *ResetPasswordHandler) processRequests() {
+	for req := range h.requests {
+		h.sendCode(req)
+	}
+}
 
-	if msg, ok := validate.IsValidBody(c, &enterCode, h.ls); !ok {
-		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, msg)
+// sendCode выдает код и ставит письмо в очередь. Для неизвестного email ничего не делает
+func (h *ResetPasswordHandler) sendCode(req resetRequest) {
+	user, ok := h.us.GetByEmail(req.email)
+	if !ok {
+		h.log.Debug("запрос сброса пароля для неизвестного email")
 		return
 	}
 
-	lang := c.GetHeader("Accept-Language")
-
-	code, err := h.rp.GetCode(enterCode.Code)
+	code, token, err := h.rp.GenerateAndSaveCode(user.Id.Int64)
 	if err != nil {
-		msg := h.ls.GetMessage(
-			localizer.InvalidResetPasswordCode,
-			lang,
-			"Invalid code",
-			nil,
-		)
-		responseutil.ErrorResponse(c, http.StatusNotFound, errormsg.NotFound, msg)
+		h.log.Error("ошибка при выдаче кода сброса пароля: ", err)
 		return
 	}
 
-	if code.IsExpired() || !code.IsValid {
-		msg := h.ls.GetMessage(
-			localizer.CodeExpired,
-			lang,
-			"Code expired",
-			nil,
-		)
-		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.CodeExpired, msg)
-		return
+	data := map[string]interface{}{
+		"appName":        h.appInfo.AppName,
+		"appSupportLink": h.appInfo.SupportLink,
+		"code":           code.Code,
+		"url":            fmt.Sprintf("%s/reset-password?token=%s", h.appInfo.AppUrl, url.QueryEscape(token)),
+		"expiresMinutes": expiresMinutes(code.ExpiredAt),
 	}
 
-	if err := h.us.EditPassword(code.UserId, enterCode.NewPassword); err != nil {
-		if err.Error() == errormsg.LastPasswordIsExists {
-			msg := h.ls.GetMessage(
-				localizer.LastPasswords,
-				lang,
-				"The new password should not be equal to the last two",
-				nil,
-			)
-			responseutil.ErrorResponse(c, http.StatusBadRequest, err.Error(), msg)
+	mailLang := mailLanguage(req, user.PreferredLocale())
+
+	if _, err := h.ms.Enqueue(mailer.TemplateResetPassword, mailLang, data, user.Email); err != nil {
+		if errors.Is(err, services.ErrEmailSuppressed) {
 			return
 		}
-		responseutil.ErrorResponse(c, http.StatusInternalServerError, errormsg.ServerInternalError, "server error")
+		h.log.Error("ошибка при постановке письма сброса пароля в очередь: ", err)
+	}
+}
+
+// EditPassword смена пароля по коду с email или по токену из ссылки. После смены все коды сброса аннулируются
+func (h *ResetPasswordHandler) EditPassword(c *gin.Context) {
+	var enterCode dto.EnterCodeResetPassword
+
+	if verr, ok := validate.IsValidBody(c, &enterCode, h.ls); !ok {
+		responseutil.ErrorResponse(c, http.StatusBadRequest, errormsg.InvalidBody, verr.Message, verr.Details()...)
 		return
 	}
 
-	code.IsValid = false
-	if err := h.rp.SetIsValid(code.Code, false); err != nil {
-		h.log.Error("ошибка при обнавлении статуса кода: ", err)
+	var code *entity.ResetPasswordCode
+	var err error
+	if enterCode.Token != "" {
+		code, err = h.rp.VerifyToken(enterCode.Token)
+	} else if user, ok := h.us.GetByEmail(enterCode.Email); ok {
+		code, err = h.rp.VerifyCode(user.Id.Int64, enterCode.Code)
+	} else {
+		err = services.ErrInvalidResetCode
+	}
+
+	if err == nil {
+		err = h.rp.ResetPassword(code, enterCode.NewPassword)
+	}
+	if err != nil {
+		h.errs.
+			WithMessage(
+				services.ErrCodeAttemptsExceeded,
+				localizer.ResetCodeAttemptsExceeded,
+				"Too many wrong attempts, the code is no longer valid. Request a new code",
+			).
+			Respond(c, err, nil)
 		return
 	}
 
 	responseutil.SuccessResponse(c, http.StatusOK, nil)
 }
+
+// mailLanguage язык письма: явно выбранный в запросе, затем язык из профиля, затем язык запроса
+func mailLanguage(req resetRequest, preferred string) string {
+	if req.explicitLang || preferred == "" {
+		return req.lang
+	}
+
+	return preferred
+}
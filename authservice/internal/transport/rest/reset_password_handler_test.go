@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EddyZe/foodApp/authservice/internal/util/ratelimit"
+	"github.com/EddyZe/foodApp/common/middleware"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestResetPasswordHandler(t *testing.T, byEmail, byIp *ratelimit.Limiter) *ResetPasswordHandler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	log := logrus.NewEntry(logrus.New())
+	lms := localizer.NewLocalizeService(log, "../../../locales")
+
+	// без фоновых обработчиков: запросы остаются в очереди, и тест видит, что в нее попало
+	return &ResetPasswordHandler{
+		log:      log,
+		ls:       lms,
+		errs:     newErrorRegistry(log, lms),
+		byEmail:  byEmail,
+		byIp:     byIp,
+		requests: make(chan resetRequest, 1),
+	}
+}
+
+func sendResetCode(h *ResetPasswordHandler, email string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.POST("/reset-password", h.SendCode)
+
+	req := httptest.NewRequest(http.MethodPost, "/reset-password", strings.NewReader(`{"email":"`+email+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.1:5000"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// Обработчик не обращается к сервисам: любой адрес только ставится в очередь, поэтому ответ и время
+// ответа не зависят от того, зарегистрирован ли email
+func TestSendCodeQueuesRequest(t *testing.T) {
+	h := newTestResetPasswordHandler(t, ratelimit.New(0, time.Minute), ratelimit.New(0, time.Minute))
+
+	w := sendResetCode(h, "Ivan@mail.ru")
+	if w.Code != http.StatusOK {
+		t.Fatalf("ожидался код 200, получен %d: %s", w.Code, w.Body.String())
+	}
+
+	select {
+	case req := <-h.requests:
+		if req.email != "Ivan@mail.ru" {
+			t.Fatalf("email = %q", req.email)
+		}
+	default:
+		t.Fatal("запрос не поставлен в очередь")
+	}
+
+	// переполненная очередь не меняет ответ
+	h.requests <- resetRequest{email: "other@mail.ru"}
+	if w := sendResetCode(h, "ivan@mail.ru"); w.Code != http.StatusOK {
+		t.Fatalf("ожидался код 200 при переполненной очереди, получен %d", w.Code)
+	}
+}
+
+func TestSendCodeRateLimit(t *testing.T) {
+	cases := []struct {
+		name    string
+		exhaust func(byEmail, byIp *ratelimit.Limiter)
+	}{
+		{"по email без учета регистра", func(byEmail, _ *ratelimit.Limiter) { byEmail.Allow("ivan@mail.ru") }},
+		{"по IP", func(_, byIp *ratelimit.Limiter) { byIp.Allow("192.0.2.1") }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			byEmail := ratelimit.New(1, time.Minute)
+			byIp := ratelimit.New(1, time.Minute)
+			tc.exhaust(byEmail, byIp)
+			h := newTestResetPasswordHandler(t, byEmail, byIp)
+
+			if w := sendResetCode(h, "Ivan@mail.ru"); w.Code != http.StatusTooManyRequests {
+				t.Fatalf("ожидался код 429, получен %d: %s", w.Code, w.Body.String())
+			}
+			if len(h.requests) != 0 {
+				t.Fatal("запрос сверх лимита не должен попадать в очередь")
+			}
+		})
+	}
+}
+
+func TestMailLanguage(t *testing.T) {
+	cases := []struct {
+		name      string
+		req       resetRequest
+		preferred string
+		want      string
+	}{
+		{"язык из профиля важнее Accept-Language", resetRequest{lang: "en"}, "ru", "ru"},
+		{"явный выбор важнее профиля", resetRequest{lang: "en", explicitLang: true}, "ru", "en"},
+		{"язык в профиле не выбран", resetRequest{lang: "en"}, "", "en"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := mailLanguage(tc.req, tc.preferred); got != tc.want {
+				t.Errorf("mailLanguage = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSendCodeKeepsExplicitLang(t *testing.T) {
+	h := newTestResetPasswordHandler(t, ratelimit.New(0, time.Minute), ratelimit.New(0, time.Minute))
+	r := gin.New()
+	r.Use(middleware.Locale(h.ls))
+	r.POST("/reset-password", h.SendCode)
+
+	req := httptest.NewRequest(http.MethodPost, "/reset-password?lang=en", strings.NewReader(`{"email":"ivan@mail.ru"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "ru")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ожидался код 200, получен %d: %s", w.Code, w.Body.String())
+	}
+
+	queued := <-h.requests
+	if queued.lang != "en" || !queued.explicitLang {
+		t.Fatalf("в очереди язык %q, явный %v", queued.lang, queued.explicitLang)
+	}
+}
@@ -0,0 +1,31 @@
+package auditaction
+
+const (
+	RoleCreate            = "ROLE_CREATE"
+	RoleUpdate            = "ROLE_UPDATE"
+	RoleDelete            = "ROLE_DELETE"
+	PermissionCreate      = "PERMISSION_CREATE"
+	PermissionUpdate      = "PERMISSION_UPDATE"
+	PermissionDelete      = "PERMISSION_DELETE"
+	PermissionAttach      = "PERMISSION_ATTACH"
+	PermissionDetach      = "PERMISSION_DETACH"
+	UserRoleGrant         = "USER_ROLE_GRANT"
+	UserRoleRevoke        = "USER_ROLE_REVOKE"
+	UserBan               = "USER_BAN"
+	UserUnban             = "USER_UNBAN"
+	BanAppealCreate       = "BAN_APPEAL_CREATE"
+	BanAppealReview       = "BAN_APPEAL_REVIEW"
+	AccountDelete         = "ACCOUNT_DELETE"
+	InviteCreate          = "INVITE_CREATE"
+	InviteRevoke          = "INVITE_REVOKE"
+	ConsentPublish        = "CONSENT_PUBLISH"
+	DataExport            = "DATA_EXPORT"
+	MailRetry             = "MAIL_RETRY"
+	MailSuppressionDelete = "MAIL_SUPPRESSION_DELETE"
+	// ImpersonationStart выдан токен имперсонации
+	ImpersonationStart = "IMPERSONATION_START"
+	// ImpersonationEnd администратор завершил сессию имперсонации
+	ImpersonationEnd = "IMPERSONATION_END"
+	// ImpersonatedRequest запрос, выполненный с токеном имперсонации
+	ImpersonatedRequest = "IMPERSONATED_REQUEST"
+)
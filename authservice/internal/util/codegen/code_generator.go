@@ -1,17 +1,21 @@
 package codegen
 
 import (
-	"math/rand"
-	"time"
+	"crypto/rand"
+	"math/big"
 )
 
 func GenerateRandomCode(length int) string {
-	seededRand := rand.New(rand.NewSource(time.Now().UnixNano()))
 	chars := "1234567890QWERTYUIOPASDFGHJKLZXCVBNMqwertyuiopasdfghjklzxcvbnm"
+	max := big.NewInt(int64(len(chars)))
 
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = chars[seededRand.Intn(len(chars))]
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = chars[n.Int64()]
 	}
 
 	return string(b)
@@ -1,17 +1,36 @@
 package errormsg
 
 const (
-	NotFound               = "NOT_FOUND"
-	IsExists               = "IS_ALREADY_EXISTS"
-	LastPasswordIsExists   = "LAST_PASSWORD_IS_EXISTS"
-	InvalidEmailOrPassword = "INVALID_EMAIL_OR_PASSWORD"
-	ServerInternalError    = "SERVER_INTERNAL_ERROR"
-	Unauthorized           = "UNAUTHORIZED"
-	AccountIsBlocked       = "ACCOUNT_IS_BLOCKED"
-	UserIsAlreadyBlocked   = "USER_IS_ALREADY_BLOCKED"
-	InvalidBody            = "INVALID_BODY"
-	InvalidEmailCode       = "INVALID_EMAIL_CODE"
-	EmailIsConfirmed       = "EMAIL_IS_CONFIRMED"
-	CodeExpired            = "CODE_EXPIRED"
-	UserIsBlockedExists    = "USER_IS_BLOCKED"
+	NotFound                = "NOT_FOUND"
+	IsExists                = "IS_ALREADY_EXISTS"
+	LastPasswordIsExists    = "LAST_PASSWORD_IS_EXISTS"
+	InvalidEmailOrPassword  = "INVALID_EMAIL_OR_PASSWORD"
+	ServerInternalError     = "SERVER_INTERNAL_ERROR"
+	Unauthorized            = "UNAUTHORIZED"
+	AccountIsBlocked        = "ACCOUNT_IS_BLOCKED"
+	UserIsAlreadyBlocked    = "USER_IS_ALREADY_BLOCKED"
+	InvalidBody             = "INVALID_BODY"
+	InvalidEmailCode        = "INVALID_EMAIL_CODE"
+	EmailIsConfirmed        = "EMAIL_IS_CONFIRMED"
+	CodeExpired             = "CODE_EXPIRED"
+	UserIsBlockedExists     = "USER_IS_BLOCKED"
+	RoleIsProtected         = "ROLE_IS_PROTECTED"
+	OwnAdminRole            = "OWN_ADMIN_ROLE"
+	LastAdmin               = "LAST_ADMIN"
+	InvalidParam            = "INVALID_PARAM"
+	UserIsNotBlocked        = "USER_IS_NOT_BLOCKED"
+	AppealIsReviewed        = "APPEAL_IS_REVIEWED"
+	ImpersonationNotAllowed = "IMPERSONATION_NOT_ALLOWED"
+	RegistrationRestricted  = "REGISTRATION_RESTRICTED"
+	InvalidInviteCode       = "INVALID_INVITE_CODE"
+	InviteRoleNotAllowed    = "INVITE_ROLE_NOT_ALLOWED"
+	ConsentRequired         = "CONSENT_REQUIRED"
+	CodeResendCooldown      = "CODE_RESEND_COOLDOWN"
+	CodeLimitExceeded       = "CODE_LIMIT_EXCEEDED"
+	CodeAttemptsExceeded    = "CODE_ATTEMPTS_EXCEEDED"
+	TooManyRequests         = "TOO_MANY_REQUESTS"
+	InvalidResetCode        = "INVALID_RESET_CODE"
+	EmailUndeliverable      = "EMAIL_UNDELIVERABLE"
+	Forbidden               = "FORBIDDEN"
+	ServiceUnavailable      = "SERVICE_UNAVAILABLE"
 )
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter ограничивает число событий по ключу (email, IP) в фиксированном окне.
+// Счетчики хранятся в памяти процесса, поэтому на нескольких репликах лимит действует на каждой отдельно
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+type counter struct {
+	count   int
+	resetAt time.Time
+}
+
+// New лимитер на limit событий за window. limit <= 0 - ограничение выключено
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:    limit,
+		window:   window,
+		now:      time.Now,
+		counters: make(map[string]*counter),
+	}
+}
+
+// Allow учитывает событие по ключу. Если лимит исчерпан, событие не учитывается,
+// а вторым значением возвращается время до начала нового окна
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	c, ok := l.counters[key]
+	if !ok || !now.Before(c.resetAt) {
+		l.counters[key] = &counter{count: 1, resetAt: now.Add(l.window)}
+		return true, 0
+	}
+	if c.count >= l.limit {
+		return false, c.resetAt.Sub(now)
+	}
+	c.count++
+	return true, 0
+}
+
+// sweep раз в окно удаляет счетчики, окно которых закончилось
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, c := range l.counters {
+		if !now.Before(c.resetAt) {
+			delete(l.counters, key)
+		}
+	}
+}
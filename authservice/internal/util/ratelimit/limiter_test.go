@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("ivan@mail.ru"); !ok {
+			t.Fatalf("попытка %d отклонена", i+1)
+		}
+	}
+
+	now = now.Add(20 * time.Second)
+	ok, retryAfter := l.Allow("ivan@mail.ru")
+	if ok {
+		t.Fatal("попытка сверх лимита разрешена")
+	}
+	if retryAfter != 40*time.Second {
+		t.Errorf("retryAfter: ожидалось 40s, получено %v", retryAfter)
+	}
+
+	if ok, _ := l.Allow("petr@mail.ru"); !ok {
+		t.Error("лимит одного ключа затронул другой")
+	}
+
+	now = now.Add(40 * time.Second)
+	if ok, _ := l.Allow("ivan@mail.ru"); !ok {
+		t.Error("в новом окне попытка отклонена")
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := New(0, time.Minute)
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow("ivan@mail.ru"); !ok {
+			t.Fatal("выключенный лимитер отклонил попытку")
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package signedtoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+const separator = "|"
+
+// Sign подписывает значения HMAC-SHA256. Формат: base64url(значения через |).base64url(подпись)
+func Sign(secret string, values ...string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(strings.Join(values, separator)))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac(secret, payload))
+}
+
+// Verify проверяет подпись и возвращает подписанные значения
+func Verify(secret, token string) ([]string, bool) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, false
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, mac(secret, payload)) {
+		return nil, false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, false
+	}
+
+	return strings.Split(string(data), separator), true
+}
+
+func mac(secret, payload string) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
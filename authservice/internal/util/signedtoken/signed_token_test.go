@@ -0,0 +1,47 @@
+package signedtoken
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	token := Sign("secret", "password_reset", "15", "42", "1750000000")
+
+	values, ok := Verify("secret", token)
+	if !ok {
+		t.Fatal("подпись не прошла проверку")
+	}
+	if want := []string{"password_reset", "15", "42", "1750000000"}; !slices.Equal(values, want) {
+		t.Fatalf("values = %v, want %v", values, want)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	token := Sign("secret", "password_reset", "15", "42")
+	payload, signature, _ := strings.Cut(token, ".")
+	other := Sign("secret", "password_reset", "16", "42")
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	cases := []struct {
+		name   string
+		secret string
+		token  string
+	}{
+		{"другой ключ", "other", token},
+		{"подмененные значения", "secret", otherPayload + "." + signature},
+		{"без подписи", "secret", payload},
+		{"пустая подпись", "secret", payload + "."},
+		{"подпись не base64", "secret", payload + ".***"},
+		{"пустой токен", "secret", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, ok := Verify(tc.secret, tc.token); ok {
+				t.Fatal("токен должен быть отклонен")
+			}
+		})
+	}
+}
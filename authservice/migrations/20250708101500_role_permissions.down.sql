@@ -0,0 +1,3 @@
+delete
+from auth.permission
+where name in ('users:read', 'users:ban', 'users:unban', 'roles:manage');
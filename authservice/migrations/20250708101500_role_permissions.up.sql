@@ -0,0 +1,15 @@
+--разрешения ролей
+insert into auth.permission(name, description)
+VALUES ('users:read', 'Просмотр пользователей'),
+       ('users:ban', 'Блокировка пользователей'),
+       ('users:unban', 'Разблокировка пользователей'),
+       ('roles:manage', 'Управление ролями и разрешениями')
+on conflict (name) do nothing;
+
+insert into auth.role_permission(role_id, permission_id)
+select r.id, p.id
+from auth.role r
+         cross join auth.permission p
+where r.name = 'admin'
+  and p.name in ('users:read', 'users:ban', 'users:unban', 'roles:manage')
+on conflict do nothing;
@@ -0,0 +1,3 @@
+alter table auth.audit_log
+    drop column if exists actor_id,
+    drop column if exists details;
@@ -0,0 +1,6 @@
+--кто совершил действие и его детали
+alter table auth.audit_log
+    add column if not exists actor_id bigint references auth.users (id) on delete set null,
+    add column if not exists details  jsonb;
+
+create index on auth.audit_log (actor_id);
@@ -0,0 +1,88 @@
+drop table if exists auth.ban_appeals cascade;
+
+--в старой схеме у пользователя может быть только одна блокировка
+delete
+from auth.users_ban
+where unbanned_at is not null
+   or id not in (select max(id) from auth.users_ban group by user_id);
+
+alter table auth.users_ban
+    drop column if exists issued_by,
+    drop column if exists reason_category,
+    drop column if exists unbanned_at,
+    drop column if exists unbanned_by,
+    drop column if exists unban_reason;
+
+alter table auth.users_ban
+    add constraint users_ban_user_id_key unique (user_id);
+
+create or replace function auth.clean_expired_data()
+    returns void as
+$$
+begin
+    loop
+        delete
+        from auth.refresh_token
+        where id in (select id
+                     from auth.refresh_token
+                     where expired_at < NOW()
+                     limit 1000);
+        exit when not found;
+        perform pg_sleep(0.1);
+    end loop;
+
+    loop
+        delete
+        from auth.access_token
+        where token in (select token
+                        from auth.access_token
+                        where expired_at < now()
+                        limit 1000);
+        exit when not found;
+    end loop;
+
+    loop
+        delete
+        from auth.black_list_token
+        where token in (select token
+                        from auth.black_list_token
+                        where expired_at < NOW()
+                        limit 1000);
+        exit when not found;
+        perform pg_sleep(0.1);
+    end loop;
+
+    loop
+        delete
+        from auth.email_verification_codes
+        where id in (select id
+                     from auth.email_verification_codes
+                     where expired_at < NOW()
+                     limit 1000);
+        exit when not found;
+        perform pg_sleep(0.1);
+    end loop;
+
+    loop
+        delete
+        from auth.reset_password_codes
+        where id in (select id
+                     from auth.reset_password_codes
+                     where expired_at < NOW()
+                     limit 1000);
+        exit when not found;
+        perform pg_sleep(0.1);
+    end loop;
+
+    loop
+        delete
+        from auth.users_ban
+        where id in (select id
+                     from auth.users_ban
+                     where expired_at < NOW()
+                     limit 1000);
+        exit when not found;
+        perform pg_sleep(0.1);
+    end loop;
+end;
+$$ language plpgsql;
@@ -0,0 +1,93 @@
+--история блокировок: строки больше не удаляются, а закрываются
+alter table auth.users_ban
+    drop constraint if exists users_ban_user_id_key;
+
+alter table auth.users_ban
+    add column if not exists issued_by       bigint references auth.users (id) on delete set null,
+    add column if not exists reason_category varchar(64) not null default 'other',
+    add column if not exists unbanned_at     timestamp,
+    add column if not exists unbanned_by     bigint references auth.users (id) on delete set null,
+    add column if not exists unban_reason    varchar(1024);
+
+create index on auth.users_ban (user_id, created_at);
+--открытая блокировка у пользователя одна. Истекшие, но не закрытые шедулером блокировки тоже считаются
+--открытыми: now() в условии индекса недопустим, поэтому перед новой блокировкой они закрываются
+create unique index users_ban_open_user_id_key on auth.users_ban (user_id) where unbanned_at is null;
+create index on auth.users_ban (expired_at) where unbanned_at is null and is_forever = false;
+
+create table if not exists auth.ban_appeals
+(
+    id             bigserial primary key,
+    ban_id         bigint        not null references auth.users_ban (id) on delete cascade,
+    user_id        bigint        not null references auth.users (id) on delete cascade,
+    message        varchar(2048) not null,
+    status         varchar(32)   not null default 'pending',
+    reviewed_by    bigint references auth.users (id) on delete set null,
+    review_comment varchar(1024),
+    created_at     timestamp     not null default now(),
+    reviewed_at    timestamp
+);
+
+create index on auth.ban_appeals (status, created_at);
+create unique index on auth.ban_appeals (ban_id) where status = 'pending';
+
+--истекшие блокировки закрывает шедулер, чтобы отправить событие UserUnbanned
+create or replace function auth.clean_expired_data()
+    returns void as
+$$
+begin
+    loop
+        delete
+        from auth.refresh_token
+        where id in (select id
+                     from auth.refresh_token
+                     where expired_at < NOW()
+                     limit 1000);
+        exit when not found;
+        perform pg_sleep(0.1);
+    end loop;
+
+    loop
+        delete
+        from auth.access_token
+        where token in (select token
+                        from auth.access_token
+                        where expired_at < now()
+                        limit 1000);
+        exit when not found;
+    end loop;
+
+    loop
+        delete
+        from auth.black_list_token
+        where token in (select token
+                        from auth.black_list_token
+                        where expired_at < NOW()
+                        limit 1000);
+        exit when not found;
+        perform pg_sleep(0.1);
+    end loop;
+
+    loop
+        delete
+        from auth.email_verification_codes
+        where id in (select id
+                     from auth.email_verification_codes
+                     where expired_at < NOW()
+                     limit 1000);
+        exit when not found;
+        perform pg_sleep(0.1);
+    end loop;
+
+    loop
+        delete
+        from auth.reset_password_codes
+        where id in (select id
+                     from auth.reset_password_codes
+                     where expired_at < NOW()
+                     limit 1000);
+        exit when not found;
+        perform pg_sleep(0.1);
+    end loop;
+end;
+$$ language plpgsql;
@@ -0,0 +1,5 @@
+alter table auth.black_list_token
+    alter column token type varchar(256);
+
+alter table auth.access_token
+    alter column token type varchar(256);
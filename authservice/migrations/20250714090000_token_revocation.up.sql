@@ -0,0 +1,6 @@
+--jwt с разрешениями в scope не помещается в 256 символов
+alter table auth.access_token
+    alter column token type text;
+
+alter table auth.black_list_token
+    alter column token type text;
@@ -0,0 +1,3 @@
+delete
+from auth.permission
+where name = 'users:impersonate';
@@ -0,0 +1,12 @@
+--разрешение на вход от имени пользователя
+insert into auth.permission(name, description)
+VALUES ('users:impersonate', 'Вход от имени пользователя')
+on conflict (name) do nothing;
+
+insert into auth.role_permission(role_id, permission_id)
+select r.id, p.id
+from auth.role r
+         cross join auth.permission p
+where r.name = 'admin'
+  and p.name = 'users:impersonate'
+on conflict do nothing;
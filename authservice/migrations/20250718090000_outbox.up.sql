@@ -0,0 +1,18 @@
+--доменные события, записанные в одной транзакции с изменением данных
+create table if not exists auth.outbox
+(
+    id              bigserial primary key,
+    aggregate_key   varchar(128) not null,
+    event_type      varchar(128) not null,
+    topic           varchar(256) not null,
+    payload         jsonb        not null,
+    created_at      timestamp    not null default now(),
+    attempts        int          not null default 0,
+    next_attempt_at timestamp    not null default now(),
+    last_error      text,
+    published_at    timestamp
+);
+
+create index on auth.outbox (id) where published_at is null;
+create index on auth.outbox (aggregate_key, id) where published_at is null;
+create index on auth.outbox (published_at) where published_at is not null;
@@ -0,0 +1,2 @@
+alter table auth.outbox
+    drop column if exists dead_at;
@@ -0,0 +1,19 @@
+--сообщения outbox, которые нельзя отправить (не разбираются как конверт), откладываются
+--в dead-letter: остаются в таблице для разбора и не блокируют остальные события пользователя
+alter table auth.outbox
+    add column if not exists dead_at timestamp;
+
+create index on auth.outbox (dead_at) where dead_at is not null;
+
+--неотправленные записи первой версии outbox хранят полезную нагрузку без конверта, упаковываем их
+update auth.outbox
+set payload = jsonb_build_object(
+        'id', gen_random_uuid()::text,
+        'type', event_type,
+        'version', 1,
+        'occurred_at', to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US') || 'Z',
+        'key', aggregate_key,
+        'payload', payload
+              )
+where published_at is null
+  and not (payload ? 'id' and payload ? 'type' and payload ? 'payload');
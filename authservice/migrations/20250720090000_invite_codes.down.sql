@@ -0,0 +1,7 @@
+delete
+from auth.permission
+where name = 'invites:manage';
+
+drop table if exists auth.invite_code_usages cascade;
+drop table if exists auth.invite_code_roles cascade;
+drop table if exists auth.invite_codes cascade;
@@ -0,0 +1,42 @@
+--инвайт-коды для закрытой регистрации
+create table if not exists auth.invite_codes
+(
+    id         bigserial primary key,
+    code       varchar(64) not null unique,
+    max_uses   int         not null check (max_uses > 0),
+    used_count int         not null default 0 check (used_count <= max_uses),
+    expired_at timestamp,
+    created_by bigint references auth.users (id) on delete set null,
+    created_at timestamp   not null default now(),
+    revoked_at timestamp
+);
+
+--роли, которые получает пользователь, зарегистрированный по коду
+create table if not exists auth.invite_code_roles
+(
+    invite_id bigint not null references auth.invite_codes (id) on delete cascade,
+    role_id   bigint not null references auth.role (id) on delete cascade,
+    primary key (invite_id, role_id)
+);
+
+create table if not exists auth.invite_code_usages
+(
+    id        bigserial primary key,
+    invite_id bigint    not null references auth.invite_codes (id) on delete cascade,
+    user_id   bigint references auth.users (id) on delete set null,
+    used_at   timestamp not null default now()
+);
+
+create index on auth.invite_code_usages (invite_id, used_at);
+
+insert into auth.permission(name, description)
+VALUES ('invites:manage', 'Управление инвайт-кодами')
+on conflict (name) do nothing;
+
+insert into auth.role_permission(role_id, permission_id)
+select r.id, p.id
+from auth.role r
+         cross join auth.permission p
+where r.name = 'admin'
+  and p.name = 'invites:manage'
+on conflict do nothing;
@@ -0,0 +1,6 @@
+delete
+from auth.permission
+where name = 'consents:manage';
+
+drop table if exists auth.user_consents cascade;
+drop table if exists auth.consent_documents cascade;
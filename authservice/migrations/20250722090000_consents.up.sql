@@ -0,0 +1,35 @@
+--версии пользовательского соглашения и политики конфиденциальности
+create table if not exists auth.consent_documents
+(
+    id           bigserial primary key,
+    type         varchar(64)   not null,
+    version      varchar(64)   not null,
+    url          varchar(1024) not null,
+    published_by bigint references auth.users (id) on delete set null,
+    published_at timestamp     not null default now(),
+    unique (type, version)
+);
+
+create index on auth.consent_documents (type, published_at);
+
+--какую версию документа и когда принял пользователь
+create table if not exists auth.user_consents
+(
+    id          bigserial primary key,
+    user_id     bigint    not null references auth.users (id) on delete cascade,
+    document_id bigint    not null references auth.consent_documents (id) on delete cascade,
+    accepted_at timestamp not null default now(),
+    unique (user_id, document_id)
+);
+
+insert into auth.permission(name, description)
+VALUES ('consents:manage', 'Публикация версий соглашений')
+on conflict (name) do nothing;
+
+insert into auth.role_permission(role_id, permission_id)
+select r.id, p.id
+from auth.role r
+         cross join auth.permission p
+where r.name = 'admin'
+  and p.name = 'consents:manage'
+on conflict do nothing;
@@ -0,0 +1,4 @@
+drop index if exists auth.email_verification_codes_user_id_created_at_idx;
+
+alter table auth.email_verification_codes
+    drop column if exists failed_attempts;
@@ -0,0 +1,5 @@
+--число неверных попыток ввода кода, после лимита код аннулируется
+alter table auth.email_verification_codes
+    add column if not exists failed_attempts int not null default 0;
+
+create index on auth.email_verification_codes (user_id, created_at);
@@ -0,0 +1,11 @@
+drop index if exists auth.reset_password_codes_user_id_created_at_idx;
+
+alter table auth.reset_password_codes
+    drop column if exists failed_attempts;
+
+delete
+from auth.reset_password_codes
+where id not in (select min(id) from auth.reset_password_codes group by code);
+
+alter table auth.reset_password_codes
+    add constraint reset_password_codes_code_key unique (code);
@@ -0,0 +1,8 @@
+--код сброса привязан к пользователю и может совпадать у разных пользователей
+alter table auth.reset_password_codes
+    drop constraint if exists reset_password_codes_code_key;
+
+alter table auth.reset_password_codes
+    add column if not exists failed_attempts int not null default 0;
+
+create index on auth.reset_password_codes (user_id, created_at);
@@ -0,0 +1,5 @@
+delete
+from auth.permission
+where name = 'mail:manage';
+
+drop table if exists auth.mail_queue cascade;
@@ -0,0 +1,34 @@
+--очередь исходящих писем. Письмо хранится уже отрендеренным, worker только доставляет его
+create table if not exists auth.mail_queue
+(
+    id              bigserial primary key,
+    template        varchar(128)  not null,
+    sender          varchar(512)  not null,
+    recipients      text[]        not null,
+    subject         varchar(1024) not null,
+    html_body       text          not null,
+    text_body       text          not null,
+    --pending, sending, sent или dead (попытки исчерпаны)
+    status          varchar(16)   not null default 'pending',
+    attempts        int           not null default 0,
+    next_attempt_at timestamp     not null default now(),
+    last_error      text,
+    created_at      timestamp     not null default now(),
+    sent_at         timestamp
+);
+
+create index on auth.mail_queue (next_attempt_at, id) where status in ('pending', 'sending');
+create index on auth.mail_queue (status, id);
+create index on auth.mail_queue (sent_at) where status = 'sent';
+
+insert into auth.permission(name, description)
+VALUES ('mail:manage', 'Просмотр очереди писем и повторная отправка')
+on conflict (name) do nothing;
+
+insert into auth.role_permission(role_id, permission_id)
+select r.id, p.id
+from auth.role r
+         cross join auth.permission p
+where r.name = 'admin'
+  and p.name = 'mail:manage'
+on conflict do nothing;
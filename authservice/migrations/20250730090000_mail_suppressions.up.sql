@@ -0,0 +1,13 @@
+--адреса, на которые нельзя отправлять письма: постоянный возврат или жалоба на спам
+create table if not exists auth.mail_suppressions
+(
+    id         bigserial primary key,
+    email      varchar(256) not null,
+    --bounce или complaint
+    reason     varchar(16)  not null,
+    detail     text,
+    created_at timestamp    not null default now(),
+    updated_at timestamp    not null default now()
+);
+
+create unique index on auth.mail_suppressions (lower(email));
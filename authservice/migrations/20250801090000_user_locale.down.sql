@@ -0,0 +1,2 @@
+alter table auth.users
+    drop column if exists locale;
@@ -0,0 +1,3 @@
+--предпочитаемый язык писем и сообщений, null - язык из запроса
+alter table auth.users
+    add column if not exists locale varchar(16);
@@ -0,0 +1 @@
+drop index if exists auth.mail_queue_dead_created_at_idx;
@@ -0,0 +1,7 @@
+--тексты доставленных писем больше не хранятся: в них коды подтверждения и ссылки сброса пароля
+update auth.mail_queue
+set html_body = '',
+    text_body = ''
+where status = 'sent';
+
+create index if not exists mail_queue_dead_created_at_idx on auth.mail_queue (created_at) where status = 'dead';
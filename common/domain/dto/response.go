@@ -12,6 +12,27 @@ type APIError struct {
 	Details interface{} `json:"details,omitempty"`
 }
 
+// Problem тело ошибки в формате RFC 7807 (application/problem+json).
+// Code и TraceId - расширения, Errors - ошибки по полям, как details в APIError
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code"`
+	TraceId  string      `json:"trace_id,omitempty"`
+	Errors   interface{} `json:"errors,omitempty"`
+}
+
+// FieldError ошибка валидации одного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
 type Message struct {
 	Message string `json:"message"`
 }
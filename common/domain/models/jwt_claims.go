@@ -1,10 +1,42 @@
 package models
 
+import "slices"
+
 type JwtClaims struct {
-	Ext           int64
-	Iat           int64
+	Ext int64
+	Iat int64
+	// IatMs время выдачи в миллисекундах (claim iat_ms). 0 - токен выдан до появления claim
+	IatMs         int64
 	Email         string
 	EmailVerified bool
 	Role          []string
+	Permissions   []string
 	Sub           int64
+	// Actor id администратора, если токен выдан через имперсонацию (claim act). 0 - обычный токен
+	Actor int64
+	// Locale предпочитаемый язык пользователя (claim locale), пустой - не выбран
+	Locale string
+}
+
+// HasRole проверяет наличие роли в токене
+func (c *JwtClaims) HasRole(role string) bool {
+	return slices.Contains(c.Role, role)
+}
+
+// HasPermission проверяет наличие разрешения в токене
+func (c *JwtClaims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// IssuedAtMilli время выдачи в миллисекундах. У старых токенов без iat_ms - начало секунды iat
+func (c *JwtClaims) IssuedAtMilli() int64 {
+	if c.IatMs > 0 {
+		return c.IatMs
+	}
+	return c.Iat * 1000
+}
+
+// IsImpersonated токен выдан администратору, действующему от имени пользователя
+func (c *JwtClaims) IsImpersonated() bool {
+	return c.Actor != 0
 }
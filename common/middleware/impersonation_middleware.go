@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"github.com/EddyZe/foodApp/common/domain/models"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// DenyImpersonation запрещает чувствительные операции (пароль, email, удаление аккаунта) из сессии имперсонации.
+// Должен вызываться после JwtFilter
+func DenyImpersonation(locliz *localizer.LocalizeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.Get("claims")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		claimsMap, ok := claims.(*models.JwtClaims)
+		if ok && claimsMap.IsImpersonated() {
+			errMsg := locliz.GetMessage(
+				localizer.ImpersonationForbidden,
+				localizer.FromContext(c),
+				"This action is not available in an impersonated session",
+				nil,
+			)
+
+			responseutil.ErrorResponse(c, http.StatusForbidden, "IMPERSONATION_FORBIDDEN", errMsg)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -4,16 +4,14 @@ import (
 	"github.com/EddyZe/foodApp/common/domain/models"
 	"github.com/EddyZe/foodApp/common/pkg/localizer"
 	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/EddyZe/foodApp/common/pkg/roles"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
 
 func IsAdmin(locliz *localizer.LocalizeService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		lang := c.GetHeader("Accept-Language")
-		if lang == "" {
-			lang = "en"
-		}
+		lang := localizer.FromContext(c)
 		claims, ok := c.Get("claims")
 		if !ok {
 			msg := locliz.GetMessage(
@@ -30,13 +28,9 @@ func IsAdmin(locliz *localizer.LocalizeService) gin.HandlerFunc {
 
 		claimsMap := claims.(*models.JwtClaims)
 
-		roles := claimsMap.Role
-
-		for _, role := range roles {
-			if role == "admin" {
-				c.Next()
-				return
-			}
+		if claimsMap.HasRole(roles.Admin) {
+			c.Next()
+			return
 		}
 
 		errMsg := locliz.GetMessage(
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"github.com/EddyZe/foodApp/common/domain/models"
 	"github.com/EddyZe/foodApp/common/pkg/jwtutil"
 	"github.com/EddyZe/foodApp/common/pkg/localizer"
 	"github.com/EddyZe/foodApp/common/pkg/responseutil"
@@ -8,9 +9,14 @@ import (
 	"net/http"
 )
 
-func JwtFilter(jwtsecret string, ls *localizer.LocalizeService) gin.HandlerFunc {
+// RevocationChecker проверяет, что валидный по подписи токен не был отозван (бан, выход со всех устройств)
+type RevocationChecker interface {
+	IsRevoked(token string, claims *models.JwtClaims) bool
+}
+
+func JwtFilter(jwtsecret string, ls *localizer.LocalizeService, checkers ...RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		lang := c.GetHeader("Accept-Language")
+		lang := localizer.FromContext(c)
 		tokenString, ok := jwtutil.ExtractBearerTokenHeader(c)
 		if !ok {
 			responseutil.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", getMsgUnAuthorized(ls, lang))
@@ -25,7 +31,19 @@ func JwtFilter(jwtsecret string, ls *localizer.LocalizeService) gin.HandlerFunc
 			return
 		}
 
+		for _, checker := range checkers {
+			if checker.IsRevoked(tokenString, claims) {
+				responseutil.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", getMsgUnAuthorized(ls, lang))
+				c.Abort()
+				return
+			}
+		}
+
 		c.Set("claims", claims)
+		preferUserLocale(c, ls, claims.Locale)
+		if claims.IsImpersonated() {
+			c.Set("actor", claims.Actor)
+		}
 		c.Next()
 	}
 }
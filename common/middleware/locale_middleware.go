@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const (
+	// LangParam параметр запроса и cookie для явного выбора языка
+	LangParam = "lang"
+
+	langCookieMaxAge = 365 * 24 * 60 * 60
+)
+
+// Locale определяет язык запроса и сохраняет его в контекст (localizer.FromContext). Порядок:
+// параметр lang (запоминается в cookie), cookie lang, Accept-Language с учетом q, язык по умолчанию.
+// Язык из профиля пользователя подставляет JwtFilter, если язык не выбран явно
+func Locale(ls *localizer.LocalizeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if lang, ok := ls.Match(c.Query(LangParam)); ok {
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(LangParam, lang, langCookieMaxAge, "/", "", isHTTPS(c), true)
+			localizer.SetContext(c, lang, true)
+			c.Next()
+			return
+		}
+
+		if cookie, err := c.Cookie(LangParam); err == nil {
+			if lang, ok := ls.Match(cookie); ok {
+				localizer.SetContext(c, lang, true)
+				c.Next()
+				return
+			}
+		}
+
+		lang, ok := ls.Match(c.GetHeader("Accept-Language"))
+		if !ok {
+			lang = ls.DefaultLanguage()
+		}
+		localizer.SetContext(c, lang, false)
+		c.Next()
+	}
+}
+
+// isHTTPS запрос пришел по https напрямую или через прокси. Только тогда cookie помечается Secure,
+// иначе браузер не вернет ее при локальной разработке по http
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
+
+// preferUserLocale язык из профиля пользователя важнее Accept-Language, но не явного выбора в запросе
+func preferUserLocale(c *gin.Context, ls *localizer.LocalizeService, locale string) {
+	if locale == "" || localizer.IsExplicit(c) {
+		return
+	}
+	if lang, ok := ls.Match(locale); ok {
+		localizer.SetContext(c, lang, false)
+	}
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLocalizer(t *testing.T) *localizer.LocalizeService {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"app.en.toml", "app.ru.toml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("[Hello]\nother = \"hello\"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return localizer.NewLocalizeService(logrus.NewEntry(logrus.New()), dir)
+}
+
+type localeResult struct {
+	lang     string
+	explicit bool
+	w        *httptest.ResponseRecorder
+}
+
+func serveLocale(t *testing.T, ls *localizer.LocalizeService, req *http.Request, userLocale string) localeResult {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var res localeResult
+	r := gin.New()
+	r.Use(Locale(ls))
+	r.GET("/", func(c *gin.Context) {
+		preferUserLocale(c, ls, userLocale)
+		res.lang = localizer.FromContext(c)
+		res.explicit = localizer.IsExplicit(c)
+	})
+
+	res.w = httptest.NewRecorder()
+	r.ServeHTTP(res.w, req)
+	return res
+}
+
+func TestLocale(t *testing.T) {
+	ls := newTestLocalizer(t)
+
+	withHeader := func(target, accept string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Language", accept)
+		}
+		return req
+	}
+	withCookie := func(lang, accept string) *http.Request {
+		req := withHeader("/", accept)
+		req.AddCookie(&http.Cookie{Name: LangParam, Value: lang})
+		return req
+	}
+
+	cases := []struct {
+		name       string
+		req        *http.Request
+		userLocale string
+		want       string
+		explicit   bool
+	}{
+		{"параметр важнее заголовка", withHeader("/?lang=ru", "en"), "", "ru", true},
+		{"cookie важнее заголовка", withCookie("ru", "en"), "", "ru", true},
+		{"неизвестный язык в cookie", withCookie("de", "ru"), "", "ru", false},
+		{"заголовок с весами", withHeader("/", "de;q=0.9,ru;q=0.8,en;q=0.1"), "", "ru", false},
+		{"язык по умолчанию", withHeader("/", "de"), "", "en", false},
+		{"профиль важнее заголовка", withHeader("/", "en"), "ru", "ru", false},
+		{"явный выбор важнее профиля", withHeader("/?lang=en", "ru"), "ru", "en", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := serveLocale(t, ls, tc.req, tc.userLocale)
+			if res.lang != tc.want || res.explicit != tc.explicit {
+				t.Fatalf("язык %q, явный %v; ожидалось %q, %v", res.lang, res.explicit, tc.want, tc.explicit)
+			}
+			if got := res.w.Header().Get("Content-Language"); got != tc.want {
+				t.Errorf("Content-Language = %q", got)
+			}
+		})
+	}
+}
+
+func TestLocaleCookie(t *testing.T) {
+	ls := newTestLocalizer(t)
+
+	cases := []struct {
+		name   string
+		proto  string
+		secure bool
+	}{
+		{"http", "", false},
+		{"https за прокси", "https", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?lang=ru", nil)
+			if tc.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tc.proto)
+			}
+			res := serveLocale(t, ls, req, "")
+
+			cookies := res.w.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Name != LangParam || cookies[0].Value != "ru" {
+				t.Fatalf("cookies = %v", cookies)
+			}
+			c := cookies[0]
+			if !c.HttpOnly || c.SameSite != http.SameSiteLaxMode || c.Secure != tc.secure {
+				t.Errorf("HttpOnly=%v SameSite=%v Secure=%v", c.HttpOnly, c.SameSite, c.Secure)
+			}
+		})
+	}
+
+	// неизвестный язык в параметре не запоминается
+	res := serveLocale(t, ls, httptest.NewRequest(http.MethodGet, "/?lang=de", nil), "")
+	if cookies := res.w.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("cookies = %v", cookies)
+	}
+}
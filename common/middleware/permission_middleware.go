@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"github.com/EddyZe/foodApp/common/domain/models"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// RequirePermission пропускает запрос, только если у пользователя есть все перечисленные разрешения.
+// Должен вызываться после JwtFilter
+func RequirePermission(locliz *localizer.LocalizeService, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := localizer.FromContext(c)
+		claims, ok := c.Get("claims")
+		if !ok {
+			responseutil.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", getMsgUnAuthorized(locliz, lang))
+			c.Abort()
+			return
+		}
+
+		claimsMap, ok := claims.(*models.JwtClaims)
+		if !ok {
+			responseutil.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", getMsgUnAuthorized(locliz, lang))
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !claimsMap.HasPermission(permission) {
+				errMsg := locliz.GetMessage(
+					localizer.Forbidden,
+					lang,
+					"Not enough right",
+					nil,
+				)
+
+				responseutil.ErrorResponse(c, http.StatusForbidden, "FORBIDDEN", errMsg)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
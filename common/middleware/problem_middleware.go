@@ -0,0 +1,14 @@
+package middleware
+
+import (
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/gin-gonic/gin"
+)
+
+// Problems задает формат ответов об ошибках для запросов, которые проходят через этот middleware
+func Problems(opts responseutil.ProblemOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(responseutil.ProblemOptionsKey, opts)
+		c.Next()
+	}
+}
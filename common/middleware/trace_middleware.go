@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/EddyZe/foodApp/common/pkg/responseutil"
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+// Trace определяет идентификатор запроса: trace-id из traceparent (W3C), X-Request-Id клиента или новый.
+// Идентификатор попадает в ответы об ошибках и в заголовок X-Request-Id ответа
+func Trace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := traceparentId(c.GetHeader("traceparent"))
+		if id == "" {
+			id = c.GetHeader(RequestIdHeader)
+		}
+		if id == "" || len(id) > 128 {
+			id = newTraceId()
+		}
+
+		c.Set(responseutil.TraceIdKey, id)
+		c.Header(RequestIdHeader, id)
+		c.Next()
+	}
+}
+
+// traceparentId trace-id из заголовка вида 00-<trace-id>-<parent-id>-<flags>
+func traceparentId(header string) string {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return ""
+	}
+	return parts[1]
+}
+
+func newTraceId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,88 @@
+// Package apperr виды доменных ошибок, общие для сервисов. Репозитории переводят ошибки базы
+// в эти виды, сервисы объявляют на их основе свои ошибки с кодами, а транспорт по виду
+// выбирает ответ. Причина ошибки сохраняется и доступна через errors.Is и errors.As
+package apperr
+
+import "errors"
+
+// Виды ошибок. Проверяются через errors.Is(err, apperr.ErrNotFound)
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrForbidden   = errors.New("forbidden")
+	ErrInvalid     = errors.New("invalid")
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// Error ошибка определенного вида. Code - код для клиента (например USER_IS_NOT_BLOCKED),
+// может быть пустым для ошибок, которые описывает сам вид. Err - причина
+type Error struct {
+	Kind error
+	Code string
+	Err  error
+}
+
+// New ошибка вида kind с кодом code, обычно объявляется переменной пакета сервиса
+func New(kind error, code string) *Error {
+	return &Error{Kind: kind, Code: code}
+}
+
+func (e *Error) Error() string {
+	msg := e.Code
+	if msg == "" {
+		msg = e.Kind.Error()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Is ошибки с одинаковыми видом и кодом равны независимо от причины,
+// поэтому errors.Is(ErrX.Wrap(cause), ErrX) истинно
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// Wrap копия ошибки с причиной cause
+func (e *Error) Wrap(cause error) *Error {
+	return &Error{Kind: e.Kind, Code: e.Code, Err: cause}
+}
+
+func NotFound(cause error) error {
+	return &Error{Kind: ErrNotFound, Err: cause}
+}
+
+func Conflict(cause error) error {
+	return &Error{Kind: ErrConflict, Err: cause}
+}
+
+func Forbidden(cause error) error {
+	return &Error{Kind: ErrForbidden, Err: cause}
+}
+
+func Invalid(cause error) error {
+	return &Error{Kind: ErrInvalid, Err: cause}
+}
+
+func Unavailable(cause error) error {
+	return &Error{Kind: ErrUnavailable, Err: cause}
+}
+
+// KindOf вид ошибки или nil, если err не относится ни к одному виду
+func KindOf(err error) error {
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrForbidden, ErrInvalid, ErrUnavailable} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
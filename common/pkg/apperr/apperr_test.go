@@ -0,0 +1,42 @@
+package apperr
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var errTestNotBlocked = New(ErrInvalid, "USER_IS_NOT_BLOCKED")
+
+func TestErrorChain(t *testing.T) {
+	err := fmt.Errorf("разблокировка: %w", errTestNotBlocked.Wrap(sql.ErrNoRows))
+
+	if !errors.Is(err, errTestNotBlocked) {
+		t.Error("не найдена ошибка сервиса")
+	}
+	if !errors.Is(err, ErrInvalid) {
+		t.Error("не найден вид ошибки")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Error("потеряна причина")
+	}
+	if errors.Is(err, ErrNotFound) || errors.Is(err, New(ErrInvalid, "OTHER")) {
+		t.Error("совпала чужая ошибка")
+	}
+	if KindOf(err) != ErrInvalid {
+		t.Errorf("вид %v", KindOf(err))
+	}
+
+	var appErr *Error
+	if !errors.As(err, &appErr) || appErr.Code != "USER_IS_NOT_BLOCKED" {
+		t.Errorf("errors.As: %v", appErr)
+	}
+	if got := err.Error(); got != "разблокировка: USER_IS_NOT_BLOCKED: sql: no rows in result set" {
+		t.Errorf("текст %q", got)
+	}
+
+	if unavailable := Unavailable(errors.New("connection refused")); KindOf(unavailable) != ErrUnavailable {
+		t.Errorf("вид %v", KindOf(unavailable))
+	}
+}
@@ -0,0 +1,61 @@
+package authclient
+
+import (
+	"context"
+	"crypto/tls"
+
+	authv1 "github.com/EddyZe/foodApp/common/api/auth/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Config параметры подключения к gRPC API сервиса авторизации
+type Config struct {
+	Addr  string
+	Token string
+	// TLS настройки TLS. nil - проверка сертификата сервера по системным корневым сертификатам
+	TLS *tls.Config
+	// Insecure соединение без TLS, только для локального запуска. Служебный токен уходит открытым текстом
+	Insecure bool
+}
+
+// serviceToken передает служебный токен в каждом вызове
+type serviceToken struct {
+	token      string
+	requireTLS bool
+}
+
+func (t serviceToken) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+// RequireTransportSecurity пока Insecure не задан, grpc не отправит токен по соединению без TLS
+func (t serviceToken) RequireTransportSecurity() bool {
+	return t.requireTLS
+}
+
+// Dial клиент gRPC API сервиса авторизации. По умолчанию соединение защищено TLS.
+// Соединение нужно закрыть после использования
+func Dial(cfg Config, opts ...grpc.DialOption) (authv1.AuthServiceClient, *grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if !cfg.Insecure {
+		tlsCfg := cfg.TLS
+		if tlsCfg == nil {
+			tlsCfg = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		creds = credentials.NewTLS(tlsCfg)
+	}
+
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithPerRPCCredentials(serviceToken{token: cfg.Token, requireTLS: !cfg.Insecure}),
+	}, opts...)
+
+	conn, err := grpc.NewClient(cfg.Addr, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return authv1.NewAuthServiceClient(conn), conn, nil
+}
@@ -0,0 +1,42 @@
+package authclient
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestServiceTokenRequiresTLS(t *testing.T) {
+	tok := serviceToken{token: "secret", requireTLS: true}
+	if !tok.RequireTransportSecurity() {
+		t.Fatal("токен должен требовать TLS")
+	}
+
+	md, err := tok.GetRequestMetadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md["authorization"] != "Bearer secret" {
+		t.Fatalf("authorization = %q", md["authorization"])
+	}
+}
+
+// Без явного Insecure токен не должен уходить по соединению без TLS
+func TestDialRejectsPlaintextByDefault(t *testing.T) {
+	_, conn, err := Dial(Config{Addr: "localhost:1", Token: "secret"},
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err == nil {
+		_ = conn.Close()
+		t.Fatal("ожидалась ошибка: токен требует TLS, а соединение без него")
+	}
+}
+
+func TestDialInsecure(t *testing.T) {
+	_, conn, err := Dial(Config{Addr: "localhost:1", Token: "secret", Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+}
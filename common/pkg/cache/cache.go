@@ -0,0 +1,265 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/EddyZe/foodApp/common/pkg/apperr"
+	"github.com/EddyZe/foodApp/common/pkg/redisutil"
+)
+
+// ErrCachedMiss в кеше записано, что значения нет. Возвращается обернутым в apperr.ErrNotFound
+var ErrCachedMiss = errors.New("значение отсутствует (negative cache)")
+
+// Store хранилище кеша, обычно redis. PutEx сериализует value в json
+type Store interface {
+	Get(key string) (string, bool)
+	PutEx(key string, value interface{}, expiration time.Duration) error
+	Del(key string) error
+}
+
+// VersionedStore хранилище с версией у каждого ключа. Set и Invalidate увеличивают версию,
+// а значение, загруженное из источника, записывается только если версия не изменилась с начала
+// загрузки. Так медленный читатель не затрет устаревшими данными свежую запись
+type VersionedStore interface {
+	Store
+	Version(key string) (int64, error)
+	CompareAndSet(key string, version int64, value interface{}, expiration time.Duration) (bool, error)
+	SetVersioned(key string, value interface{}, expiration time.Duration) error
+	DelVersioned(key string) error
+}
+
+type Options struct {
+	// Namespace префикс ключей, например users:id
+	Namespace string
+	// Version входит в ключ. Увеличение версии делает все старые записи невидимыми,
+	// например после изменения структуры кешируемой сущности
+	Version int
+	TTL     time.Duration
+	// NegativeTTL сколько помнить, что значения нет (loader вернул apperr.ErrNotFound). 0 - не кешировать
+	NegativeTTL time.Duration
+	// Jitter доля TTL, на которую случайно сдвигается срок жизни записи, чтобы ключи не истекали разом
+	Jitter  float64
+	Metrics Metrics
+}
+
+// entry то, что лежит в хранилище. Missing - запись negative cache
+type entry[T any] struct {
+	Value   T    `json:"v"`
+	Missing bool `json:"m,omitempty"`
+}
+
+// Cache типизированный кеш поверх Store. Одновременные промахи по одному ключу
+// загружаются один раз
+type Cache struct {
+	store Store
+	opts  Options
+	group *group
+}
+
+func New(store Store, opts Options) *Cache {
+	if opts.Metrics == nil {
+		opts.Metrics = nopMetrics{}
+	}
+	return &Cache{
+		store: store,
+		opts:  opts,
+		group: newGroup(),
+	}
+}
+
+// Namespace кеш с тем же хранилищем и настройками, но другим префиксом ключей
+func (c *Cache) Namespace(namespace string) *Cache {
+	opts := c.opts
+	opts.Namespace = namespace
+	return &Cache{
+		store: c.store,
+		opts:  opts,
+		group: c.group,
+	}
+}
+
+// WithTTL кеш с тем же префиксом и другим сроком жизни записей
+func (c *Cache) WithTTL(ttl time.Duration) *Cache {
+	opts := c.opts
+	opts.TTL = ttl
+	return &Cache{
+		store: c.store,
+		opts:  opts,
+		group: c.group,
+	}
+}
+
+// Key полный ключ в хранилище: namespace:v<version>:id
+func (c *Cache) Key(id string) string {
+	return redisutil.GenerateKey(fmt.Sprintf("%s:v%d", c.opts.Namespace, c.opts.Version), id)
+}
+
+// Set записывает значение с TTL кеша
+func (c *Cache) Set(id string, value interface{}) error {
+	return c.SetEx(id, value, c.opts.TTL)
+}
+
+// SetEx записывает значение с заданным TTL, к которому добавляется jitter
+func (c *Cache) SetEx(id string, value interface{}, ttl time.Duration) error {
+	return c.put(id, entry[interface{}]{Value: value}, ttl)
+}
+
+// Invalidate удаляет записи, в том числе negative. Возвращает первую ошибку хранилища
+func (c *Cache) Invalidate(ids ...string) error {
+	var res error
+	for _, id := range ids {
+		if err := c.del(c.Key(id)); err != nil {
+			c.opts.Metrics.Event(c.opts.Namespace, EventStoreError)
+			if res == nil {
+				res = err
+			}
+		}
+	}
+	return res
+}
+
+func (c *Cache) del(key string) error {
+	if vs, ok := c.store.(VersionedStore); ok {
+		return vs.DelVersioned(key)
+	}
+	return c.store.Del(key)
+}
+
+// put запись после изменения данных, увеличивает версию ключа
+func (c *Cache) put(id string, e interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	key := c.Key(id)
+	if vs, ok := c.store.(VersionedStore); ok {
+		err = vs.SetVersioned(key, json.RawMessage(data), c.jitter(ttl))
+	} else {
+		err = c.store.PutEx(key, json.RawMessage(data), c.jitter(ttl))
+	}
+	if err != nil {
+		c.opts.Metrics.Event(c.opts.Namespace, EventStoreError)
+		return err
+	}
+	return nil
+}
+
+// version текущая версия ключа перед загрузкой. ok=false - результат загрузки кешировать нельзя
+func (c *Cache) version(key string) (int64, bool) {
+	vs, ok := c.store.(VersionedStore)
+	if !ok {
+		return 0, true
+	}
+	v, err := vs.Version(key)
+	if err != nil {
+		c.opts.Metrics.Event(c.opts.Namespace, EventStoreError)
+		return 0, false
+	}
+	return v, true
+}
+
+// fill запись загруженного значения. Если версия ключа изменилась, значение устарело и не пишется
+func (c *Cache) fill(id string, version int64, e interface{}, ttl time.Duration) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	key := c.Key(id)
+	vs, ok := c.store.(VersionedStore)
+	if !ok {
+		if err := c.store.PutEx(key, json.RawMessage(data), c.jitter(ttl)); err != nil {
+			c.opts.Metrics.Event(c.opts.Namespace, EventStoreError)
+		}
+		return
+	}
+
+	stored, err := vs.CompareAndSet(key, version, json.RawMessage(data), c.jitter(ttl))
+	switch {
+	case err != nil:
+		c.opts.Metrics.Event(c.opts.Namespace, EventStoreError)
+	case !stored:
+		c.opts.Metrics.Event(c.opts.Namespace, EventStale)
+	}
+}
+
+func (c *Cache) jitter(ttl time.Duration) time.Duration {
+	if c.opts.Jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	delta := time.Duration(float64(ttl) * c.opts.Jitter * (2*rand.Float64() - 1))
+	if ttl+delta <= 0 {
+		return ttl
+	}
+	return ttl + delta
+}
+
+// lookup читает запись. ok=false - записи нет или она не читается (тогда она удаляется)
+func lookup[T any](c *Cache, id string) (entry[T], bool) {
+	var e entry[T]
+	key := c.Key(id)
+	data, ok := c.store.Get(key)
+	if !ok {
+		return e, false
+	}
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		c.opts.Metrics.Event(c.opts.Namespace, EventDecodeError)
+		_ = c.store.Del(key)
+		return e, false
+	}
+	return e, true
+}
+
+// Get значение из кеша без загрузки. Запись negative cache считается отсутствием значения
+func Get[T any](c *Cache, id string) (T, bool) {
+	e, ok := lookup[T](c, id)
+	if !ok || e.Missing {
+		var zero T
+		return zero, false
+	}
+	return e.Value, true
+}
+
+// GetOrLoad возвращает значение из кеша, а при промахе вызывает load и кеширует результат.
+// Если load вернул apperr.ErrNotFound, отсутствие запоминается на NegativeTTL.
+// Прочие ошибки load не кешируются
+func GetOrLoad[T any](c *Cache, id string, load func() (T, error)) (T, error) {
+	var zero T
+	if e, ok := lookup[T](c, id); ok {
+		if e.Missing {
+			c.opts.Metrics.Event(c.opts.Namespace, EventNegativeHit)
+			return zero, apperr.NotFound(ErrCachedMiss)
+		}
+		c.opts.Metrics.Event(c.opts.Namespace, EventHit)
+		return e.Value, nil
+	}
+	c.opts.Metrics.Event(c.opts.Namespace, EventMiss)
+
+	v, err, shared := c.group.do(c.Key(id), func() (interface{}, error) {
+		version, cacheable := c.version(c.Key(id))
+		res, err := load()
+		if err != nil {
+			if c.opts.NegativeTTL > 0 && cacheable && errors.Is(err, apperr.ErrNotFound) {
+				c.fill(id, version, entry[T]{Missing: true}, c.opts.NegativeTTL)
+			} else if !errors.Is(err, apperr.ErrNotFound) {
+				c.opts.Metrics.Event(c.opts.Namespace, EventLoadError)
+			}
+			return nil, err
+		}
+		if cacheable {
+			c.fill(id, version, entry[T]{Value: res}, c.opts.TTL)
+		}
+		return res, nil
+	})
+	if shared {
+		c.opts.Metrics.Event(c.opts.Namespace, EventShared)
+	}
+	if err != nil {
+		return zero, err
+	}
+	res, _ := v.(T)
+	return res, nil
+}
@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EddyZe/foodApp/common/pkg/apperr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	data map[string]string
+	ttl  map[string]time.Duration
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: map[string]string{}, ttl: map[string]time.Duration{}}
+}
+
+func (s *memStore) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+func (s *memStore) PutEx(key string, value interface{}, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = string(data)
+	s.ttl[key] = expiration
+	return nil
+}
+
+func (s *memStore) Del(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+type user struct {
+	Id   int64
+	Name string
+}
+
+func TestGetOrLoadSingleflight(t *testing.T) {
+	c := New(newMemStore(), Options{Namespace: "users", Version: 1, TTL: time.Minute})
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := GetOrLoad(c, "1", func() (*user, error) {
+				loads.Add(1)
+				<-release
+				return &user{Id: 1, Name: "bob"}, nil
+			})
+			if err != nil || u == nil || u.Name != "bob" {
+				t.Errorf("неожиданный результат: %v %v", u, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("загрузка должна выполниться один раз, выполнено %d", n)
+	}
+
+	u, err := GetOrLoad(c, "1", func() (*user, error) {
+		t.Error("значение должно браться из кеша")
+		return nil, nil
+	})
+	if err != nil || u.Name != "bob" {
+		t.Errorf("неожиданный результат из кеша: %v %v", u, err)
+	}
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	c := New(newMemStore(), Options{Namespace: "users", Version: 1, TTL: time.Minute})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	errs := make(chan error, 5)
+	go func() {
+		_, err := GetOrLoad(c, "1", func() (*user, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+		errs <- err
+	}()
+	<-started
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := GetOrLoad(c, "1", func() (*user, error) {
+				t.Error("ожидающие запросы не должны загружать значение сами")
+				return nil, nil
+			})
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < 5; i++ {
+		var panicErr *PanicError
+		if err := <-errs; !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+			t.Errorf("ожидалась PanicError, получено %v", err)
+		}
+	}
+
+	// после паники ключ снова загружается
+	u, err := GetOrLoad(c, "1", func() (*user, error) {
+		return &user{Id: 1, Name: "bob"}, nil
+	})
+	if err != nil || u.Name != "bob" {
+		t.Errorf("неожиданный результат после паники: %v %v", u, err)
+	}
+}
+
+func TestGetOrLoadNegative(t *testing.T) {
+	c := New(newMemStore(), Options{Namespace: "users", TTL: time.Minute, NegativeTTL: time.Second})
+
+	notFound := apperr.NotFound(errors.New("нет строки"))
+	var loads int
+	load := func() (*user, error) {
+		loads++
+		return nil, notFound
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := GetOrLoad(c, "2", load); !errors.Is(err, apperr.ErrNotFound) {
+			t.Errorf("ожидалась ошибка NotFound, получено %v", err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("отсутствие значения должно кешироваться, загрузок %d", loads)
+	}
+	if _, ok := Get[*user](c, "2"); ok {
+		t.Error("negative запись не должна возвращаться как значение")
+	}
+
+	if err := c.Invalidate("2"); err != nil {
+		t.Fatal(err)
+	}
+	GetOrLoad(c, "2", load)
+	if loads != 2 {
+		t.Error("после Invalidate значение должно загружаться заново")
+	}
+
+	failing := func() (*user, error) {
+		loads++
+		return nil, errors.New("база недоступна")
+	}
+	GetOrLoad(c, "3", failing)
+	GetOrLoad(c, "3", failing)
+	if loads != 4 {
+		t.Error("ошибки, кроме NotFound, не должны кешироваться")
+	}
+}
+
+func TestVersionAndDecode(t *testing.T) {
+	store := newMemStore()
+	v1 := New(store, Options{Namespace: "users", Version: 1, TTL: time.Minute, Jitter: 0.1})
+	v2 := New(store, Options{Namespace: "users", Version: 2, TTL: time.Minute})
+
+	if err := v1.Set("1", &user{Id: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := store.ttl[v1.Key("1")]; ttl < 54*time.Second || ttl > 66*time.Second {
+		t.Errorf("TTL с jitter вне допустимого диапазона: %v", ttl)
+	}
+	if _, ok := Get[*user](v2, "1"); ok {
+		t.Error("запись другой версии не должна быть видна")
+	}
+
+	store.data[v2.Key("1")] = "не json"
+	u, err := GetOrLoad(v2, "1", func() (*user, error) {
+		return &user{Id: 1, Name: "db"}, nil
+	})
+	if err != nil || u.Name != "db" {
+		t.Errorf("битая запись должна перезагружаться из источника: %v %v", u, err)
+	}
+}
+
+type versionedStore struct {
+	*memStore
+	versions map[string]int64
+}
+
+func (s *versionedStore) Version(key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.versions[key], nil
+}
+
+func (s *versionedStore) CompareAndSet(key string, version int64, value interface{}, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	if s.versions[key] != version {
+		s.mu.Unlock()
+		return false, nil
+	}
+	s.mu.Unlock()
+	return true, s.PutEx(key, value, expiration)
+}
+
+func (s *versionedStore) SetVersioned(key string, value interface{}, expiration time.Duration) error {
+	s.mu.Lock()
+	s.versions[key]++
+	s.mu.Unlock()
+	return s.PutEx(key, value, expiration)
+}
+
+func (s *versionedStore) DelVersioned(key string) error {
+	s.mu.Lock()
+	s.versions[key]++
+	s.mu.Unlock()
+	return s.Del(key)
+}
+
+func TestStaleFillDoesNotOverwrite(t *testing.T) {
+	c := New(&versionedStore{memStore: newMemStore(), versions: map[string]int64{}}, Options{Namespace: "users", TTL: time.Minute})
+
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		GetOrLoad(c, "1", func() (*user, error) {
+			close(loading)
+			<-release
+			return &user{Id: 1, Name: "old"}, nil
+		})
+	}()
+
+	<-loading
+	if err := c.Set("1", &user{Id: 1, Name: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-done
+
+	if u, ok := Get[*user](c, "1"); !ok || u.Name != "new" {
+		t.Errorf("устаревшая загрузка не должна затирать свежую запись: %v", u)
+	}
+}
+
+func TestPrometheusMetricsRegisterTwice(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	first, err := NewPrometheusMetrics(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewPrometheusMetrics(reg)
+	if err != nil {
+		t.Fatalf("повторная регистрация: %v", err)
+	}
+
+	first.Event("users", EventHit)
+	second.Event("users", EventHit)
+	if got := testutil.ToFloat64(first.events.WithLabelValues("users", string(EventHit))); got != 2 {
+		t.Errorf("cache_events_total = %v, ожидалось 2", got)
+	}
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// call загрузка, результат которой ждут все одновременные запросы ключа
+type call struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// PanicError загрузка завершилась паникой. Паника не выходит за пределы кеша, а возвращается
+// ошибкой и вызову, который выполнял загрузку, и всем, кто ждал ее результат
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("паника при загрузке значения в кеш: %v\n%s", e.Value, e.Stack)
+}
+
+// group упрощенный singleflight: пока идет загрузка ключа, остальные вызовы ждут ее результат
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+func newGroup() *group {
+	return &group{
+		calls: make(map[string]*call),
+	}
+}
+
+// do выполняет fn один раз на ключ. shared=true у вызовов, получивших результат чужой загрузки
+func (g *group) do(key string, fn func() (interface{}, error)) (interface{}, error, bool) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				c.val, c.err = nil, &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		c.val, c.err = fn()
+	}()
+	return c.val, c.err, false
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Event string
+
+const (
+	EventHit         Event = "hit"
+	EventNegativeHit Event = "negative_hit"
+	EventMiss        Event = "miss"
+	// EventShared вызов получил результат чужой загрузки того же ключа
+	EventShared Event = "shared"
+	// EventStale загруженное значение не записано: ключ изменился во время загрузки
+	EventStale       Event = "stale"
+	EventLoadError   Event = "load_error"
+	EventDecodeError Event = "decode_error"
+	EventStoreError  Event = "store_error"
+)
+
+// Metrics получает события кеша с его namespace
+type Metrics interface {
+	Event(namespace string, event Event)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) Event(string, Event) {}
+
+// PrometheusMetrics считает события в cache_events_total
+type PrometheusMetrics struct {
+	events *prometheus.CounterVec
+}
+
+// NewPrometheusMetrics регистрирует cache_events_total в reg. Если счетчик уже зарегистрирован
+// (несколько кешей в одном процессе), используется существующий
+func NewPrometheusMetrics(reg prometheus.Registerer) (*PrometheusMetrics, error) {
+	events := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_events_total",
+			Help: "Total cache lookups and failures by namespace and event",
+		},
+		[]string{"namespace", "event"},
+	)
+	if err := reg.Register(events); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if !errors.As(err, &registered) {
+			return nil, err
+		}
+		existing, ok := registered.ExistingCollector.(*prometheus.CounterVec)
+		if !ok {
+			return nil, err
+		}
+		events = existing
+	}
+
+	return &PrometheusMetrics{events: events}, nil
+}
+
+func (m *PrometheusMetrics) Event(namespace string, event Event) {
+	m.events.WithLabelValues(namespace, string(event)).Inc()
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Handler обработчик события. *DecodeError отправляет сообщение в dead-letter, остальные ошибки - повтор
+type Handler func(ctx context.Context, env *Envelope) error
+
+// Producer отправляет события. Конверты с одинаковым Key доставляются в порядке отправки
+type Producer interface {
+	Publish(ctx context.Context, topic string, envs ...*Envelope) error
+	Close() error
+}
+
+// Consumer читает топики в составе группы: каждое событие обрабатывается одним потребителем группы
+type Consumer interface {
+	Subscribe(topic string, h Handler)
+	// Run блокируется до отмены ctx
+	Run(ctx context.Context) error
+	Close() error
+}
+
+const (
+	headerId      = "event_id"
+	headerType    = "event_type"
+	headerVersion = "event_version"
+	headerError   = "dlq_error"
+
+	// maxHandleAttempts после стольких неудачных вызовов обработчика сообщение уходит в dead-letter
+	maxHandleAttempts = 10
+)
+
+// задержки между повторами обработчика, в тестах уменьшаются
+var (
+	retryDelay    = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+// handle вызывает обработчик с повтором, пока он не завершится успешно, не вернет *DecodeError,
+// не исчерпает maxHandleAttempts попыток или не отменят ctx. true - сообщение нужно отправить в dead-letter
+func handle(ctx context.Context, h Handler, env *Envelope) (bool, error) {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := h(ContextWithTrace(ctx, env.Trace), env)
+		if err == nil {
+			return false, nil
+		}
+
+		var decodeErr *DecodeError
+		if errors.As(err, &decodeErr) {
+			return true, err
+		}
+		if attempt >= maxHandleAttempts {
+			return true, fmt.Errorf("обработчик не справился за %d попыток: %w", attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event типизированная полезная нагрузка события
+type Event interface {
+	EventType() string
+	EventVersion() int
+}
+
+// Envelope конверт события: метаданные и сериализованная полезная нагрузка.
+// Версия меняется при несовместимом изменении полезной нагрузки
+type Envelope struct {
+	Id         string            `json:"id"`
+	Type       string            `json:"type"`
+	Version    int               `json:"version"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Key        string            `json:"key,omitempty"`
+	Trace      map[string]string `json:"trace,omitempty"`
+	Payload    json.RawMessage   `json:"payload"`
+}
+
+// NewEnvelope упаковывает событие. Key - ключ упорядочивания (обычно id пользователя)
+func NewEnvelope(key string, ev Event) (*Envelope, error) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		Id:         uuid.NewString(),
+		Type:       ev.EventType(),
+		Version:    ev.EventVersion(),
+		OccurredAt: time.Now().UTC(),
+		Key:        key,
+		Payload:    payload,
+	}, nil
+}
+
+// Decode распаковывает полезную нагрузку в v. Ошибка типа *DecodeError отправляет сообщение в dead-letter
+func (e *Envelope) Decode(v Event) error {
+	if e.Type != v.EventType() {
+		return &DecodeError{Err: fmt.Errorf("тип события %s, ожидался %s", e.Type, v.EventType())}
+	}
+	if e.Version > v.EventVersion() {
+		return &DecodeError{Err: fmt.Errorf("неподдерживаемая версия %s: %d", e.Type, e.Version)}
+	}
+
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return &DecodeError{Err: err}
+	}
+
+	return nil
+}
+
+// DecodeError сообщение невозможно разобрать. Повтор не поможет, поэтому оно уходит в dead-letter топик
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return "ошибка разбора события: " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DeadLetterTopic топик для сообщений, которые не удалось разобрать
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+// ParseEnvelope разбирает конверт. Конверт без id, type или payload - ошибка *DecodeError
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, &DecodeError{Err: err}
+	}
+	if env.Id == "" || env.Type == "" || len(env.Payload) == 0 {
+		return nil, &DecodeError{Err: fmt.Errorf("в конверте нет id, type или payload")}
+	}
+
+	return &env, nil
+}
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseEnvelope(t *testing.T) {
+	env, err := NewEnvelope("1", UserDeleted{UserId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseEnvelope(data); err != nil {
+		t.Errorf("конверт не разобран: %v", err)
+	}
+
+	for name, raw := range map[string]string{
+		"не json": "not json",
+		"нагрузка без конверта": `{"user_id":1,"deleted_at":"2025-07-18T10:00:00Z"}`,
+		"конверт без payload":   `{"id":"1","type":"UserDeleted","version":1}`,
+	} {
+		var decodeErr *DecodeError
+		if _, err := ParseEnvelope([]byte(raw)); !errors.As(err, &decodeErr) {
+			t.Errorf("%s: ожидалась *DecodeError, получено %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaProducer партиция выбирается по хешу ключа, поэтому события одного пользователя сохраняют порядок
+type KafkaProducer struct {
+	w *kafka.Writer
+}
+
+// writerBatchTimeout сколько writer ждет новых сообщений перед отправкой батча
+const writerBatchTimeout = 5 * time.Millisecond
+
+func NewKafkaProducer(brokers []string) *KafkaProducer {
+	return &KafkaProducer{
+		w: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			MaxAttempts:            3,
+			WriteTimeout:           10 * time.Second,
+			AllowAutoTopicCreation: true,
+			// сообщения уже собраны в пачку вызывающим, ждать наполнения батча (по умолчанию 1с) незачем
+			BatchTimeout: writerBatchTimeout,
+		},
+	}
+}
+
+func (p *KafkaProducer) Publish(ctx context.Context, topic string, envs ...*Envelope) error {
+	msgs := make([]kafka.Message, 0, len(envs))
+	for _, env := range envs {
+		if env.Trace == nil {
+			env.Trace = TraceFromContext(ctx)
+		}
+
+		data, err := json.Marshal(env)
+		if err != nil {
+			return err
+		}
+
+		msgs = append(msgs, kafka.Message{
+			Topic: topic,
+			Key:   []byte(env.Key),
+			Value: data,
+			Headers: []kafka.Header{
+				{Key: headerId, Value: []byte(env.Id)},
+				{Key: headerType, Value: []byte(env.Type)},
+				{Key: headerVersion, Value: []byte(strconv.Itoa(env.Version))},
+			},
+		})
+	}
+
+	return p.w.WriteMessages(ctx, msgs...)
+}
+
+func (p *KafkaProducer) Close() error {
+	return p.w.Close()
+}
+
+// KafkaConsumer читает топики в составе consumer group. Смещение фиксируется после обработки,
+// неразбираемые сообщения перекладываются в <topic>.dlq
+type KafkaConsumer struct {
+	brokers  []string
+	group    string
+	handlers map[string]Handler
+	dlq      *kafka.Writer
+
+	mu      sync.Mutex
+	readers []*kafka.Reader
+}
+
+func NewKafkaConsumer(brokers []string, group string) *KafkaConsumer {
+	return &KafkaConsumer{
+		brokers:  brokers,
+		group:    group,
+		handlers: make(map[string]Handler),
+		dlq: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+			BatchTimeout:           writerBatchTimeout,
+		},
+	}
+}
+
+func (c *KafkaConsumer) Subscribe(topic string, h Handler) {
+	c.handlers[topic] = h
+}
+
+// Run ошибка чтения любого топика останавливает остальные и возвращается с именем топика
+func (c *KafkaConsumer) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(c.handlers))
+
+	for topic, h := range c.handlers {
+		r := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  c.brokers,
+			GroupID:  c.group,
+			Topic:    topic,
+			MinBytes: 1,
+			MaxBytes: 10e6,
+		})
+		c.mu.Lock()
+		c.readers = append(c.readers, r)
+		c.mu.Unlock()
+
+		wg.Add(1)
+		go func(topic string, h Handler, r *kafka.Reader) {
+			defer wg.Done()
+			if err := c.consume(ctx, topic, h, r); err != nil {
+				errs <- fmt.Errorf("топик %s: %w", topic, err)
+				cancel()
+			}
+		}(topic, h, r)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	var res []error
+	for err := range errs {
+		res = append(res, err)
+	}
+	return errors.Join(res...)
+}
+
+func (c *KafkaConsumer) consume(ctx context.Context, topic string, h Handler, r *kafka.Reader) error {
+	for {
+		msg, err := r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		env, err := ParseEnvelope(msg.Value)
+		toDeadLetter := err != nil
+		if err == nil {
+			toDeadLetter, err = handle(ctx, h, env)
+			if !toDeadLetter && ctx.Err() != nil {
+				return nil
+			}
+		}
+
+		if toDeadLetter {
+			if err := c.deadLetter(ctx, topic, msg, err); err != nil {
+				return err
+			}
+		}
+
+		if err := r.CommitMessages(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (c *KafkaConsumer) deadLetter(ctx context.Context, topic string, msg kafka.Message, cause error) error {
+	headers := append(msg.Headers, kafka.Header{Key: headerError, Value: []byte(cause.Error())})
+	return c.dlq.WriteMessages(ctx, kafka.Message{
+		Topic:   DeadLetterTopic(topic),
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+}
+
+func (c *KafkaConsumer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, r := range c.readers {
+		if err := r.Close(); err != nil {
+			return err
+		}
+	}
+
+	return c.dlq.Close()
+}
@@ -0,0 +1,164 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Record сообщение в MemoryBus
+type Record struct {
+	Value   []byte
+	Headers map[string]string
+}
+
+// MemoryBus брокер в памяти процесса для тестов и локального запуска. Реализует Producer
+type MemoryBus struct {
+	mu      sync.Mutex
+	topics  map[string][]Record
+	offsets map[string]int
+	notify  chan struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		topics:  make(map[string][]Record),
+		offsets: make(map[string]int),
+		notify:  make(chan struct{}),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, topic string, envs ...*Envelope) error {
+	for _, env := range envs {
+		if env.Trace == nil {
+			env.Trace = TraceFromContext(ctx)
+		}
+
+		data, err := json.Marshal(env)
+		if err != nil {
+			return err
+		}
+
+		b.PublishRaw(topic, data, map[string]string{
+			headerId:   env.Id,
+			headerType: env.Type,
+		})
+	}
+
+	return nil
+}
+
+// PublishRaw кладет в топик произвольные байты, в том числе неразбираемые
+func (b *MemoryBus) PublishRaw(topic string, data []byte, headers map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.topics[topic] = append(b.topics[topic], Record{Value: data, Headers: headers})
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+// Records копия сообщений топика в порядке отправки
+func (b *MemoryBus) Records(topic string) []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := make([]Record, len(b.topics[topic]))
+	copy(res, b.topics[topic])
+	return res
+}
+
+func (b *MemoryBus) Close() error {
+	return nil
+}
+
+// next забирает следующее сообщение топика для группы. Смещение общее для группы,
+// поэтому каждое сообщение получает только один потребитель группы
+func (b *MemoryBus) next(group, topic string) (*Record, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := group + "/" + topic
+	offset := b.offsets[key]
+	if offset >= len(b.topics[topic]) {
+		return nil, b.notify
+	}
+
+	b.offsets[key] = offset + 1
+	rec := b.topics[topic][offset]
+	return &rec, nil
+}
+
+// MemoryConsumer потребитель MemoryBus в составе группы
+type MemoryConsumer struct {
+	bus      *MemoryBus
+	group    string
+	handlers map[string]Handler
+}
+
+func NewMemoryConsumer(bus *MemoryBus, group string) *MemoryConsumer {
+	return &MemoryConsumer{
+		bus:      bus,
+		group:    group,
+		handlers: make(map[string]Handler),
+	}
+}
+
+func (c *MemoryConsumer) Subscribe(topic string, h Handler) {
+	c.handlers[topic] = h
+}
+
+func (c *MemoryConsumer) Run(ctx context.Context) error {
+	for {
+		var wait <-chan struct{}
+		processed := false
+
+		for topic, h := range c.handlers {
+			rec, notify := c.bus.next(c.group, topic)
+			if rec == nil {
+				wait = notify
+				continue
+			}
+
+			processed = true
+			if err := c.process(ctx, topic, h, rec); err != nil {
+				return err
+			}
+		}
+
+		if processed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wait:
+		}
+	}
+}
+
+func (c *MemoryConsumer) process(ctx context.Context, topic string, h Handler, rec *Record) error {
+	env, err := ParseEnvelope(rec.Value)
+	if err == nil {
+		var toDeadLetter bool
+		toDeadLetter, err = handle(ctx, h, env)
+		if !toDeadLetter {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+
+	headers := map[string]string{headerError: err.Error()}
+	for k, v := range rec.Headers {
+		headers[k] = v
+	}
+	c.bus.PublishRaw(DeadLetterTopic(topic), rec.Value, headers)
+	return nil
+}
+
+func (c *MemoryConsumer) Close() error {
+	return nil
+}
@@ -0,0 +1,170 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testTopic = "auth.user-events"
+
+func runConsumer(t *testing.T, c Consumer) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		if err := c.Run(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+	return cancel
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("условие не выполнено за отведенное время")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPublishAndConsume(t *testing.T) {
+	bus := NewMemoryBus()
+	env, err := NewEnvelope("1", UserBanned{UserId: 1, BanId: 10, ReasonCategory: "spam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := ContextWithTrace(context.Background(), map[string]string{"traceparent": "00-abc-def-01"})
+	if err := bus.Publish(ctx, testTopic, env); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan UserBanned, 1)
+	traces := make(chan string, 1)
+	consumer := NewMemoryConsumer(bus, "profile")
+	consumer.Subscribe(testTopic, func(ctx context.Context, env *Envelope) error {
+		var ev UserBanned
+		if err := env.Decode(&ev); err != nil {
+			return err
+		}
+		traces <- TraceFromContext(ctx)["traceparent"]
+		received <- ev
+		return nil
+	})
+	defer runConsumer(t, consumer)()
+
+	select {
+	case ev := <-received:
+		if ev.UserId != 1 || ev.BanId != 10 || ev.ReasonCategory != "spam" {
+			t.Errorf("неверная полезная нагрузка: %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("событие не получено")
+	}
+
+	if trace := <-traces; trace != "00-abc-def-01" {
+		t.Errorf("контекст трассировки не передан: %q", trace)
+	}
+}
+
+func TestUndecodableGoesToDeadLetter(t *testing.T) {
+	bus := NewMemoryBus()
+	bus.PublishRaw(testTopic, []byte("not json"), nil)
+
+	env, err := NewEnvelope("2", UserDeleted{UserId: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Publish(context.Background(), testTopic, env); err != nil {
+		t.Fatal(err)
+	}
+
+	consumer := NewMemoryConsumer(bus, "profile")
+	consumer.Subscribe(testTopic, func(ctx context.Context, env *Envelope) error {
+		// UserDeleted нельзя разобрать как UserRegistered
+		var ev UserRegistered
+		return env.Decode(&ev)
+	})
+	defer runConsumer(t, consumer)()
+
+	waitFor(t, func() bool { return len(bus.Records(DeadLetterTopic(testTopic))) == 2 })
+
+	dlq := bus.Records(DeadLetterTopic(testTopic))
+	if string(dlq[0].Value) != "not json" || dlq[0].Headers[headerError] == "" {
+		t.Errorf("в dead-letter ожидалось исходное сообщение с причиной: %+v", dlq[0])
+	}
+}
+
+func TestConsumerGroups(t *testing.T) {
+	bus := NewMemoryBus()
+	for i := int64(1); i <= 10; i++ {
+		env, err := NewEnvelope("1", UserRegistered{UserId: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bus.Publish(context.Background(), testTopic, env); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counts := make(chan string, 100)
+	newConsumer := func(group string) *MemoryConsumer {
+		c := NewMemoryConsumer(bus, group)
+		c.Subscribe(testTopic, func(ctx context.Context, env *Envelope) error {
+			counts <- group
+			return nil
+		})
+		return c
+	}
+
+	// два потребителя одной группы делят сообщения, другая группа получает все
+	defer runConsumer(t, newConsumer("profile"))()
+	defer runConsumer(t, newConsumer("profile"))()
+	defer runConsumer(t, newConsumer("notification"))()
+
+	got := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		select {
+		case group := <-counts:
+			got[group]++
+		case <-time.After(2 * time.Second):
+			t.Fatalf("получено меньше событий, чем ожидалось: %v", got)
+		}
+	}
+
+	if got["profile"] != 10 || got["notification"] != 10 {
+		t.Errorf("каждая группа должна получить каждое событие один раз: %v", got)
+	}
+}
+
+func TestFailingHandlerGoesToDeadLetter(t *testing.T) {
+	defer func(delay, maxDelay time.Duration) {
+		retryDelay, maxRetryDelay = delay, maxDelay
+	}(retryDelay, maxRetryDelay)
+	retryDelay, maxRetryDelay = time.Millisecond, time.Millisecond
+
+	bus := NewMemoryBus()
+	env, err := NewEnvelope("1", UserRegistered{UserId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Publish(context.Background(), testTopic, env); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	consumer := NewMemoryConsumer(bus, "profile")
+	consumer.Subscribe(testTopic, func(ctx context.Context, env *Envelope) error {
+		calls++
+		return errors.New("база недоступна")
+	})
+	defer runConsumer(t, consumer)()
+
+	waitFor(t, func() bool { return len(bus.Records(DeadLetterTopic(testTopic))) == 1 })
+
+	if calls != maxHandleAttempts {
+		t.Errorf("ожидалось %d попыток, выполнено %d", maxHandleAttempts, calls)
+	}
+}
@@ -0,0 +1,66 @@
+package events
+
+import "time"
+
+const (
+	TypeUserRegistered     = "UserRegistered"
+	TypeUserEmailConfirmed = "UserEmailConfirmed"
+	TypeUserBanned         = "UserBanned"
+	TypeUserUnbanned       = "UserUnbanned"
+	TypeUserDeleted        = "UserDeleted"
+)
+
+// UserRegistered пользователь зарегистрирован
+type UserRegistered struct {
+	UserId       int64     `json:"user_id"`
+	Email        string    `json:"email"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+func (UserRegistered) EventType() string { return TypeUserRegistered }
+func (UserRegistered) EventVersion() int { return 1 }
+
+// UserEmailConfirmed пользователь подтвердил email
+type UserEmailConfirmed struct {
+	UserId      int64     `json:"user_id"`
+	Email       string    `json:"email"`
+	ConfirmedAt time.Time `json:"confirmed_at"`
+}
+
+func (UserEmailConfirmed) EventType() string { return TypeUserEmailConfirmed }
+func (UserEmailConfirmed) EventVersion() int { return 1 }
+
+// UserBanned пользователь заблокирован, все его сессии отозваны
+type UserBanned struct {
+	UserId         int64      `json:"user_id"`
+	BanId          int64      `json:"ban_id"`
+	ReasonCategory string     `json:"reason_category"`
+	IsForever      bool       `json:"is_forever"`
+	BannedAt       time.Time  `json:"banned_at"`
+	ExpiredAt      *time.Time `json:"expired_at,omitempty"`
+	IssuedBy       *int64     `json:"issued_by,omitempty"`
+}
+
+func (UserBanned) EventType() string { return TypeUserBanned }
+func (UserBanned) EventVersion() int { return 1 }
+
+// UserUnbanned блокировка снята администратором, по апелляции или по истечении срока
+type UserUnbanned struct {
+	UserId     int64     `json:"user_id"`
+	BanId      int64     `json:"ban_id"`
+	UnbannedAt time.Time `json:"unbanned_at"`
+	UnbannedBy *int64    `json:"unbanned_by,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+func (UserUnbanned) EventType() string { return TypeUserUnbanned }
+func (UserUnbanned) EventVersion() int { return 1 }
+
+// UserDeleted аккаунт пользователя удален
+type UserDeleted struct {
+	UserId    int64     `json:"user_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+func (UserDeleted) EventType() string { return TypeUserDeleted }
+func (UserDeleted) EventVersion() int { return 1 }
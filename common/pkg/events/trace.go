@@ -0,0 +1,19 @@
+package events
+
+import "context"
+
+type traceKey struct{}
+
+// ContextWithTrace кладет контекст трассировки (например, traceparent и tracestate) в ctx
+func ContextWithTrace(ctx context.Context, trace map[string]string) context.Context {
+	if len(trace) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// TraceFromContext контекст трассировки, который продюсер запишет в конверт
+func TraceFromContext(ctx context.Context) map[string]string {
+	trace, _ := ctx.Value(traceKey{}).(map[string]string)
+	return trace
+}
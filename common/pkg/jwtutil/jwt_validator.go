@@ -77,13 +77,41 @@ func getClaimsByToken(claims jwt.MapClaims) (*models.JwtClaims, bool) {
 		return nil, false
 	}
 
+	// iat_ms есть только у токенов, выданных после перехода отзыва на миллисекунды
+	var iatMs int64
+	if v, ok := claims["iat_ms"].(float64); ok {
+		iatMs = int64(v)
+	}
+
+	// scope необязателен: разрешения кладутся в токен только если это включено в сервисе авторизации
+	var permissions []string
+	if scope, ok := claims["scope"].(string); ok && scope != "" {
+		permissions = strings.Fields(scope)
+	}
+
+	// act есть только у токенов имперсонации (RFC 8693)
+	var actor int64
+	if act, ok := claims["act"].(map[string]interface{}); ok {
+		actorSub, ok := act["sub"].(float64)
+		if !ok {
+			return nil, false
+		}
+		actor = int64(actorSub)
+	}
+
+	locale, _ := claims["locale"].(string)
+
 	jwtTok := models.JwtClaims{
 		Sub:           int64(sub),
 		Role:          roles,
+		Permissions:   permissions,
 		Ext:           int64(exp),
 		Email:         email,
 		EmailVerified: emailVerified,
 		Iat:           int64(iat),
+		IatMs:         iatMs,
+		Actor:         actor,
+		Locale:        locale,
 	}
 	return &jwtTok, true
 }
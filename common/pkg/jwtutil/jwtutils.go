@@ -17,12 +17,28 @@ func GenerateClaims(token *models.JwtClaims) map[string]interface{} {
 
 	}
 
-	return map[string]interface{}{
+	claims := map[string]interface{}{
 		"sub":            token.Sub,
 		"email":          token.Email,
 		"email_verified": token.EmailVerified,
 		"roles":          rls,
 	}
+
+	if len(token.Permissions) > 0 {
+		claims["scope"] = strings.Join(token.Permissions, " ")
+	}
+
+	if token.Locale != "" {
+		claims["locale"] = token.Locale
+	}
+
+	if token.Actor != 0 {
+		claims["act"] = map[string]interface{}{
+			"sub": token.Actor,
+		}
+	}
+
+	return claims
 }
 
 func ExtractBearerTokenHeader(c *gin.Context) (string, bool) {
@@ -0,0 +1,126 @@
+package jwtutil
+
+import (
+	"github.com/EddyZe/foodApp/common/domain/models"
+	"github.com/golang-jwt/jwt/v5"
+	"testing"
+	"time"
+)
+
+func signClaims(t *testing.T, claims map[string]interface{}, secret string) string {
+	jwtClaims := jwt.MapClaims{
+		"ext": time.Now().Add(time.Minute).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	for k, v := range claims {
+		jwtClaims[k] = v
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestPermissionsScope(t *testing.T) {
+	claims := GenerateClaims(&models.JwtClaims{
+		Sub:         1,
+		Email:       "admin@test.com",
+		Role:        []string{"admin"},
+		Permissions: []string{"users:ban", "users:unban"},
+	})
+
+	parsed, ok := ParseToken(signClaims(t, claims, "secret"), "secret")
+	if !ok {
+		t.Fatal("токен не распознан")
+	}
+
+	if !parsed.HasPermission("users:ban") || !parsed.HasPermission("users:unban") {
+		t.Errorf("разрешения не найдены в токене: %v", parsed.Permissions)
+	}
+
+	if parsed.HasPermission("roles:manage") {
+		t.Error("лишнее разрешение в токене")
+	}
+}
+
+func TestWithoutScope(t *testing.T) {
+	claims := GenerateClaims(&models.JwtClaims{
+		Sub:   1,
+		Email: "user@test.com",
+		Role:  []string{"user"},
+	})
+
+	if _, ok := claims["scope"]; ok {
+		t.Error("scope не должен добавляться без разрешений")
+	}
+
+	parsed, ok := ParseToken(signClaims(t, claims, "secret"), "secret")
+	if !ok {
+		t.Fatal("токен не распознан")
+	}
+
+	if len(parsed.Permissions) != 0 {
+		t.Errorf("ожидался пустой список разрешений: %v", parsed.Permissions)
+	}
+}
+
+func TestActorClaim(t *testing.T) {
+	claims := GenerateClaims(&models.JwtClaims{
+		Sub:   2,
+		Email: "user@test.com",
+		Role:  []string{"user"},
+		Actor: 1,
+	})
+
+	parsed, ok := ParseToken(signClaims(t, claims, "secret"), "secret")
+	if !ok {
+		t.Fatal("токен не распознан")
+	}
+
+	if !parsed.IsImpersonated() || parsed.Actor != 1 || parsed.Sub != 2 {
+		t.Errorf("ожидался токен имперсонации пользователя 2 администратором 1: %+v", parsed)
+	}
+}
+
+func TestLocaleClaim(t *testing.T) {
+	claims := GenerateClaims(&models.JwtClaims{
+		Sub:    1,
+		Email:  "user@test.com",
+		Role:   []string{"user"},
+		Locale: "ru",
+	})
+
+	parsed, ok := ParseToken(signClaims(t, claims, "secret"), "secret")
+	if !ok {
+		t.Fatal("токен не распознан")
+	}
+
+	if parsed.Locale != "ru" {
+		t.Errorf("ожидался язык ru: %q", parsed.Locale)
+	}
+}
+
+func TestIssuedAtMilli(t *testing.T) {
+	claims := GenerateClaims(&models.JwtClaims{Sub: 1, Email: "user@test.com", Role: []string{"user"}})
+
+	legacy, ok := ParseToken(signClaims(t, claims, "secret"), "secret")
+	if !ok {
+		t.Fatal("токен не распознан")
+	}
+	if legacy.IssuedAtMilli() != legacy.Iat*1000 {
+		t.Errorf("без iat_ms ожидалось начало секунды iat, получено %d", legacy.IssuedAtMilli())
+	}
+
+	issuedAt := time.Now()
+	claims["iat"] = issuedAt.Unix()
+	claims["iat_ms"] = issuedAt.UnixMilli()
+	parsed, ok := ParseToken(signClaims(t, claims, "secret"), "secret")
+	if !ok {
+		t.Fatal("токен не распознан")
+	}
+	if parsed.IssuedAtMilli() != issuedAt.UnixMilli() {
+		t.Errorf("iat_ms: ожидалось %d, получено %d", issuedAt.UnixMilli(), parsed.IssuedAtMilli())
+	}
+}
@@ -0,0 +1,245 @@
+package localizer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// IssueMissing ключ из констант отсутствует в файле языка
+	IssueMissing = "missing"
+	// IssueUnused ключ есть в файле, но такой константы нет
+	IssueUnused = "unused"
+	// IssueVariables переменные шаблона отличаются от эталонного языка
+	IssueVariables = "variables"
+	// IssuePlural у сообщения с формами множественного числа нет нужной для языка формы
+	IssuePlural = "plural"
+	IssueParse  = "parse"
+)
+
+// pluralForms формы множественного числа по CLDR для языков каталога. Для остальных языков проверяется только other
+var pluralForms = map[string][]string{
+	"en": {"one", "other"},
+	"ru": {"one", "few", "many", "other"},
+}
+
+var allPluralForms = []string{"zero", "one", "two", "few", "many", "other"}
+
+var templateVariable = regexp.MustCompile(`\{\{[^}]*?\.(\w+)`)
+
+type CatalogIssue struct {
+	Kind   string
+	Key    string
+	Locale string
+	Detail string
+}
+
+func (i CatalogIssue) String() string {
+	res := fmt.Sprintf("%-9s %-4s %s", i.Kind, i.Locale, i.Key)
+	if i.Detail != "" {
+		res += ": " + i.Detail
+	}
+	return res
+}
+
+// ParseIDs значения строковых констант из go файла, например localize_ids.go
+func ParseIDs(path string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			for _, value := range spec.(*ast.ValueSpec).Values {
+				lit, ok := value.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				id, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, id)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// CheckCatalog сверяет ключи ids с файлами переводов в dir. refLocale - язык,
+// с которым сравниваются переменные шаблонов остальных языков
+func CheckCatalog(ids []string, dir, refLocale string) ([]CatalogIssue, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var issues []CatalogIssue
+	// locale -> ключ -> форма -> текст
+	catalogs := make(map[string]map[string]map[string]string)
+	for _, f := range files {
+		locale := fileLocale(f)
+		messages, err := readMessages(f)
+		if err != nil {
+			issues = append(issues, CatalogIssue{Kind: IssueParse, Locale: locale, Key: filepath.Base(f), Detail: err.Error()})
+			continue
+		}
+		if catalogs[locale] == nil {
+			catalogs[locale] = make(map[string]map[string]string)
+		}
+		for key, forms := range messages {
+			catalogs[locale][key] = forms
+		}
+	}
+
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	known := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		known[id] = true
+	}
+
+	for _, locale := range locales {
+		messages := catalogs[locale]
+		for _, id := range ids {
+			if _, ok := messages[id]; !ok {
+				issues = append(issues, CatalogIssue{Kind: IssueMissing, Locale: locale, Key: id})
+			}
+		}
+
+		for _, key := range sortedKeys(messages) {
+			forms := messages[key]
+			if !known[key] {
+				issues = append(issues, CatalogIssue{Kind: IssueUnused, Locale: locale, Key: key})
+			}
+
+			if missing := missingPluralForms(locale, forms); len(missing) > 0 {
+				issues = append(issues, CatalogIssue{
+					Kind:   IssuePlural,
+					Locale: locale,
+					Key:    key,
+					Detail: "нет форм " + strings.Join(missing, ", "),
+				})
+			}
+
+			ref, ok := catalogs[refLocale][key]
+			if locale == refLocale || !ok {
+				continue
+			}
+			if want, got := variables(ref), variables(forms); !slices.Equal(want, got) {
+				issues = append(issues, CatalogIssue{
+					Kind:   IssueVariables,
+					Locale: locale,
+					Key:    key,
+					Detail: fmt.Sprintf("%s: %v, %s: %v", refLocale, want, locale, got),
+				})
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+// fileLocale язык по имени файла, как в go-i18n: auth.ru.toml -> ru
+func fileLocale(path string) string {
+	parts := strings.Split(strings.TrimSuffix(filepath.Base(path), ".toml"), ".")
+	return parts[len(parts)-1]
+}
+
+// readMessages сообщения файла: ключ -> форма -> текст. Запись вида Key = "текст" считается формой other
+func readMessages(path string) (map[string]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			res[key] = map[string]string{"other": v}
+		case map[string]interface{}:
+			forms := make(map[string]string)
+			for _, form := range allPluralForms {
+				if text, ok := v[form].(string); ok {
+					forms[form] = text
+				}
+			}
+			res[key] = forms
+		}
+	}
+
+	return res, nil
+}
+
+// missingPluralForms если у сообщения есть формы кроме other, у него должны быть все формы языка
+func missingPluralForms(locale string, forms map[string]string) []string {
+	required := []string{"other"}
+	if len(forms) > 1 || forms["other"] == "" {
+		base, _, _ := strings.Cut(locale, "-")
+		if langForms, ok := pluralForms[base]; ok {
+			required = langForms
+		}
+	}
+
+	var res []string
+	for _, form := range required {
+		if _, ok := forms[form]; !ok {
+			res = append(res, form)
+		}
+	}
+	return res
+}
+
+func variables(forms map[string]string) []string {
+	set := make(map[string]bool)
+	for _, text := range forms {
+		for _, m := range templateVariable.FindAllStringSubmatch(text, -1) {
+			set[m[1]] = true
+		}
+	}
+
+	res := make([]string, 0, len(set))
+	for v := range set {
+		res = append(res, v)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func sortedKeys(m map[string]map[string]string) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
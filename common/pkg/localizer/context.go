@@ -0,0 +1,30 @@
+package localizer
+
+import "github.com/gin-gonic/gin"
+
+const (
+	// ContextKey ключ gin контекста, под которым middleware.Locale сохраняет язык запроса
+	ContextKey = "locale"
+	// explicitContextKey язык выбран явно параметром или cookie и не заменяется языком из профиля
+	explicitContextKey = "locale_explicit"
+)
+
+// FromContext язык запроса, определенный middleware.Locale. Если middleware не подключен - Accept-Language как есть
+func FromContext(c *gin.Context) string {
+	if lang := c.GetString(ContextKey); lang != "" {
+		return lang
+	}
+	return c.GetHeader("Accept-Language")
+}
+
+// SetContext сохраняет язык запроса и дублирует его в заголовок Content-Language
+func SetContext(c *gin.Context, lang string, explicit bool) {
+	c.Set(ContextKey, lang)
+	c.Set(explicitContextKey, explicit)
+	c.Header("Content-Language", lang)
+}
+
+// IsExplicit язык выбран пользователем в запросе (параметр lang или cookie)
+func IsExplicit(c *gin.Context) bool {
+	return c.GetBool(explicitContextKey)
+}
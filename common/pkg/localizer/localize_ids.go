@@ -1,27 +1,71 @@
 package localizer
 
 const (
-	SendVerifiedCodeSubject  = "SendVerifiedCodeSubject"
-	SendVerifiedCodeBody     = "SendVerifiedCodeBody"
-	FieldRequired            = "FieldRequired"
-	FieldEmail               = "FieldEmail"
-	FieldMin                 = "FieldMin"
-	FieldDefault             = "FieldDefault"
-	InvalidEmailCode         = "InvalidEmailCode"
-	ExpiredEmailCode         = "ExpiredEmailCode"
-	InvalidBody              = "InvalidBody"
-	AccountIsBlocked         = "AccountIsBlocked"
-	AccountBanForever        = "AccountBanForever"
-	EmailConfirm             = "EmailConfirm"
-	Forbidden                = "Forbidden"
-	UserNotFoundByEmail      = "UserNotFoundByEmail"
-	ResetPasswordEmail       = "ResetPasswordEmail"
-	ResetPasswordSubject     = "ResetPasswordSubject"
-	InvalidResetPasswordCode = "InvalidResetPasswordCode"
-	CodeExpired              = "CodeExpired"
-	LastPasswords            = "LastPasswords"
-	Unauthorized             = "Unauthorized"
-	UserIsExists             = "UserIsExists"
-	InvalidEmailOrPassword   = "InvalidEmailOrPassword"
-	UserIsBlocked            = "UserIsBlocked"
+	FieldRequired             = "FieldRequired"
+	FieldEmail                = "FieldEmail"
+	FieldMin                  = "FieldMin"
+	FieldMax                  = "FieldMax"
+	FieldLen                  = "FieldLen"
+	FieldMinItems             = "FieldMinItems"
+	FieldMaxItems             = "FieldMaxItems"
+	FieldLenItems             = "FieldLenItems"
+	FieldMinValue             = "FieldMinValue"
+	FieldMaxValue             = "FieldMaxValue"
+	FieldOneOf                = "FieldOneOf"
+	FieldUrl                  = "FieldUrl"
+	FieldUuid                 = "FieldUuid"
+	FieldRequiredWithout      = "FieldRequiredWithout"
+	FieldDefault              = "FieldDefault"
+	InvalidEmailCode          = "InvalidEmailCode"
+	ExpiredEmailCode          = "ExpiredEmailCode"
+	InvalidBody               = "InvalidBody"
+	AccountIsBlocked          = "AccountIsBlocked"
+	AccountBanForever         = "AccountBanForever"
+	EmailConfirm              = "EmailConfirm"
+	Forbidden                 = "Forbidden"
+	UserNotFoundByEmail       = "UserNotFoundByEmail"
+	InvalidResetPasswordCode  = "InvalidResetPasswordCode"
+	CodeExpired               = "CodeExpired"
+	LastPasswords             = "LastPasswords"
+	Unauthorized              = "Unauthorized"
+	UserIsExists              = "UserIsExists"
+	InvalidEmailOrPassword    = "InvalidEmailOrPassword"
+	UserIsBlocked             = "UserIsBlocked"
+	InvalidParam              = "InvalidParam"
+	RoleNotFound              = "RoleNotFound"
+	RoleIsExists              = "RoleIsExists"
+	RoleIsProtected           = "RoleIsProtected"
+	OwnAdminRole              = "OwnAdminRole"
+	LastAdmin                 = "LastAdmin"
+	PermissionNotFound        = "PermissionNotFound"
+	PermissionIsExists        = "PermissionIsExists"
+	UserNotFound              = "UserNotFound"
+	UserRoleIsExists          = "UserRoleIsExists"
+	UserIsNotBlocked          = "UserIsNotBlocked"
+	BanAppealIsExists         = "BanAppealIsExists"
+	BanAppealNotFound         = "BanAppealNotFound"
+	BanAppealIsReviewed       = "BanAppealIsReviewed"
+	ImpersonationForbidden    = "ImpersonationForbidden"
+	ImpersonationNotAllowed   = "ImpersonationNotAllowed"
+	RegistrationRestricted    = "RegistrationRestricted"
+	InvalidInviteCode         = "InvalidInviteCode"
+	InviteRoleNotAllowed      = "InviteRoleNotAllowed"
+	InviteCodeNotFound        = "InviteCodeNotFound"
+	ConsentRequired           = "ConsentRequired"
+	ConsentDocumentIsExists   = "ConsentDocumentIsExists"
+	CodeResendCooldown        = "CodeResendCooldown"
+	CodeLimitExceeded         = "CodeLimitExceeded"
+	CodeAttemptsExceeded      = "CodeAttemptsExceeded"
+	TooManyRequests           = "TooManyRequests"
+	ResetCodeAttemptsExceeded = "ResetCodeAttemptsExceeded"
+	CodeNoLongerValid         = "CodeNoLongerValid"
+	InvalidRequest            = "InvalidRequest"
+	MailMessageNotFound       = "MailMessageNotFound"
+	MailSuppressionNotFound   = "MailSuppressionNotFound"
+	EmailUndeliverable        = "EmailUndeliverable"
+	AccountBanDaysLeft        = "AccountBanDaysLeft"
+	NotFound                  = "NotFound"
+	IsExists                  = "IsExists"
+	ServerError               = "ServerError"
+	ServiceUnavailable        = "ServiceUnavailable"
 )
@@ -1,42 +1,216 @@
 package localizer
 
 import (
+	"errors"
+	"fmt"
 	"github.com/BurntSushi/toml"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/text/language"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
+// catalog загруженные переводы. При перезагрузке заменяется целиком
+type catalog struct {
+	bundle  *i18n.Bundle
+	matcher language.Matcher
+	// version имена, размеры и время изменения файлов, по ним определяется, что каталог изменился
+	version string
+}
+
 type LocalizeService struct {
-	log    *logrus.Entry
-	bundle *i18n.Bundle
+	log *logrus.Entry
+	dir string
+	// defaultLang язык, если запрос не подошел ни к одному из загруженных, и язык сообщений по умолчанию
+	defaultLang language.Tag
+
+	mu  sync.RWMutex
+	cat *catalog
 }
 
-func NewLocalizeService(log *logrus.Entry, localizeDir string) *LocalizeService {
+// Option настройка LocalizeService
+type Option func(s *LocalizeService)
 
-	bundle := i18n.NewBundle(language.Russian)
+// WithDefaultLanguage язык по умолчанию вместо английского. Некорректный код языка игнорируется
+func WithDefaultLanguage(lang string) Option {
+	return func(s *LocalizeService) {
+		tag, err := language.Parse(lang)
+		if err != nil {
+			s.log.Errorf("некорректный язык по умолчанию %q, используется %s", lang, s.defaultLang)
+			return
+		}
+		s.defaultLang = tag
+	}
+}
+
+func NewLocalizeService(log *logrus.Entry, localizeDir string, opts ...Option) *LocalizeService {
+	s := &LocalizeService{
+		log:         log,
+		dir:         localizeDir,
+		defaultLang: language.English,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	cat, errs := loadCatalog(localizeDir, s.defaultLang)
+	for _, err := range errs {
+		// битый файл не должен мешать запуску, но и пропадать молча тоже
+		log.Error("ошибка загрузки файла переводов: ", err)
+	}
+	s.cat = cat
+
+	return s
+}
+
+func loadCatalog(dir string, defaultLang language.Tag) (*catalog, []error) {
+	bundle := i18n.NewBundle(defaultLang)
 	bundle.RegisterUnmarshalFunc("toml", toml.Unmarshal)
 
-	files, err := os.ReadDir(localizeDir)
+	files, version, err := catalogFiles(dir)
 	if err != nil {
-		log.Debug("Директория с переводами не найдена. Будут применены стандартные сообщения!")
+		return &catalog{
+			bundle:  bundle,
+			matcher: language.NewMatcher(bundle.LanguageTags()),
+		}, []error{err}
 	}
 
+	var errs []error
 	for _, f := range files {
-		if strings.HasSuffix(f.Name(), ".toml") {
-			_, err := bundle.LoadMessageFile(filepath.Join(localizeDir, f.Name()))
-			if err != nil {
-				continue
+		if _, err := bundle.LoadMessageFile(f); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(f), err))
+		}
+	}
+
+	return &catalog{
+		bundle:  bundle,
+		matcher: language.NewMatcher(bundle.LanguageTags()),
+		version: version,
+	}, errs
+}
+
+// catalogFiles toml файлы каталога и их версия
+func catalogFiles(dir string) ([]string, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var files []string
+	var version strings.Builder
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".toml") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, "", err
+		}
+
+		files = append(files, filepath.Join(dir, e.Name()))
+		fmt.Fprintf(&version, "%s:%d:%d;", e.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	sort.Strings(files)
+
+	return files, version.String(), nil
+}
+
+func (s *LocalizeService) current() *catalog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cat
+}
+
+// Reload перечитывает файлы переводов. Если хотя бы один файл не разбирается,
+// остаются прежние переводы, чтобы опечатка в файле не сломала сообщения на работающем сервисе
+func (s *LocalizeService) Reload() error {
+	cat, errs := loadCatalog(s.dir, s.defaultLang)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	s.mu.Lock()
+	s.cat = cat
+	s.mu.Unlock()
+
+	s.log.Infof("переводы перезагружены, языки: %s", strings.Join(s.Languages(), ", "))
+	return nil
+}
+
+// Watch проверяет каталог переводов каждые interval и перезагружает его при изменении файлов.
+// Возвращает функцию остановки
+func (s *LocalizeService) Watch(interval time.Duration) func() {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_, version, err := catalogFiles(s.dir)
+				if err != nil || version == s.current().version {
+					continue
+				}
+				if err := s.Reload(); err != nil {
+					s.log.Error("переводы не перезагружены: ", err)
+				}
 			}
 		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
 	}
-	return &LocalizeService{
-		log:    log,
-		bundle: bundle,
+}
+
+// Languages языки, для которых загружены переводы. Первый - язык по умолчанию
+func (s *LocalizeService) Languages() []string {
+	tags := s.current().bundle.LanguageTags()
+	res := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, tag.String())
+	}
+	return res
+}
+
+func (s *LocalizeService) DefaultLanguage() string {
+	return s.defaultLang.String()
+}
+
+// Match подбирает загруженный язык по списку предпочтений. Каждое значение - код языка или
+// заголовок Accept-Language с весами q, например "ru-RU,ru;q=0.9,en;q=0.8". false - подходящего языка нет
+func (s *LocalizeService) Match(prefs ...string) (string, bool) {
+	var tags []language.Tag
+	for _, pref := range prefs {
+		if pref == "" {
+			continue
+		}
+		parsed, _, err := language.ParseAcceptLanguage(pref)
+		if err != nil {
+			continue
+		}
+		tags = append(tags, parsed...)
+	}
+	if len(tags) == 0 {
+		return "", false
+	}
+
+	cat := s.current()
+	_, idx, confidence := cat.matcher.Match(tags...)
+	if confidence == language.No {
+		return "", false
 	}
+
+	return cat.bundle.LanguageTags()[idx].String(), true
 }
 
 // GetMessage находит переыод сообщения
@@ -44,16 +218,62 @@ func (s *LocalizeService) GetMessage(
 	idTranslate, lang, defaultMessage string,
 	templateData map[string]interface{},
 ) string {
-	localizer := i18n.NewLocalizer(s.bundle, lang)
-	res, err := localizer.Localize(&i18n.LocalizeConfig{
+	return s.localize(lang, defaultMessage, &i18n.LocalizeConfig{
 		DefaultMessage: &i18n.Message{
 			ID:    idTranslate,
 			Other: defaultMessage,
 		},
 		TemplateData: templateData,
 	})
+}
+
+// GetPluralMessage перевод с формой, зависящей от count: "1 попытка", "3 попытки", "5 попыток".
+// count доступен в шаблоне как {{.count}}. one и other - сообщения по умолчанию
+func (s *LocalizeService) GetPluralMessage(
+	idTranslate, lang string,
+	count int64,
+	one, other string,
+	templateData map[string]interface{},
+) string {
+	data := make(map[string]interface{}, len(templateData)+1)
+	for k, v := range templateData {
+		data[k] = v
+	}
+	data["count"] = count
+
+	defaultMessage := other
+	if count == 1 {
+		defaultMessage = one
+	}
+
+	return s.localize(lang, defaultMessage, &i18n.LocalizeConfig{
+		// формы, которых нет в английском, берутся из other, иначе для ru без перевода будет ошибка
+		DefaultMessage: &i18n.Message{
+			ID:    idTranslate,
+			Zero:  other,
+			One:   one,
+			Two:   other,
+			Few:   other,
+			Many:  other,
+			Other: other,
+		},
+		PluralCount:  count,
+		TemplateData: data,
+	})
+}
+
+func (s *LocalizeService) localize(lang, defaultMessage string, cfg *i18n.LocalizeConfig) string {
+	localizer := i18n.NewLocalizer(s.current().bundle, lang)
+	res, err := localizer.Localize(cfg)
 
 	if err != nil {
+		// перевода нет на этом языке, но он есть на языке по умолчанию: лучше он, чем сообщение из кода
+		var notFound *i18n.MessageNotFoundErr
+		if errors.As(err, &notFound) && res != "" {
+			s.log.Warn("нет перевода: ", err)
+			return res
+		}
+
 		s.log.Error("ошибка при локализации сообщения: ", err)
 		return defaultMessage
 	}
@@ -3,19 +3,150 @@ package localizer
 import (
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestGetMessage(t *testing.T) {
 	ls := NewLocalizeService(logrus.NewEntry(logrus.New()), "./locales")
 	res := ls.GetMessage(
-		SendVerifiedCodeBody,
+		FieldRequired,
 		"ru",
-		"hello {{.code}}",
+		"Field {{.field}} required",
 		map[string]interface{}{
-			"code": "test",
+			"field": "test",
 		},
 	)
 
 	fmt.Println(res)
 }
+
+func TestMatch(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"app.en.toml", "app.ru.toml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("[Hello]\nother = \"hello\"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ls := NewLocalizeService(logrus.NewEntry(logrus.New()), dir)
+
+	cases := []struct {
+		prefs []string
+		want  string
+		ok    bool
+	}{
+		{[]string{"en-US,en;q=0.9,ru;q=0.8"}, "en", true},
+		{[]string{"de-DE,de;q=0.9,ru;q=0.5,en;q=0.3"}, "ru", true},
+		{[]string{"ru-RU"}, "ru", true},
+		{[]string{"", "en"}, "en", true},
+		{[]string{"de,fr;q=0.5"}, "", false},
+		{[]string{"not a language"}, "", false},
+	}
+
+	for _, tc := range cases {
+		got, ok := ls.Match(tc.prefs...)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("Match(%q) = %q, %v; ожидалось %q, %v", tc.prefs, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	if ls.DefaultLanguage() != "en" {
+		t.Errorf("язык по умолчанию %q", ls.DefaultLanguage())
+	}
+
+	ls = NewLocalizeService(logrus.NewEntry(logrus.New()), dir, WithDefaultLanguage("ru"))
+	if ls.DefaultLanguage() != "ru" {
+		t.Errorf("язык по умолчанию из настроек %q", ls.DefaultLanguage())
+	}
+	if langs := ls.Languages(); langs[0] != "ru" {
+		t.Errorf("языки %v, первым должен быть язык по умолчанию", langs)
+	}
+}
+
+func TestPluralAndReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.ru.toml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("[Days]\none = \"{{.count}} день\"\nfew = \"{{.count}} дня\"\nmany = \"{{.count}} дней\"\nother = \"{{.count}} дня\"\n")
+	ls := NewLocalizeService(logrus.NewEntry(logrus.New()), dir)
+
+	for count, want := range map[int64]string{1: "1 день", 3: "3 дня", 5: "5 дней", 21: "21 день"} {
+		if got := ls.GetPluralMessage("Days", "ru", count, "{{.count}} day", "{{.count}} days", nil); got != want {
+			t.Errorf("count %d: %q, ожидалось %q", count, got, want)
+		}
+	}
+
+	write("[Days]\nother = \"дни\"\n")
+	if err := ls.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ls.GetMessage("Days", "ru", "", nil); got != "дни" {
+		t.Errorf("после перезагрузки %q", got)
+	}
+
+	write("[Days\n")
+	if err := ls.Reload(); err == nil {
+		t.Error("ожидалась ошибка разбора")
+	}
+	if got := ls.GetMessage("Days", "ru", "", nil); got != "дни" {
+		t.Errorf("после неудачной перезагрузки %q", got)
+	}
+}
+
+// Сообщения без перевода собираются из one/other и для языков с формами few и many
+func TestPluralDefaultMessage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.ru.toml"), []byte("[Hello]\nother = \"привет\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, defaultLang := range []string{"en", "ru"} {
+		ls := NewLocalizeService(logrus.NewEntry(logrus.New()), dir, WithDefaultLanguage(defaultLang))
+		for count, want := range map[int64]string{0: "0 days", 1: "1 day", 2: "2 days", 3: "3 days", 5: "5 days"} {
+			if got := ls.GetPluralMessage("Missing", "ru", count, "{{.count}} day", "{{.count}} days", nil); got != want {
+				t.Errorf("язык по умолчанию %s, count %d: %q, ожидалось %q", defaultLang, count, got, want)
+			}
+		}
+	}
+}
+
+func TestCheckCatalog(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.en.toml": "[Hello]\nother = \"Hello, {{.name}}\"\n[Days]\none = \"day\"\nother = \"days\"\n",
+		"app.ru.toml": "[Hello]\nother = \"Привет, {{.user}}\"\n[Days]\none = \"день\"\nother = \"дня\"\n[Old]\nother = \"старое\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	issues, err := CheckCatalog([]string{"Hello", "Days", "Bye"}, dir, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{
+		IssueMissing + " en Bye":     true,
+		IssueMissing + " ru Bye":     true,
+		IssuePlural + " ru Days":     true,
+		IssueUnused + " ru Old":      true,
+		IssueVariables + " ru Hello": true,
+	}
+	for _, issue := range issues {
+		key := issue.Kind + " " + issue.Locale + " " + issue.Key
+		if !want[key] {
+			t.Errorf("лишняя проблема: %s", issue)
+		}
+		delete(want, key)
+	}
+	for key := range want {
+		t.Errorf("не найдена проблема: %s", key)
+	}
+}
@@ -0,0 +1,14 @@
+package permissions
+
+const (
+	UsersRead   string = "users:read"
+	UsersBan    string = "users:ban"
+	UsersUnban  string = "users:unban"
+	RolesManage string = "roles:manage"
+	// UsersImpersonate вход от имени пользователя для поддержки
+	UsersImpersonate string = "users:impersonate"
+	InvitesManage    string = "invites:manage"
+	ConsentsManage   string = "consents:manage"
+	// MailManage просмотр очереди писем и повторная отправка недоставленных
+	MailManage string = "mail:manage"
+)
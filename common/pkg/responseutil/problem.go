@@ -0,0 +1,78 @@
+package responseutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	commonDto "github.com/EddyZe/foodApp/common/domain/dto"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+	// TraceIdKey ключ в gin.Context, под которым middleware.Trace хранит идентификатор запроса
+	TraceIdKey = "trace_id"
+	// ProblemOptionsKey ключ в gin.Context, под которым middleware.Problems хранит ProblemOptions
+	ProblemOptionsKey = "problem_options"
+)
+
+// ProblemOptions ответы об ошибках в формате RFC 7807. Enabled - для всех запросов, иначе формат выбирается
+// только клиентами, которые прислали Accept: application/problem+json. TypeBase - префикс URI типа ошибки:
+// TypeBase + "not-found", по умолчанию /problems/
+type ProblemOptions struct {
+	Enabled  bool
+	TypeBase string
+}
+
+func problemOptions(c *gin.Context) ProblemOptions {
+	opts, _ := c.Value(ProblemOptionsKey).(ProblemOptions)
+	return opts
+}
+
+func wantsProblem(c *gin.Context) bool {
+	return problemOptions(c).Enabled || strings.Contains(c.GetHeader("Accept"), ProblemContentType)
+}
+
+func problemResponse(c *gin.Context, status int, code, message string, details []interface{}) {
+	problem := commonDto.Problem{
+		Type:     ProblemType(problemOptions(c).TypeBase, code),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   message,
+		Instance: c.Request.URL.RequestURI(),
+		Code:     code,
+		TraceId:  c.GetString(TraceIdKey),
+	}
+	if len(details) > 0 {
+		problem.Errors = details
+	}
+
+	c.Render(status, problemRender{problem})
+}
+
+// ProblemType URI типа ошибки по коду: NOT_FOUND -> <typeBase>not-found
+func ProblemType(typeBase, code string) string {
+	base := typeBase
+	if base == "" {
+		base = "/problems/"
+	}
+	return base + strings.ReplaceAll(strings.ToLower(code), "_", "-")
+}
+
+// problemRender как render.JSON, но с Content-Type application/problem+json
+type problemRender struct {
+	problem commonDto.Problem
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.problem)
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if val := header["Content-Type"]; len(val) == 0 {
+		header["Content-Type"] = []string{ProblemContentType + "; charset=utf-8"}
+	}
+}
@@ -0,0 +1,136 @@
+package responseutil
+
+import (
+	"errors"
+
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorSpec как доменная ошибка отдается клиенту: статус, код и ключ перевода сообщения
+type ErrorSpec struct {
+	Status    int
+	Code      string
+	MessageId string
+	Default   string
+}
+
+type registryEntry struct {
+	target error
+	match  func(error) bool
+	spec   ErrorSpec
+}
+
+// ErrorRegistry сопоставляет доменные ошибки с ответами. Ошибки ищутся через errors.Is,
+// поэтому обернутые через fmt.Errorf("...: %w", err) ошибки тоже находятся
+type ErrorRegistry struct {
+	log      *logrus.Entry
+	ls       *localizer.LocalizeService
+	entries  []registryEntry
+	fallback ErrorSpec
+}
+
+// NewErrorRegistry fallback отдается для ошибок, которых нет в реестре, такие ошибки логируются
+func NewErrorRegistry(log *logrus.Entry, ls *localizer.LocalizeService, fallback ErrorSpec) *ErrorRegistry {
+	return &ErrorRegistry{
+		log:      log,
+		ls:       ls,
+		fallback: fallback,
+	}
+}
+
+// Register сопоставляет spec с ошибкой target
+func (r *ErrorRegistry) Register(target error, spec ErrorSpec) *ErrorRegistry {
+	r.entries = append(r.entries, isEntry(target, spec))
+	return r
+}
+
+func isEntry(target error, spec ErrorSpec) registryEntry {
+	return registryEntry{
+		target: target,
+		match:  func(err error) bool { return errors.Is(err, target) },
+		spec:   spec,
+	}
+}
+
+// With копия реестра, в которой target сопоставлен со spec. Нужна, когда одна и та же ошибка
+// в разных обработчиках значит разное: NotFound роли и NotFound разрешения. Если target уже есть
+// в реестре, spec заменяется на том же месте, чтобы ошибки с кодом по-прежнему проверялись раньше
+// общих видов, иначе target проверяется первым
+func (r *ErrorRegistry) With(target error, spec ErrorSpec) *ErrorRegistry {
+	entries := make([]registryEntry, 0, len(r.entries)+1)
+	replaced := false
+	for _, e := range r.entries {
+		if e.target == target && !replaced {
+			e = isEntry(target, spec)
+			replaced = true
+		}
+		entries = append(entries, e)
+	}
+	if !replaced {
+		entries = append([]registryEntry{isEntry(target, spec)}, entries...)
+	}
+
+	return &ErrorRegistry{
+		log:      r.log,
+		ls:       r.ls,
+		entries:  entries,
+		fallback: r.fallback,
+	}
+}
+
+// WithMessage как With, но меняет только сообщение, статус и код остаются из реестра
+func (r *ErrorRegistry) WithMessage(target error, messageId, defaultMessage string) *ErrorRegistry {
+	spec, _ := r.Lookup(target)
+	spec.MessageId = messageId
+	spec.Default = defaultMessage
+	return r.With(target, spec)
+}
+
+// Lookup spec для ошибки и признак, что ошибка есть в реестре
+func (r *ErrorRegistry) Lookup(err error) (ErrorSpec, bool) {
+	for _, e := range r.entries {
+		if e.match(err) {
+			return e.spec, true
+		}
+	}
+	return r.fallback, false
+}
+
+// TemplateDataError ошибка, которая сама несет данные для шаблона сообщения, например имя некорректного параметра
+type TemplateDataError interface {
+	error
+	TemplateData() map[string]interface{}
+}
+
+// Respond отвечает ошибкой по реестру. Данные шаблона сообщения берутся из ошибки (TemplateDataError),
+// data дополняет и переопределяет их
+func (r *ErrorRegistry) Respond(c *gin.Context, err error, data map[string]interface{}, details ...interface{}) {
+	spec, ok := r.Lookup(err)
+	if !ok {
+		r.log.Error(err)
+	}
+
+	msg := spec.Default
+	if spec.MessageId != "" {
+		msg = r.ls.GetMessage(spec.MessageId, localizer.FromContext(c), spec.Default, templateData(err, data))
+	}
+	ErrorResponse(c, spec.Status, spec.Code, msg, details...)
+}
+
+func templateData(err error, data map[string]interface{}) map[string]interface{} {
+	var tdErr TemplateDataError
+	if !errors.As(err, &tdErr) {
+		return data
+	}
+
+	res := make(map[string]interface{}, len(data))
+	for k, v := range tdErr.TemplateData() {
+		res[k] = v
+	}
+	for k, v := range data {
+		res[k] = v
+	}
+	return res
+}
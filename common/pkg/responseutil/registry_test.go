@@ -0,0 +1,159 @@
+package responseutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	commonDto "github.com/EddyZe/foodApp/common/domain/dto"
+	"github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+var errTestNotFound = errors.New("NOT_FOUND")
+
+func TestRegistryProblemResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logrus.NewEntry(logrus.New())
+	ls := localizer.NewLocalizeService(log, t.TempDir())
+
+	registry := NewErrorRegistry(log, ls, ErrorSpec{Status: http.StatusInternalServerError, Code: "SERVER_INTERNAL_ERROR"}).
+		Register(errTestNotFound, ErrorSpec{Status: http.StatusNotFound, Code: "NOT_FOUND", Default: "Not found"})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/roles/7?x=1", nil)
+	c.Request.Header.Set("Accept", ProblemContentType)
+	c.Set(TraceIdKey, "abc")
+
+	err := fmt.Errorf("поиск роли: %w", errTestNotFound)
+	registry.WithMessage(errTestNotFound, "RoleNotFound", "Role {{.id}} not found").
+		Respond(c, err, map[string]interface{}{"id": 7})
+
+	if ct := w.Header().Get("Content-Type"); ct != ProblemContentType+"; charset=utf-8" {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	var problem commonDto.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	want := commonDto.Problem{
+		Type:     "/problems/not-found",
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Detail:   "Role 7 not found",
+		Instance: "/api/v1/roles/7?x=1",
+		Code:     "NOT_FOUND",
+		TraceId:  "abc",
+	}
+	if problem != want {
+		t.Errorf("problem %+v, ожидалось %+v", problem, want)
+	}
+
+	if spec, ok := registry.Lookup(errors.New("other")); ok || spec.Status != http.StatusInternalServerError {
+		t.Errorf("неизвестная ошибка: %+v, %v", spec, ok)
+	}
+}
+
+func TestRegistryWithKeepsOrder(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	errKind := errors.New("conflict")
+	errReviewed := fmt.Errorf("APPEAL_IS_REVIEWED: %w", errKind)
+
+	registry := NewErrorRegistry(log, nil, ErrorSpec{Status: http.StatusInternalServerError}).
+		Register(errReviewed, ErrorSpec{Status: http.StatusConflict, Code: "APPEAL_IS_REVIEWED"}).
+		Register(errKind, ErrorSpec{Status: http.StatusConflict, Code: "IS_ALREADY_EXISTS"})
+
+	// переопределение общего вида не должно перехватывать ошибку с кодом, которая к нему относится
+	override := registry.WithMessage(errKind, "BanAppealIsExists", "exists")
+	if spec, _ := override.Lookup(errReviewed); spec.Code != "APPEAL_IS_REVIEWED" {
+		t.Errorf("ошибка с кодом: %+v", spec)
+	}
+	if spec, _ := override.Lookup(errKind); spec.MessageId != "BanAppealIsExists" {
+		t.Errorf("переопределение: %+v", spec)
+	}
+	if spec, _ := registry.Lookup(errKind); spec.MessageId != "" {
+		t.Errorf("исходный реестр изменился: %+v", spec)
+	}
+}
+
+func TestProblemOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name    string
+		opts    *ProblemOptions
+		problem bool
+		typeUri string
+	}{
+		{"без настроек", nil, false, ""},
+		{"выключено", &ProblemOptions{}, false, ""},
+		{"включено", &ProblemOptions{Enabled: true, TypeBase: "https://example.com/problems/"}, true, "https://example.com/problems/not-found"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.opts != nil {
+				c.Set(ProblemOptionsKey, *tc.opts)
+			}
+
+			ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", "Not found")
+
+			isProblem := strings.HasPrefix(w.Header().Get("Content-Type"), ProblemContentType)
+			if isProblem != tc.problem {
+				t.Fatalf("Content-Type %q", w.Header().Get("Content-Type"))
+			}
+			if !tc.problem {
+				return
+			}
+			var problem commonDto.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Type != tc.typeUri {
+				t.Errorf("type %q", problem.Type)
+			}
+		})
+	}
+}
+
+type testParamError struct{ param string }
+
+func (e *testParamError) Error() string { return "invalid " + e.param }
+
+func (e *testParamError) Unwrap() error { return errTestNotFound }
+
+func (e *testParamError) TemplateData() map[string]interface{} {
+	return map[string]interface{}{"param": e.param, "id": 1}
+}
+
+func TestRegistryTemplateDataFromError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logrus.NewEntry(logrus.New())
+	registry := NewErrorRegistry(log, localizer.NewLocalizeService(log, t.TempDir()), ErrorSpec{}).
+		Register(errTestNotFound, ErrorSpec{Status: http.StatusBadRequest, Code: "INVALID", MessageId: "Invalid", Default: "{{.param}} {{.id}}"})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("Accept", ProblemContentType)
+
+	// данные обработчика дополняют данные ошибки и важнее их
+	registry.Respond(c, fmt.Errorf("поиск: %w", &testParamError{"cursor"}), map[string]interface{}{"id": 2})
+
+	var problem commonDto.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Detail != "cursor 2" {
+		t.Errorf("detail %q", problem.Detail)
+	}
+}
@@ -6,6 +6,11 @@ import (
 )
 
 func ErrorResponse(c *gin.Context, status int, code, message string, details ...interface{}) {
+	if wantsProblem(c) {
+		problemResponse(c, status, code, message, details)
+		return
+	}
+
 	err := commonDto.APIError{
 		Code:    code,
 		Message: message,
@@ -0,0 +1,87 @@
+package revocation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/EddyZe/foodApp/common/domain/models"
+	"github.com/EddyZe/foodApp/common/pkg/redisutil"
+)
+
+const (
+	userPrefix  = "revoked:user"
+	tokenPrefix = "revoked:token"
+)
+
+// Store хранилище отзывов (обычно общий redis). Сервис авторизации пишет туда ключи, остальные сервисы только читают
+type Store interface {
+	Get(key string) (string, bool)
+}
+
+// LookupStore хранилище, которое отличает отсутствие ключа от собственной недоступности
+type LookupStore interface {
+	Lookup(key string) (string, bool, error)
+}
+
+// UserKey ключ с временем отзыва всех токенов пользователя в unix ms
+func UserKey(userId int64) string {
+	return redisutil.GenerateKey(userPrefix, strconv.FormatInt(userId, 10))
+}
+
+// TokenKey ключ отозванного access токена. В ключе хранится хеш, а не сам токен
+func TokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return redisutil.GenerateKey(tokenPrefix, hex.EncodeToString(sum[:]))
+}
+
+// Checker проверяет, что access токен не был отозван после выдачи
+type Checker struct {
+	store Store
+	// failClosed считать токен отозванным, если хранилище недоступно
+	failClosed bool
+}
+
+func NewChecker(store Store, failClosed bool) *Checker {
+	return &Checker{
+		store:      store,
+		failClosed: failClosed,
+	}
+}
+
+// IsRevoked токен отозван, если он в черном списке или выдан не позже отзыва всех токенов пользователя.
+// Время сравнивается в миллисекундах: токен, выданный в ту же секунду, но после отзыва, действителен.
+// Если хранилище недоступно, результат зависит от failClosed
+func (c *Checker) IsRevoked(token string, claims *models.JwtClaims) bool {
+	_, ok, err := c.lookup(TokenKey(token))
+	if err != nil {
+		return c.failClosed
+	}
+	if ok {
+		return true
+	}
+
+	value, ok, err := c.lookup(UserKey(claims.Sub))
+	if err != nil {
+		return c.failClosed
+	}
+	if !ok {
+		return false
+	}
+
+	revokedAt, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return claims.IssuedAtMilli() <= revokedAt
+}
+
+func (c *Checker) lookup(key string) (string, bool, error) {
+	if ls, ok := c.store.(LookupStore); ok {
+		return ls.Lookup(key)
+	}
+	value, ok := c.store.Get(key)
+	return value, ok, nil
+}
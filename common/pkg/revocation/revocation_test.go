@@ -0,0 +1,84 @@
+package revocation
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/EddyZe/foodApp/common/domain/models"
+)
+
+type mapStore map[string]string
+
+func (s mapStore) Get(key string) (string, bool) {
+	v, ok := s[key]
+	return v, ok
+}
+
+func TestRevokedByUser(t *testing.T) {
+	store := mapStore{UserKey(7): strconv.FormatInt(1000500, 10)}
+	checker := NewChecker(store, false)
+
+	if !checker.IsRevoked("old", &models.JwtClaims{Sub: 7, Iat: 999, IatMs: 999900}) {
+		t.Error("токен, выданный до отзыва, должен быть отозван")
+	}
+	if checker.IsRevoked("new", &models.JwtClaims{Sub: 7, Iat: 1001, IatMs: 1001000}) {
+		t.Error("токен, выданный после отзыва, не должен быть отозван")
+	}
+	if checker.IsRevoked("other", &models.JwtClaims{Sub: 8, Iat: 999, IatMs: 999900}) {
+		t.Error("отзыв не должен затрагивать других пользователей")
+	}
+}
+
+// Отзыв и новый вход в пределах одной секунды: решает миллисекундное время выдачи
+func TestRevokedWithinSecond(t *testing.T) {
+	store := mapStore{UserKey(7): strconv.FormatInt(1000500, 10)}
+	checker := NewChecker(store, false)
+
+	if !checker.IsRevoked("before", &models.JwtClaims{Sub: 7, Iat: 1000, IatMs: 1000200}) {
+		t.Error("токен, выданный в ту же секунду до отзыва, должен быть отозван")
+	}
+	if !checker.IsRevoked("same", &models.JwtClaims{Sub: 7, Iat: 1000, IatMs: 1000500}) {
+		t.Error("токен, выданный в момент отзыва, должен быть отозван")
+	}
+	if checker.IsRevoked("after", &models.JwtClaims{Sub: 7, Iat: 1000, IatMs: 1000700}) {
+		t.Error("токен, выданный в ту же секунду после отзыва, не должен быть отозван")
+	}
+	// у старого токена известна только секунда, поэтому он считается выданным в ее начале
+	if !checker.IsRevoked("legacy", &models.JwtClaims{Sub: 7, Iat: 1000}) {
+		t.Error("старый токен, выданный в секунду отзыва, должен быть отозван")
+	}
+}
+
+func TestRevokedByToken(t *testing.T) {
+	store := mapStore{TokenKey("token"): "true"}
+	checker := NewChecker(store, false)
+
+	if !checker.IsRevoked("token", &models.JwtClaims{Sub: 1, Iat: 1}) {
+		t.Error("токен из черного списка должен быть отозван")
+	}
+	if checker.IsRevoked("another", &models.JwtClaims{Sub: 1, Iat: 1}) {
+		t.Error("токен не из черного списка не должен быть отозван")
+	}
+}
+
+type downStore struct{}
+
+func (downStore) Get(string) (string, bool) {
+	return "", false
+}
+
+func (downStore) Lookup(string) (string, bool, error) {
+	return "", false, errors.New("хранилище недоступно")
+}
+
+func TestUnavailableStore(t *testing.T) {
+	claims := &models.JwtClaims{Sub: 1, Iat: 1}
+
+	if NewChecker(downStore{}, false).IsRevoked("token", claims) {
+		t.Error("при fail open недоступное хранилище не должно отзывать токен")
+	}
+	if !NewChecker(downStore{}, true).IsRevoked("token", claims) {
+		t.Error("при fail closed недоступное хранилище должно отзывать токен")
+	}
+}
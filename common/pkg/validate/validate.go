@@ -2,72 +2,201 @@ package validate
 
 import (
 	"fmt"
-	localizer2 "github.com/EddyZe/foodApp/common/pkg/localizer"
-	"github.com/go-playground/validator/v10"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+
+	commonDto "github.com/EddyZe/foodApp/common/domain/dto"
+	localizer2 "github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
-func ValidateBody(validationErrors validator.ValidationErrors, ls *localizer2.LocalizeService, lang string) string {
-	errorMessages := ""
+// RegisterTagNames имена полей в ошибках берутся из тегов json или form, чтобы клиент получал то имя, которое отправлял.
+// Меняет общий валидатор gin, поэтому вызывается один раз при запуске, до обработки запросов
+func RegisterTagNames() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return fmt.Errorf("неподдерживаемый валидатор gin: %T", binding.Validator.Engine())
+	}
+	v.RegisterTagNameFunc(fieldName)
+
+	return nil
+}
+
+func fieldName(fld reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(fld.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return camelToSnake(fld.Name)
+}
+
+// ValidateBody ошибки валидации одной строкой через "; "
+func ValidateBody(obj any, validationErrors validator.ValidationErrors, ls *localizer2.LocalizeService, lang string) string {
+	fields := FieldErrors(obj, validationErrors, ls, lang)
+	messages := make([]string, 0, len(fields))
+	for _, f := range fields {
+		messages = append(messages, f.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// FieldErrors переведенные ошибки по каждому полю. obj - проверенная структура: по ней поля,
+// на которые ссылаются правила вроде required_without, называются так же, как в тегах
+func FieldErrors(obj any, validationErrors validator.ValidationErrors, ls *localizer2.LocalizeService, lang string) []commonDto.FieldError {
+	res := make([]commonDto.FieldError, 0, len(validationErrors))
 	for _, fieldError := range validationErrors {
-		switch fieldError.Tag() {
-		case "required":
-			msg := ls.GetMessage(
-				localizer2.FieldRequired,
-				lang,
-				fmt.Sprintf("Field %s required", camelToSnake(fieldError.Field())),
-				map[string]interface{}{
-					"field": camelToSnake(fieldError.Field()),
-				},
-			)
-
-			errorMessages += msg + "; "
-		case "email":
-			msg := ls.GetMessage(
-				localizer2.FieldEmail,
-				lang,
-				fmt.Sprintf("Invalid email in field %s", camelToSnake(fieldError.Field())),
-				map[string]interface{}{
-					"field": camelToSnake(fieldError.Field()),
-				},
-			)
-
-			errorMessages += msg
-		case "min":
-			msg := ls.GetMessage(
-				localizer2.FieldMin,
-				lang,
-				fmt.Sprintf("Field %s must be at least %s characters long", camelToSnake(fieldError.Field()), fieldError.Param()),
-				map[string]interface{}{
-					"field": camelToSnake(fieldError.Field()),
-					"param": fieldError.Param(),
-				},
-			)
-
-			errorMessages += msg + "; "
+		field := fieldPath(fieldError)
+		param := fieldError.Param()
+		if fieldError.Tag() == "required_without" {
+			param = paramName(obj, fieldError)
+		}
+		res = append(res, commonDto.FieldError{
+			Field:   field,
+			Rule:    fieldError.Tag(),
+			Param:   param,
+			Message: fieldMessage(fieldError, field, param, ls, lang),
+		})
+	}
+	return res
+}
+
+// paramName имя соседнего поля из параметра правила по тегам json или form. Родительская структура
+// находится по пути поля в obj. Если поле найти не удалось, имя приводится к snake_case
+func paramName(obj any, fieldError validator.FieldError) string {
+	param := fieldError.Param()
+	t := reflect.TypeOf(obj)
+	segments := strings.Split(fieldError.StructNamespace(), ".")
+	// первый сегмент - имя корневой структуры, последний - само поле
+	for i := 1; i < len(segments)-1 && t != nil; i++ {
+		t = elemType(t)
+		if t.Kind() != reflect.Struct {
+			return camelToSnake(param)
+		}
+		name, _, _ := strings.Cut(segments[i], "[")
+		f, ok := t.FieldByName(name)
+		if !ok {
+			return camelToSnake(param)
+		}
+		t = f.Type
+	}
+	if t == nil {
+		return camelToSnake(param)
+	}
+
+	if t = elemType(t); t.Kind() == reflect.Struct {
+		if f, ok := t.FieldByName(param); ok {
+			return fieldName(f)
+		}
+	}
+	return camelToSnake(param)
+}
+
+// elemType тип значения за указателями и тип элемента коллекций
+func elemType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
 		default:
-			msg := ls.GetMessage(
-				localizer2.FieldDefault,
-				lang,
-				fmt.Sprintf("An error in the field %s. Rule: %s", camelToSnake(fieldError.Field()), fieldError.Tag()),
-				map[string]interface{}{
-					"field": camelToSnake(fieldError.Field()),
-					"rule":  fieldError.Tag(),
-				},
-			)
-
-			errorMessages += msg + "; "
+			return t
 		}
 	}
+}
+
+// fieldPath путь до поля без имени корневой структуры: events[0].type
+func fieldPath(fieldError validator.FieldError) string {
+	ns := fieldError.Namespace()
+	if _, path, ok := strings.Cut(ns, "."); ok {
+		return path
+	}
+	return fieldError.Field()
+}
+
+func fieldMessage(fieldError validator.FieldError, field, param string, ls *localizer2.LocalizeService, lang string) string {
+	data := map[string]interface{}{
+		"field": field,
+		"param": param,
+	}
+
+	switch fieldError.Tag() {
+	case "required":
+		return ls.GetMessage(localizer2.FieldRequired, lang, "Field '{{.field}}' required", data)
+	case "required_without":
+		return ls.GetMessage(localizer2.FieldRequiredWithout, lang, "Field '{{.field}}' is required when '{{.param}}' is not set", data)
+	case "email":
+		return ls.GetMessage(localizer2.FieldEmail, lang, "Invalid email in field '{{.field}}'", data)
+	case "url":
+		return ls.GetMessage(localizer2.FieldUrl, lang, "Field '{{.field}}' must be a valid URL", data)
+	case "uuid", "uuid4":
+		return ls.GetMessage(localizer2.FieldUuid, lang, "Field '{{.field}}' must be a valid UUID", data)
+	case "oneof":
+		data["param"] = strings.Join(strings.Fields(param), ", ")
+		return ls.GetMessage(localizer2.FieldOneOf, lang, "Field '{{.field}}' must be one of: {{.param}}", data)
+	case "min", "gte":
+		return sizeMessage(fieldError, ls, lang, data, sizeIds{
+			text:   localizer2.FieldMin,
+			items:  localizer2.FieldMinItems,
+			number: localizer2.FieldMinValue,
+		})
+	case "max", "lte":
+		return sizeMessage(fieldError, ls, lang, data, sizeIds{
+			text:   localizer2.FieldMax,
+			items:  localizer2.FieldMaxItems,
+			number: localizer2.FieldMaxValue,
+		})
+	case "len":
+		return sizeMessage(fieldError, ls, lang, data, sizeIds{
+			text:   localizer2.FieldLen,
+			items:  localizer2.FieldLenItems,
+			number: localizer2.FieldDefault,
+		})
+	default:
+		data["rule"] = fieldError.Tag()
+		return ls.GetMessage(
+			localizer2.FieldDefault,
+			lang,
+			fmt.Sprintf("An error in the field %s. Rule: %s", field, fieldError.Tag()),
+			data,
+		)
+	}
+}
+
+type sizeIds struct {
+	text, items, number string
+}
+
+// sizeMessage у min, max и len смысл зависит от типа поля: длина строки, количество элементов или значение числа
+func sizeMessage(fieldError validator.FieldError, ls *localizer2.LocalizeService, lang string, data map[string]interface{}, ids sizeIds) string {
+	count, _ := strconv.ParseInt(fieldError.Param(), 10, 64)
 
-	lenMsg := len(errorMessages)
-	if lenMsg > 2 {
-		lenMsg -= 2
-	} else {
-		lenMsg -= 1
+	switch fieldError.Kind() {
+	case reflect.String:
+		return ls.GetPluralMessage(
+			ids.text,
+			lang,
+			count,
+			"Field '{{.field}}' has invalid length: {{.param}} character",
+			"Field '{{.field}}' has invalid length: {{.param}} characters",
+			data,
+		)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return ls.GetPluralMessage(
+			ids.items,
+			lang,
+			count,
+			"Field '{{.field}}' has invalid number of items: {{.param}}",
+			"Field '{{.field}}' has invalid number of items: {{.param}}",
+			data,
+		)
+	default:
+		data["rule"] = fieldError.Tag()
+		return ls.GetMessage(ids.number, lang, "Invalid value in field '{{.field}}': {{.param}}", data)
 	}
-	return errorMessages[:lenMsg]
 }
 
 func camelToSnake(s string) string {
@@ -0,0 +1,104 @@
+package validate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	localizer2 "github.com/EddyZe/foodApp/common/pkg/localizer"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type testItem struct {
+	Type      string `json:"type" binding:"required,oneof=bounce complaint"`
+	MessageId string `json:"message_ref"`
+	Recipient string `json:"recipient" binding:"required_without=MessageId"`
+}
+
+type testBody struct {
+	UserEmail string     `json:"user_email" binding:"required,email"`
+	Name      string     `json:"name" binding:"min=3"`
+	Token     string     `json:"link_token"`
+	Code      string     `json:"code" binding:"required_without=Token"`
+	Items     []testItem `json:"items" binding:"required,min=1,dive"`
+}
+
+// testLocales переводы сообщений валидации, которые проверяет тест
+const testLocales = `[FieldEmail]
+other = "Некорректный email в поле '{{.field}}'"
+
+[FieldMin]
+one = "Поле '{{.field}}' должно содержать не меньше {{.param}} символа"
+few = "Поле '{{.field}}' должно содержать не меньше {{.param}} символов"
+many = "Поле '{{.field}}' должно содержать не меньше {{.param}} символов"
+other = "Поле '{{.field}}' должно содержать не меньше {{.param}} символов"
+
+[FieldOneOf]
+other = "Поле '{{.field}}' должно принимать одно из значений: {{.param}}"
+
+[FieldRequiredWithout]
+other = "Поле '{{.field}}' обязательно, если не задано поле '{{.param}}'"
+`
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	if err := RegisterTagNames(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestIsValidBodyDetails(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.ru.toml"), []byte(testLocales), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ls := localizer2.NewLocalizeService(logrus.NewEntry(logrus.New()), dir)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(
+		http.MethodPost,
+		"/",
+		strings.NewReader(`{"user_email":"bad","name":"ab","items":[{"type":"x"}]}`),
+	)
+	c.Request.Header.Set("Content-Type", "application/json")
+	localizer2.SetContext(c, "ru", false)
+
+	var body testBody
+	verr, ok := IsValidBody(c, &body, ls)
+	if ok {
+		t.Fatal("ожидалась ошибка валидации")
+	}
+
+	want := map[string]struct{ rule, param, message string }{
+		"user_email":    {"email", "", "Некорректный email в поле 'user_email'"},
+		"name":          {"min", "3", "Поле 'name' должно содержать не меньше 3 символов"},
+		"code":          {"required_without", "link_token", "Поле 'code' обязательно, если не задано поле 'link_token'"},
+		"items[0].type": {"oneof", "bounce complaint", "Поле 'items[0].type' должно принимать одно из значений: bounce, complaint"},
+		// имя берется из тега поля вложенной структуры, а не из snake_case имени Go: message_id
+		"items[0].recipient": {"required_without", "message_ref", "Поле 'items[0].recipient' обязательно, если не задано поле 'message_ref'"},
+	}
+	if len(verr.Fields) != len(want) {
+		t.Fatalf("ошибок %d: %+v", len(verr.Fields), verr.Fields)
+	}
+	for _, f := range verr.Fields {
+		w, ok := want[f.Field]
+		if !ok || w.rule != f.Rule || w.param != f.Param {
+			t.Errorf("неожиданная ошибка %+v", f)
+			continue
+		}
+		if f.Message != w.message {
+			t.Errorf("сообщение для %s: %q, ожидалось %q", f.Field, f.Message, w.message)
+		}
+	}
+	if len(verr.Details()) != len(want) {
+		t.Errorf("details %d", len(verr.Details()))
+	}
+	if strings.Count(verr.Message, "; ") != len(want)-1 {
+		t.Errorf("сообщение %q", verr.Message)
+	}
+}
@@ -2,30 +2,67 @@ package validate
 
 import (
 	"errors"
+
+	commonDto "github.com/EddyZe/foodApp/common/domain/dto"
 	localizer2 "github.com/EddyZe/foodApp/common/pkg/localizer"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
 
-func IsValidBody(c *gin.Context, body any, ls *localizer2.LocalizeService) (string, bool) {
-	lang := c.GetHeader("Accept-language")
-	if lang == "" {
-		lang = "en"
+// ValidationError результат неуспешной проверки запроса: общее сообщение и ошибки по полям.
+// Если тело не удалось разобрать, Fields пустой
+type ValidationError struct {
+	Message string
+	Fields  []commonDto.FieldError
+}
+
+// Details ошибки полей в виде, который принимает responseutil.ErrorResponse
+func (e *ValidationError) Details() []interface{} {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	res := make([]interface{}, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		res = append(res, f)
 	}
+	return res
+}
+
+func IsValidBody(c *gin.Context, body any, ls *localizer2.LocalizeService) (*ValidationError, bool) {
 	if err := c.ShouldBindJSON(&body); err != nil {
-		var validationErrors validator.ValidationErrors
-		errorMessages := ""
-		if errors.As(err, &validationErrors) {
-			errorMessages = ValidateBody(validationErrors, ls, lang)
-		} else {
-			errorMessages = ls.GetMessage(
+		return validationError(body, err, localizer2.FromContext(c), ls), false
+	}
+	return nil, true
+}
+
+// IsValidQuery разбирает и валидирует query параметры запроса
+func IsValidQuery(c *gin.Context, query any, ls *localizer2.LocalizeService) (*ValidationError, bool) {
+	if err := c.ShouldBindQuery(query); err != nil {
+		return validationError(query, err, localizer2.FromContext(c), ls), false
+	}
+	return nil, true
+}
+
+func validationError(obj any, err error, lang string, ls *localizer2.LocalizeService) *ValidationError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return &ValidationError{
+			Message: ls.GetMessage(
 				localizer2.InvalidBody,
 				lang,
 				"Invalid body",
 				nil,
-			)
+			),
+		}
+	}
+
+	fields := FieldErrors(obj, validationErrors, ls, lang)
+	res := &ValidationError{Fields: fields}
+	for i, f := range fields {
+		if i > 0 {
+			res.Message += "; "
 		}
-		return errorMessages, false
+		res.Message += f.Message
 	}
-	return "", true
+	return res
 }